@@ -0,0 +1,73 @@
+// backfill 升级后补全Redis中新增的索引，重复执行结果不变
+//
+// 使用方法（在config.yaml所在目录执行）：
+//
+//	go run ./cmd/backfill
+//
+// 补全的内容：
+//   - user:voted:{用户ID} 用户投过票的帖子，注销账户时用于删除用户的投票
+//   - post:votes 帖子的净票数，用于总得票排行
+//   - user:posts:{用户ID} 用户发布的帖子，用于关注的人的动态，从MySQL的帖子表补全
+package main
+
+import (
+	"blue-bell_back/dao/mysql"
+	"blue-bell_back/dao/redis"
+	"blue-bell_back/settings"
+	"context"
+	"log"
+)
+
+func main() {
+	if err := settings.Init(); err != nil {
+		log.Fatalf("init settings failed, err:%v", err)
+	}
+	if err := redis.Init(settings.Get().RedisConfig); err != nil {
+		log.Fatalf("init redis failed, err:%v", err)
+	}
+	defer redis.Close()
+	if err := mysql.Init(settings.Get().MySQLConfig); err != nil {
+		log.Fatalf("init mysql failed, err:%v", err)
+	}
+	defer mysql.Close()
+
+	ctx := context.Background()
+	posts, err := redis.BackfillUserVoted(ctx)
+	if err != nil {
+		log.Fatalf("backfill user:voted failed, err:%v", err)
+	}
+	log.Printf("user:voted backfilled from %d posts", posts)
+
+	posts, err = redis.BackfillPostVotes(ctx)
+	if err != nil {
+		log.Fatalf("backfill post:votes failed, err:%v", err)
+	}
+	log.Printf("post:votes backfilled, %d posts with votes", posts)
+
+	posts, err = backfillAuthorPosts(ctx)
+	if err != nil {
+		log.Fatalf("backfill user:posts failed, err:%v", err)
+	}
+	log.Printf("user:posts backfilled with %d posts", posts)
+}
+
+// backfillAuthorPosts 分批读取MySQL中的帖子补全作者的帖子列表
+func backfillAuthorPosts(ctx context.Context) (posts int, err error) {
+	const batch = 500
+	var afterID int64
+	for {
+		list, err := mysql.GetPostsAfter(ctx, afterID, batch)
+		if err != nil {
+			return posts, err
+		}
+		n, err := redis.BackfillAuthorPosts(ctx, list)
+		posts += n
+		if err != nil {
+			return posts, err
+		}
+		if len(list) < batch {
+			return posts, nil
+		}
+		afterID = list[len(list)-1].ID
+	}
+}
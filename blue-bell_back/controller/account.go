@@ -0,0 +1,149 @@
+package controller
+
+import (
+	"blue-bell_back/logger"
+	"blue-bell_back/logic"
+	"blue-bell_back/models"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// VerifyEmailHandler 使用邮件中的令牌验证邮箱
+func VerifyEmailHandler(c *gin.Context) {
+	p := new(models.ParamVerifyEmail)
+	if !bindJSON(c, p) {
+		return
+	}
+	if err := logic.VerifyEmail(c.Request.Context(), p.Token); err != nil {
+		logger.FromContext(c.Request.Context()).Error("logic.VerifyEmail failed", zap.Error(err))
+		ResponseErrorFrom(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// ResendVerifyEmailHandler 重新发送当前用户的验证邮件
+func ResendVerifyEmailHandler(c *gin.Context) {
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	if err := logic.ResendVerifyEmail(c.Request.Context(), userID); err != nil {
+		logger.FromContext(c.Request.Context()).Error("logic.ResendVerifyEmail failed", zap.Error(err))
+		ResponseErrorFrom(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// ForgotPasswordHandler 发送重置密码邮件
+// 无论邮箱是否注册都返回成功
+func ForgotPasswordHandler(c *gin.Context) {
+	p := new(models.ParamForgotPassword)
+	if !bindJSON(c, p) {
+		return
+	}
+	if err := logic.ForgotPassword(c.Request.Context(), p.Email); err != nil {
+		logger.FromContext(c.Request.Context()).Error("logic.ForgotPassword failed", zap.Error(err))
+		ResponseErrorFrom(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// ResetPasswordHandler 使用邮件中的令牌重置密码
+func ResetPasswordHandler(c *gin.Context) {
+	p := new(models.ParamResetPassword)
+	if !bindJSON(c, p) {
+		return
+	}
+	if err := logic.ResetPassword(c.Request.Context(), p.Token, p.Password); err != nil {
+		logger.FromContext(c.Request.Context()).Error("logic.ResetPassword failed", zap.Error(err))
+		ResponseErrorFrom(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// ChangePasswordHandler 修改当前用户的密码
+// 成功后之前的令牌全部失效，响应中返回新的令牌
+func ChangePasswordHandler(c *gin.Context) {
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	p := new(models.ParamChangePassword)
+	if !bindJSON(c, p) {
+		return
+	}
+	token, err := logic.ChangePassword(c.Request.Context(), userID, p)
+	if err != nil {
+		logger.FromContext(c.Request.Context()).Error("logic.ChangePassword failed", zap.Error(err))
+		ResponseErrorFrom(c, err)
+		return
+	}
+	ResponseSuccess(c, gin.H{"token": token})
+}
+
+// DeleteAccountHandler 注销当前用户的账户
+func DeleteAccountHandler(c *gin.Context) {
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	p := new(models.ParamDeleteAccount)
+	if !bindJSON(c, p) {
+		return
+	}
+	if err := logic.DeleteAccount(c.Request.Context(), userID, p); err != nil {
+		logger.FromContext(c.Request.Context()).Error("logic.DeleteAccount failed", zap.Int64("user_id", userID), zap.Error(err))
+		ResponseErrorFrom(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// AccountDeleteEmailHandler 发送确认注销账户的邮件
+// 邮件中的令牌可以代替密码注销账户，用于第三方登录创建的没有密码的用户
+func AccountDeleteEmailHandler(c *gin.Context) {
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	if err := logic.SendAccountDeleteEmail(c.Request.Context(), userID); err != nil {
+		logger.FromContext(c.Request.Context()).Error("logic.SendAccountDeleteEmail failed", zap.Error(err))
+		ResponseErrorFrom(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// SetUserLocaleHandler 设置当前用户的界面语言
+func SetUserLocaleHandler(c *gin.Context) {
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	p := new(models.ParamUserLocale)
+	if !bindJSON(c, p) {
+		return
+	}
+	if err := logic.SetUserLocale(c.Request.Context(), userID, p.Locale); err != nil {
+		logger.FromContext(c.Request.Context()).Error("logic.SetUserLocale failed", zap.Error(err))
+		ResponseErrorFrom(c, err)
+		return
+	}
+	// 本次响应就使用新的语言
+	locale := p.Locale
+	if locale == "" {
+		locale = parseAcceptLanguage(c.GetHeader("Accept-Language"))
+	}
+	c.Set(CtxLocaleKey, locale)
+	ResponseSuccess(c, gin.H{"locale": p.Locale})
+}
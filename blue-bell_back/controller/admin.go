@@ -0,0 +1,37 @@
+package controller
+
+import (
+	"blue-bell_back/logger"
+	"blue-bell_back/models"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// 管理员相关
+
+// GetLogLevelHandler 查询当前的日志级别
+func GetLogLevelHandler(c *gin.Context) {
+	ResponseSuccess(c, gin.H{
+		"level": logger.GetLevel(),
+	})
+}
+
+// SetLogLevelHandler 在运行时修改日志级别
+func SetLogLevelHandler(c *gin.Context) {
+	p := new(models.ParamLogLevel)
+	if !bindJSON(c, p) {
+		return
+	}
+	if err := logger.SetLevel(p.Level); err != nil {
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	userID, _ := getCurrentUserID(c)
+	logger.FromContext(c.Request.Context()).Warn("log level changed by admin",
+		zap.Int64("user_id", userID),
+		zap.String("level", p.Level))
+	ResponseSuccess(c, gin.H{
+		"level": logger.GetLevel(),
+	})
+}
@@ -0,0 +1,87 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// 请求参数绑定
+// 所有接口都通过这里绑定参数，校验失败时统一返回字段级别的错误信息：
+//
+//	{
+//		"code": 1001,
+//		"msg": "请求参数错误",
+//		"data": {
+//			"errors": [
+//				{"field": "re_password", "rule": "eqfield", "message": "re_password必须等于password"}
+//			]
+//		}
+//	}
+
+// FieldError 一个字段的校验错误
+type FieldError struct {
+	Field   string `json:"field"`   // 字段名，使用json tag，嵌套字段用.连接；请求体无法解析时为空
+	Rule    string `json:"rule"`    // 未通过的校验规则，如 required、email
+	Message string `json:"message"` // 按请求语言翻译后的提示信息
+}
+
+// 无法归入某个校验规则的错误，提示信息在信息目录中的键为 bind.{规则}
+const (
+	ruleType   = "type"   // 字段类型不匹配
+	ruleFormat = "format" // 请求体不是合法的JSON等
+)
+
+// bindJSON 绑定JSON请求体，失败时返回错误响应
+// 返回值: 是否绑定成功，失败时调用方直接返回即可
+func bindJSON(c *gin.Context, obj interface{}) bool {
+	return bindWith(c, obj, binding.JSON)
+}
+
+// bindQuery 绑定URL查询参数，失败时返回错误响应
+func bindQuery(c *gin.Context, obj interface{}) bool {
+	return bindWith(c, obj, binding.Query)
+}
+
+func bindWith(c *gin.Context, obj interface{}, b binding.Binding) bool {
+	err := c.ShouldBindWith(obj, b)
+	if err == nil {
+		return true
+	}
+	ResponseErrorWithData(c, CodeInvalidParam, gin.H{"errors": translateBindError(err, GetLocale(c))})
+	return false
+}
+
+// translateBindError 把绑定或校验错误转换为字段错误列表
+func translateBindError(err error, locale string) []FieldError {
+	var errs validator.ValidationErrors
+	if errors.As(err, &errs) {
+		trans := getTranslator(locale)
+		list := make([]FieldError, 0, len(errs))
+		for _, fe := range errs {
+			list = append(list, FieldError{
+				Field:   fieldName(fe.Namespace()),
+				Rule:    fe.Tag(),
+				Message: fe.Translate(trans),
+			})
+		}
+		return list
+	}
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return []FieldError{{Field: typeErr.Field, Rule: ruleType, Message: typeErr.Field + translate(locale, "bind."+ruleType)}}
+	}
+	return []FieldError{{Rule: ruleFormat, Message: translate(locale, "bind."+ruleFormat)}}
+}
+
+// fieldName 去除命名空间中的结构体名称，如 ParamSignUp.re_password -> re_password
+func fieldName(namespace string) string {
+	if i := strings.Index(namespace, "."); i >= 0 {
+		return namespace[i+1:]
+	}
+	return namespace
+}
@@ -1,5 +1,6 @@
 package controller
 
+// ResCode 业务状态码，各语言的提示信息见 locales 目录
 type ResCode int64
 
 const (
@@ -16,27 +17,41 @@ const (
 	CodeInsertFailed
 
 	CodePostInvalid
-)
 
-var codeMsgMap = map[ResCode]string{
-	CodeSuccess:         "success",
-	CodeInvalidParam:    "请求参数错误",
-	CodeUserExist:       "用户已存在",
-	CodeUserNotExist:    "用户不存在",
-	CodeInvalidPassword: "用户名或密码错误",
-	CodeServerBusy:      "服务繁忙",
+	CodeNoPermission
+	CodeTooManyRequests
+	CodeAccountLocked
 
-	CodeNeedLogin:    "请先登录",
-	CodeInvalidToken: "无效的Token",
+	CodeEmailExist
+	CodeEmailNotVerified
+	CodeInvalidEmailToken
 
-	CodeInsertFailed: "写入数据失败",
-	CodePostInvalid:  "当前PostID错误",
-}
+	CodeOAuthFailed
+	CodeIdentityExist
+
+	CodeCommunityNotExist
+	CodeVoteExpired
+	CodeVoteRepeated
+
+	CodeNotFound
+	CodeConflict
+	CodeExpired
+)
 
+// Msg 返回默认语言的提示信息
 func (c ResCode) Msg() string {
-	msg, ok := codeMsgMap[c]
-	if !ok {
-		msg = codeMsgMap[CodeServerBusy]
+	return c.MsgIn(defaultLocale)
+}
+
+// MsgIn 返回指定语言的提示信息，信息目录中没有对应条目时使用默认语言
+func (c ResCode) MsgIn(locale string) string {
+	if cat, ok := catalogs[locale]; ok {
+		if msg, ok := cat.Codes[c]; ok {
+			return msg
+		}
+	}
+	if msg, ok := catalogs[defaultLocale].Codes[c]; ok {
+		return msg
 	}
-	return msg
+	return catalogs[defaultLocale].Codes[CodeServerBusy]
 }
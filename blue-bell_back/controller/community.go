@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"blue-bell_back/logger"
 	"blue-bell_back/logic"
 	"blue-bell_back/models"
 	"strconv"
@@ -15,10 +16,10 @@ import (
 
 func CommunityHandler(c *gin.Context) {
 	//1.查询到所有社区的信息(community_id, community_name)
-	list, err := logic.GetCommunityList()
+	list, err := logic.GetCommunityList(c.Request.Context())
 	if err != nil {
-		zap.L().Error("service.GetCommunityList failed.", zap.Error(err))
-		ResponseError(c, CodeServerBusy)
+		logger.FromContext(c.Request.Context()).Error("service.GetCommunityList failed.", zap.Error(err))
+		ResponseErrorFrom(c, err)
 		return
 	}
 	ResponseSuccess(c, list)
@@ -36,10 +37,10 @@ func CommunityDetailHandler(c *gin.Context) {
 		return
 	}
 	//根据社区id查询社区详情
-	detail, err := logic.GetCommunityDetail(id)
+	detail, err := logic.GetCommunityDetail(c.Request.Context(), id)
 	if err != nil {
-		zap.L().Error("service.GetCommunityList failed.", zap.Error(err))
-		ResponseError(c, CodeServerBusy)
+		logger.FromContext(c.Request.Context()).Error("service.GetCommunityDetail failed.", zap.Error(err))
+		ResponseErrorFrom(c, err)
 		return
 	}
 	ResponseSuccess(c, detail)
@@ -49,11 +50,8 @@ func CommunityDetailHandler(c *gin.Context) {
 func CreatePostHandler(c *gin.Context) {
 	//1.获取参数
 	post := new(models.CommunityPost)
-	if err := c.ShouldBindJSON(post); err != nil {
-		//如果参数一场就记录日志并返回错误
-		zap.L().Debug("c.ShouldBindJSON(post) failed.", zap.Any("err", err))
-		zap.L().Error("create community failed.", zap.Error(err))
-		ResponseError(c, CodeInvalidParam)
+	if !bindJSON(c, post) {
+		//参数异常时已返回字段错误信息
 		return
 	}
 	//2.获取用户id
@@ -64,10 +62,10 @@ func CreatePostHandler(c *gin.Context) {
 	}
 	post.AuthorID = int64(userId)
 	//3. 存储数据
-	if err := logic.CreateCommunityPost(post); err != nil {
+	if err := logic.CreateCommunityPost(c.Request.Context(), post); err != nil {
 		//创建失败 返回错误信息
-		zap.L().Error("service.CreateCommunityPost failed.", zap.Error(err))
-		ResponseError(c, CodeServerBusy)
+		logger.FromContext(c.Request.Context()).Error("service.CreateCommunityPost failed.", zap.Error(err))
+		ResponseErrorFrom(c, err)
 		return
 	}
 	//4. 返回
@@ -80,16 +78,16 @@ func PostDetailHandler(c *gin.Context) {
 	id := c.Param("id")
 	postId, err := strconv.ParseInt(id, 10, 64)
 	if err != nil {
-		zap.L().Error("get post detail failed. invalid param", zap.Error(err))
+		logger.FromContext(c.Request.Context()).Error("get post detail failed. invalid param", zap.Error(err))
 		ResponseError(c, CodeInvalidParam)
 		return
 	}
 
 	//2.根据帖子id查询帖子详情
-	detail, err := logic.GetPostDetail(uint64(postId))
+	detail, err := logic.GetPostDetail(c.Request.Context(), uint64(postId))
 	if err != nil {
-		zap.L().Error("service.GetPostDetail failed", zap.Error(err))
-		ResponseError(c, CodeServerBusy)
+		logger.FromContext(c.Request.Context()).Error("service.GetPostDetail failed", zap.Error(err))
+		ResponseErrorFrom(c, err)
 		return
 	}
 	ResponseSuccess(c, detail)
@@ -105,10 +103,10 @@ func GetPostListHandler(c *gin.Context) {
 	}
 
 	//2.获取数据
-	list, err := logic.GetPostList(page, size)
+	list, err := logic.GetPostList(c.Request.Context(), page, size)
 	if err != nil {
-		zap.L().Error("service.GetPostList failed.", zap.Error(err))
-		ResponseError(c, CodeServerBusy)
+		logger.FromContext(c.Request.Context()).Error("service.GetPostList failed.", zap.Error(err))
+		ResponseErrorFrom(c, err)
 		return
 	}
 
@@ -119,21 +117,19 @@ func GetPostListHandler(c *gin.Context) {
 func GetPostOrderListHandler(c *gin.Context) {
 	// 初始化结构体并指定默认参数值
 	p := &models.ParamOrderList{
-		Page:  models.Page,
-		Size:  models.Size,
+		Page:  models.DefaultPage,
+		Size:  models.DefaultSize,
 		Order: models.OrderTime,
 	}
 	// 1.获取参数
-	if err := c.ShouldBindQuery(p); err != nil {
-		zap.L().Error("参数错误", zap.Error(err))
-		ResponseError(c, CodeInvalidParam)
+	if !bindQuery(c, p) {
 		return
 	}
 	// 2.去redis查询id列表
-	list, err := logic.GetPostOrderList(p)
+	list, err := logic.GetPostOrderList(c.Request.Context(), p)
 	if err != nil {
-		zap.L().Error("service.GetPostOrderList failed.", zap.Error(err))
-		ResponseError(c, CodeServerBusy)
+		logger.FromContext(c.Request.Context()).Error("service.GetPostOrderList failed.", zap.Error(err))
+		ResponseErrorFrom(c, err)
 		return
 	}
 	// 3.返回响应
@@ -148,24 +144,22 @@ func GetCommunityPostListHandler(c *gin.Context) {
 
 	p := &models.ParamCommunityPostList{
 		ParamOrderList: &models.ParamOrderList{
-			Page:  models.Page,
-			Size:  models.Size,
+			Page:  models.DefaultPage,
+			Size:  models.DefaultSize,
 			Order: models.OrderTime,
 		},
 	}
 
 	//1.获取参数校验
-	if err := c.ShouldBindQuery(p); err != nil {
-		zap.L().Error("参数错误", zap.Error(err))
-		ResponseError(c, CodeInvalidParam)
+	if !bindQuery(c, p) {
 		return
 	}
 
 	//2.去redis查询id列表
-	list, err := logic.GetCommunityPostList(p)
+	list, err := logic.GetCommunityPostList(c.Request.Context(), p)
 	if err != nil {
-		zap.L().Error("service.GetCommunityPostList failed", zap.Error(err))
-		ResponseError(c, CodeServerBusy)
+		logger.FromContext(c.Request.Context()).Error("service.GetCommunityPostList failed", zap.Error(err))
+		ResponseErrorFrom(c, err)
 		return
 	}
 
@@ -0,0 +1,67 @@
+package controller
+
+import (
+	"blue-bell_back/dao/mysql"
+	"blue-bell_back/dao/redis"
+	"blue-bell_back/logic"
+	"blue-bell_back/pkg/errs"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// errorCodes 具体错误对应的ResCode，按顺序匹配
+var errorCodes = []struct {
+	err  error
+	code ResCode
+}{
+	{mysql.ErrorUserExist, CodeUserExist},
+	{mysql.ErrorUserNotExist, CodeUserNotExist},
+	{mysql.ErrorInvalidPassword, CodeInvalidPassword},
+	{mysql.ErrorEmailExist, CodeEmailExist},
+	{mysql.ErrorIdentityExist, CodeIdentityExist},
+	{mysql.ErrorPostNotExist, CodePostInvalid},
+	{mysql.ErrorCommunityNotExist, CodeCommunityNotExist},
+	{redis.ErrVoteExpire, CodeVoteExpired},
+	{redis.ErrVoteRepestition, CodeVoteRepeated},
+	{logic.ErrAccountLocked, CodeAccountLocked},
+	{logic.ErrEmailNotVerified, CodeEmailNotVerified},
+	{logic.ErrInvalidEmailToken, CodeInvalidEmailToken},
+	{logic.ErrOAuthProviderNotFound, CodeInvalidParam},
+	{logic.ErrInvalidOAuthState, CodeOAuthFailed},
+	{logic.ErrOAuthIdentityMismatch, CodeOAuthFailed},
+	{logic.ErrWebhookURLNotAllowed, CodeInvalidParam},
+	{logic.ErrFollowSelf, CodeInvalidParam},
+	{logic.ErrInvalidTag, CodeInvalidParam},
+}
+
+// kindCodes 没有具体对应关系时按错误类别转换
+var kindCodes = []struct {
+	kind error
+	code ResCode
+}{
+	{errs.ErrNotFound, CodeNotFound},
+	{errs.ErrConflict, CodeConflict},
+	{errs.ErrForbidden, CodeNoPermission},
+	{errs.ErrExpired, CodeExpired},
+}
+
+// errorToCode 把logic层返回的错误转换为ResCode，未知错误返回CodeServerBusy
+func errorToCode(err error) ResCode {
+	for _, e := range errorCodes {
+		if errors.Is(err, e.err) {
+			return e.code
+		}
+	}
+	for _, k := range kindCodes {
+		if errors.Is(err, k.kind) {
+			return k.code
+		}
+	}
+	return CodeServerBusy
+}
+
+// ResponseErrorFrom 根据错误返回对应的ResCode
+func ResponseErrorFrom(c *gin.Context, err error) {
+	ResponseError(c, errorToCode(err))
+}
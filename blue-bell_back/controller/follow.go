@@ -0,0 +1,94 @@
+package controller
+
+import (
+	"blue-bell_back/logger"
+	"blue-bell_back/logic"
+	"blue-bell_back/models"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// 关注用户
+
+// FollowHandler 关注用户
+func FollowHandler(c *gin.Context) {
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	followeeID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	if err := logic.Follow(c.Request.Context(), userID, followeeID); err != nil {
+		logger.FromContext(c.Request.Context()).Error("logic.Follow failed", zap.Int64("followee_id", followeeID), zap.Error(err))
+		ResponseErrorFrom(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// UnfollowHandler 取消关注
+func UnfollowHandler(c *gin.Context) {
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	followeeID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	if err := logic.Unfollow(c.Request.Context(), userID, followeeID); err != nil {
+		logger.FromContext(c.Request.Context()).Error("logic.Unfollow failed", zap.Int64("followee_id", followeeID), zap.Error(err))
+		ResponseErrorFrom(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// UserProfileHandler 查询用户的公开资料，包含粉丝数和关注数
+func UserProfileHandler(c *gin.Context) {
+	viewerID, err := getCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	profile, err := logic.GetUserProfile(c.Request.Context(), viewerID, userID)
+	if err != nil {
+		logger.FromContext(c.Request.Context()).Error("logic.GetUserProfile failed", zap.Int64("user_id", userID), zap.Error(err))
+		ResponseErrorFrom(c, err)
+		return
+	}
+	ResponseSuccess(c, profile)
+}
+
+// FollowingFeedHandler 分页查询关注的人发布的帖子
+func FollowingFeedHandler(c *gin.Context) {
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	p := &models.ParamPage{Page: models.DefaultPage, Size: models.DefaultSize}
+	if !bindQuery(c, p) {
+		return
+	}
+	data, err := logic.GetFollowingFeed(c.Request.Context(), userID, p.Page, p.Size)
+	if err != nil {
+		logger.FromContext(c.Request.Context()).Error("logic.GetFollowingFeed failed", zap.Error(err))
+		ResponseErrorFrom(c, err)
+		return
+	}
+	ResponseSuccess(c, data)
+}
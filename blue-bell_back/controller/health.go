@@ -0,0 +1,30 @@
+package controller
+
+import (
+	"blue-bell_back/logic"
+	"blue-bell_back/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 健康检查相关
+// 探针只关心HTTP状态码，因此直接返回检查结果，不使用ResponseData包装
+
+// LivenessHandler 存活检查，进程能处理请求即返回200
+func LivenessHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status": models.StatusUp,
+	})
+}
+
+// ReadinessHandler 就绪检查
+// MySQL和Redis都可用时返回200，否则或者服务正在关闭时返回503
+func ReadinessHandler(c *gin.Context) {
+	res := logic.CheckReadiness(c.Request.Context())
+	status := http.StatusOK
+	if res.Status != models.StatusUp {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, res)
+}
@@ -0,0 +1,65 @@
+package controller
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+)
+
+// 响应信息的多语言目录
+// 每种语言一个JSON文件，codes按ResCode的数值索引，messages是其他需要翻译的提示信息
+// 新增ResCode时需要在所有语言文件中添加对应的信息，否则启动时panic
+
+//go:embed locales/*.json
+var localeFS embed.FS
+
+// catalog 一种语言的信息目录
+type catalog struct {
+	Codes    map[ResCode]string `json:"codes"`
+	Messages map[string]string  `json:"messages"`
+}
+
+// catalogs 各语言的信息目录
+var catalogs = mustLoadCatalogs()
+
+func mustLoadCatalogs() map[string]*catalog {
+	m := make(map[string]*catalog)
+	for _, locale := range []string{LocaleZH, LocaleEN} {
+		data, err := localeFS.ReadFile("locales/" + locale + ".json")
+		if err != nil {
+			panic(fmt.Sprintf("load locale %s failed: %v", locale, err))
+		}
+		cat := new(catalog)
+		if err := json.Unmarshal(data, cat); err != nil {
+			panic(fmt.Sprintf("parse locale %s failed: %v", locale, err))
+		}
+		m[locale] = cat
+	}
+	// 以中文目录为准，检查其他语言是否缺少条目
+	for locale, cat := range m {
+		for code := range m[LocaleZH].Codes {
+			if _, ok := cat.Codes[code]; !ok {
+				panic(fmt.Sprintf("locale %s: missing message for code %d", locale, code))
+			}
+		}
+		for key := range m[LocaleZH].Messages {
+			if _, ok := cat.Messages[key]; !ok {
+				panic(fmt.Sprintf("locale %s: missing message %q", locale, key))
+			}
+		}
+	}
+	return m
+}
+
+// translate 返回指定语言的提示信息，不存在时使用默认语言
+func translate(locale, key string) string {
+	if cat, ok := catalogs[locale]; ok {
+		if msg, ok := cat.Messages[key]; ok {
+			return msg
+		}
+	}
+	if msg, ok := catalogs[defaultLocale].Messages[key]; ok {
+		return msg
+	}
+	return key
+}
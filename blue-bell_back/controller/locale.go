@@ -0,0 +1,83 @@
+package controller
+
+import (
+	"blue-bell_back/logic"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 支持的语言
+const (
+	LocaleZH = "zh"
+	LocaleEN = "en"
+)
+
+// CtxLocaleKey 是在 Gin 上下文中缓存本次请求语言的键
+const CtxLocaleKey = "locale"
+
+// defaultLocale 客户端没有指定或指定了不支持的语言时使用，由InitTrans设置
+var defaultLocale = LocaleZH
+
+// supportedLocale 判断是否是支持的语言
+func supportedLocale(locale string) bool {
+	return locale == LocaleZH || locale == LocaleEN
+}
+
+// GetLocale 返回本次请求使用的语言
+// 已登录用户优先使用自己设置的语言，否则根据 Accept-Language 请求头协商
+// 用户设置的语言需要查询，结果缓存在上下文中；认证之前调用时不缓存，避免认证后仍使用请求头中的语言
+func GetLocale(c *gin.Context) string {
+	if v, ok := c.Get(CtxLocaleKey); ok {
+		if locale, ok := v.(string); ok {
+			return locale
+		}
+	}
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		return parseAcceptLanguage(c.GetHeader("Accept-Language"))
+	}
+	locale := logic.GetUserLocale(c.Request.Context(), userID)
+	if !supportedLocale(locale) {
+		locale = parseAcceptLanguage(c.GetHeader("Accept-Language"))
+	}
+	c.Set(CtxLocaleKey, locale)
+	return locale
+}
+
+// parseAcceptLanguage 按照q值从高到低选择第一个支持的语言
+// 只比较主语言标签，如 zh-CN、zh-TW 都视为 zh
+func parseAcceptLanguage(header string) string {
+	type langQ struct {
+		lang string
+		q    float64
+	}
+	var list []langQ
+	for _, part := range strings.Split(header, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		lang, params, _ := strings.Cut(part, ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+		primary, _, _ := strings.Cut(strings.TrimSpace(lang), "-")
+		list = append(list, langQ{lang: strings.ToLower(primary), q: q})
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].q > list[j].q })
+	for _, l := range list {
+		if supportedLocale(l.lang) {
+			return l.lang
+		}
+	}
+	return defaultLocale
+}
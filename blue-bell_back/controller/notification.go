@@ -0,0 +1,121 @@
+package controller
+
+import (
+	"blue-bell_back/logger"
+	"blue-bell_back/logic"
+	"blue-bell_back/models"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// 站内通知相关
+
+// NotificationListHandler 分页查询当前用户的通知
+func NotificationListHandler(c *gin.Context) {
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	p := &models.ParamPage{Page: models.DefaultPage, Size: models.DefaultSize}
+	if !bindQuery(c, p) {
+		return
+	}
+	list, err := logic.GetNotifications(c.Request.Context(), userID, p.Page, p.Size)
+	if err != nil {
+		logger.FromContext(c.Request.Context()).Error("logic.GetNotifications failed", zap.Error(err))
+		ResponseErrorFrom(c, err)
+		return
+	}
+	locale := GetLocale(c)
+	for _, n := range list {
+		n.Message = notificationMessage(locale, n)
+	}
+	ResponseSuccess(c, list)
+}
+
+// notificationMessage 按语言生成通知的提示信息
+func notificationMessage(locale string, n *models.Notification) string {
+	switch n.Type {
+	case models.NotifyTypeVote:
+		if n.ActorCount <= 1 {
+			return fmt.Sprintf(translate(locale, "notify.vote.one"), n.PostTitle)
+		}
+		return fmt.Sprintf(translate(locale, "notify.vote"), n.ActorCount, n.PostTitle)
+	}
+	return ""
+}
+
+// UnreadNotificationCountHandler 查询当前用户的未读通知数量
+func UnreadNotificationCountHandler(c *gin.Context) {
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	count, err := logic.CountUnreadNotifications(c.Request.Context(), userID)
+	if err != nil {
+		logger.FromContext(c.Request.Context()).Error("logic.CountUnreadNotifications failed", zap.Error(err))
+		ResponseErrorFrom(c, err)
+		return
+	}
+	ResponseSuccess(c, gin.H{"count": count})
+}
+
+// MarkNotificationsReadHandler 把通知标记为已读，ids为空时标记全部
+func MarkNotificationsReadHandler(c *gin.Context) {
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	p := new(models.ParamMarkRead)
+	if !bindJSON(c, p) {
+		return
+	}
+	affected, err := logic.MarkNotificationsRead(c.Request.Context(), userID, p.IDs)
+	if err != nil {
+		logger.FromContext(c.Request.Context()).Error("logic.MarkNotificationsRead failed", zap.Error(err))
+		ResponseErrorFrom(c, err)
+		return
+	}
+	ResponseSuccess(c, gin.H{"count": affected})
+}
+
+// GetNotifyPreferencesHandler 查询当前用户的通知设置
+func GetNotifyPreferencesHandler(c *gin.Context) {
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	prefs, err := logic.GetNotifyPreferences(c.Request.Context(), userID)
+	if err != nil {
+		logger.FromContext(c.Request.Context()).Error("logic.GetNotifyPreferences failed", zap.Error(err))
+		ResponseErrorFrom(c, err)
+		return
+	}
+	ResponseSuccess(c, prefs)
+}
+
+// SetNotifyPreferencesHandler 修改当前用户的通知设置，只修改传入的类型
+func SetNotifyPreferencesHandler(c *gin.Context) {
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	p := new(models.ParamNotifyPreferences)
+	if !bindJSON(c, p) {
+		return
+	}
+	prefs, err := logic.SetNotifyPreferences(c.Request.Context(), userID, p.Preferences())
+	if err != nil {
+		logger.FromContext(c.Request.Context()).Error("logic.SetNotifyPreferences failed", zap.Error(err))
+		ResponseErrorFrom(c, err)
+		return
+	}
+	ResponseSuccess(c, prefs)
+}
@@ -0,0 +1,156 @@
+package controller
+
+import (
+	"blue-bell_back/logger"
+	"blue-bell_back/logic"
+	"blue-bell_back/models"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// 第三方登录流程：
+// 1. 前端请求 authorize 接口拿到提供方的授权地址并跳转
+// 2. 用户授权后提供方跳转到配置的 redirect_url（前端页面），带上 code 和 state
+// 3. 前端把 code 和 state 交给 callback 接口，换取和密码登录相同的令牌
+// 绑定第三方账户时使用 link 和 link/callback 接口，需要携带当前用户的令牌
+
+// OAuthAuthorizeHandler 返回第三方登录的授权地址
+func OAuthAuthorizeHandler(c *gin.Context) {
+	authURL, err := logic.OAuthAuthorizeURL(c.Request.Context(), c.Param("provider"), 0)
+	if err != nil {
+		responseOAuthError(c, "logic.OAuthAuthorizeURL failed", err)
+		return
+	}
+	ResponseSuccess(c, gin.H{"url": authURL})
+}
+
+// OAuthCallbackHandler 使用提供方返回的授权码登录
+func OAuthCallbackHandler(c *gin.Context) {
+	oauthCallback(c, 0)
+}
+
+// OAuthLinkHandler 返回为当前用户绑定第三方账户的授权地址
+func OAuthLinkHandler(c *gin.Context) {
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	authURL, err := logic.OAuthAuthorizeURL(c.Request.Context(), c.Param("provider"), userID)
+	if err != nil {
+		responseOAuthError(c, "logic.OAuthAuthorizeURL failed", err)
+		return
+	}
+	ResponseSuccess(c, gin.H{"url": authURL})
+}
+
+// OAuthLinkCallbackHandler 使用提供方返回的授权码为当前用户绑定第三方账户
+func OAuthLinkCallbackHandler(c *gin.Context) {
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	oauthCallback(c, userID)
+}
+
+// OAuthReauthHandler 返回当前用户再次验证身份的授权地址，用于没有密码的用户注销账户
+func OAuthReauthHandler(c *gin.Context) {
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	authURL, err := logic.OAuthReauthURL(c.Request.Context(), c.Param("provider"), userID)
+	if err != nil {
+		responseOAuthError(c, "logic.OAuthReauthURL failed", err)
+		return
+	}
+	ResponseSuccess(c, gin.H{"url": authURL})
+}
+
+// OAuthReauthCallbackHandler 使用提供方返回的授权码再次验证身份，返回确认注销账户的令牌
+func OAuthReauthCallbackHandler(c *gin.Context) {
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	if e := c.Query("error"); e != "" {
+		logger.FromContext(c.Request.Context()).Warn("oauth authorization denied",
+			zap.String("provider", c.Param("provider")), zap.String("error", e))
+		ResponseError(c, CodeOAuthFailed)
+		return
+	}
+	code, state := c.Query("code"), c.Query("state")
+	if code == "" || state == "" {
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	token, err := logic.OAuthReauth(c.Request.Context(), c.Param("provider"), state, code, userID)
+	if err != nil {
+		responseOAuthError(c, "logic.OAuthReauth failed", err)
+		return
+	}
+	ResponseSuccess(c, gin.H{"token": token})
+}
+
+// UserIdentitiesHandler 查询当前用户绑定的第三方账户
+func UserIdentitiesHandler(c *gin.Context) {
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	list, err := logic.GetUserIdentities(c.Request.Context(), userID)
+	if err != nil {
+		logger.FromContext(c.Request.Context()).Error("logic.GetUserIdentities failed", zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(c, list)
+}
+
+func oauthCallback(c *gin.Context, currentUserID int64) {
+	// 用户在提供方拒绝授权时，回调中带有error参数
+	if e := c.Query("error"); e != "" {
+		logger.FromContext(c.Request.Context()).Warn("oauth authorization denied",
+			zap.String("provider", c.Param("provider")), zap.String("error", e))
+		ResponseError(c, CodeOAuthFailed)
+		return
+	}
+	code, state := c.Query("code"), c.Query("state")
+	if code == "" || state == "" {
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	user, err := logic.OAuthCallback(c.Request.Context(), c.Param("provider"), state, code, currentUserID, &models.LoginMeta{
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	})
+	if err != nil {
+		responseOAuthError(c, "logic.OAuthCallback failed", err)
+		return
+	}
+	ResponseSuccess(c, gin.H{
+		"user_id":   user.UserID,
+		"user_name": user.UserName,
+		"token":     user.Token,
+	})
+}
+
+func responseOAuthError(c *gin.Context, msg string, err error) {
+	logger.FromContext(c.Request.Context()).Error(msg, zap.String("provider", c.Param("provider")), zap.Error(err))
+	if errors.Is(err, logic.ErrInvalidOAuthState) {
+		ResponseErrorWithMsg(c, CodeOAuthFailed, "oauth.invalid_state")
+		return
+	}
+	code := errorToCode(err)
+	if code == CodeServerBusy {
+		// 和提供方交互失败等未归类的错误
+		code = CodeOAuthFailed
+	}
+	ResponseError(c, code)
+}
@@ -0,0 +1,191 @@
+package controller
+
+import (
+	"blue-bell_back/pkg/openapi"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 接口文档
+// 每个 /api/v1 下的路由都要在 router 中登记 APIDoc，缺少文档或文档对应的路由不存在时 NewOpenAPI 返回错误
+
+// OpenAPIPrefix 需要文档的路由前缀
+const OpenAPIPrefix = "/api/v1"
+
+// bearerAuth 文档中JWT认证方式的名称
+const bearerAuth = "bearerAuth"
+
+// APIDoc 一个接口的文档
+type APIDoc struct {
+	Summary     string
+	Description string
+	Tag         string
+	Auth        bool        // 是否需要携带JWT
+	Query       interface{} // 查询参数，按form标签生成
+	Body        interface{} // JSON请求体，按json和binding标签生成
+	Data        interface{} // 成功时响应中data字段的类型，nil表示没有data
+}
+
+// DocKey 返回路由在文档表中的键，如 "GET /api/v1/community/:id"
+func DocKey(method, path string) string {
+	return method + " " + path
+}
+
+// NewOpenAPI 根据路由和文档表生成OpenAPI文档
+// 有路由缺少文档或文档没有对应的路由时返回错误，同时返回已有文档的部分
+func NewOpenAPI(routes gin.RoutesInfo, docs map[string]APIDoc) (*openapi.Document, error) {
+	b := openapi.NewBuilder(openapi.Info{
+		Title:       "bluebell API",
+		Description: "所有接口都返回 ResponseData，code 为业务状态码（见 ResCode），HTTP状态码由 code 决定",
+		Version:     "v1",
+	})
+	b.AddSecurityScheme(bearerAuth, &openapi.SecurityScheme{
+		Type:         "http",
+		Scheme:       "bearer",
+		BearerFormat: "JWT",
+		Description:  "登录接口返回的token，请求头格式为 Authorization: Bearer {token}",
+	})
+	codeRef := b.AddSchema("ResCode", resCodeSchema())
+	envelope := b.AddSchema("ResponseData", &openapi.Schema{
+		Type: "object",
+		Properties: map[string]*openapi.Schema{
+			"code": codeRef,
+			"msg":  {Description: "按请求语言返回的提示信息"},
+			"data": {Description: "业务数据，没有数据时不返回"},
+		},
+		Required: []string{"code", "msg"},
+	})
+	fieldErrors := b.Schema(struct {
+		Errors []FieldError `json:"errors"`
+	}{})
+
+	var missing []string
+	used := make(map[string]bool)
+	tags := make(map[string]bool)
+	for _, r := range routes {
+		if !strings.HasPrefix(r.Path, OpenAPIPrefix) {
+			continue
+		}
+		key := DocKey(r.Method, r.Path)
+		doc, ok := docs[key]
+		if !ok {
+			missing = append(missing, key)
+			continue
+		}
+		used[key] = true
+		tags[doc.Tag] = true
+
+		op := &openapi.Operation{
+			Summary:     doc.Summary,
+			Description: doc.Description,
+			OperationID: operationID(r.Method, r.Path),
+			Parameters:  b.QueryParams(doc.Query),
+			Responses: map[string]*openapi.Response{
+				"200":     jsonResponse("成功", withData(envelope, b.Schema(doc.Data))),
+				"default": jsonResponse("失败，code见ResCode", envelope),
+			},
+		}
+		if doc.Tag != "" {
+			op.Tags = []string{doc.Tag}
+		}
+		if doc.Body != nil {
+			op.RequestBody = &openapi.RequestBody{
+				Required: true,
+				Content:  map[string]*openapi.MediaType{"application/json": {Schema: b.Schema(doc.Body)}},
+			}
+		}
+		if doc.Body != nil || doc.Query != nil {
+			op.Responses["400"] = jsonResponse("参数校验失败，data中返回各字段的错误", withData(envelope, fieldErrors))
+		}
+		if doc.Auth {
+			op.Security = []map[string][]string{{bearerAuth: {}}}
+		}
+		b.AddOperation(r.Method, r.Path, op)
+	}
+	for tag := range tags {
+		if tag != "" {
+			b.AddTag(tag, "")
+		}
+	}
+
+	var stale []string
+	for key := range docs {
+		if !used[key] {
+			stale = append(stale, key)
+		}
+	}
+	if len(missing) > 0 || len(stale) > 0 {
+		sort.Strings(missing)
+		sort.Strings(stale)
+		return b.Document(), fmt.Errorf("openapi: routes without documentation: [%s], documentation without routes: [%s]",
+			strings.Join(missing, ", "), strings.Join(stale, ", "))
+	}
+	return b.Document(), nil
+}
+
+// resCodeSchema 列出所有ResCode以及对应的HTTP状态码和提示信息
+func resCodeSchema() *openapi.Schema {
+	codes := make([]ResCode, 0, len(catalogs[defaultLocale].Codes))
+	for code := range catalogs[defaultLocale].Codes {
+		codes = append(codes, code)
+	}
+	sort.Slice(codes, func(i, j int) bool { return codes[i] < codes[j] })
+
+	s := &openapi.Schema{Type: "integer", Format: "int64"}
+	lines := make([]string, 0, len(codes)+1)
+	lines = append(lines, "业务状态码，开启 response.legacy_status 时HTTP状态码始终为200\n")
+	for _, code := range codes {
+		s.Enum = append(s.Enum, int64(code))
+		lines = append(lines, fmt.Sprintf("- %d (HTTP %d): %s", code, code.HTTPStatus(), code.Msg()))
+	}
+	s.Description = strings.Join(lines, "\n")
+	return s
+}
+
+// withData 把envelope中的data替换为具体的类型
+func withData(envelope, data *openapi.Schema) *openapi.Schema {
+	if data == nil {
+		return envelope
+	}
+	return &openapi.Schema{AllOf: []*openapi.Schema{envelope, {
+		Type:       "object",
+		Properties: map[string]*openapi.Schema{"data": data},
+	}}}
+}
+
+func jsonResponse(description string, schema *openapi.Schema) *openapi.Response {
+	return &openapi.Response{
+		Description: description,
+		Content:     map[string]*openapi.MediaType{"application/json": {Schema: schema}},
+	}
+}
+
+// operationID 由方法和路径生成，如 GET /api/v1/community/:id 生成 get_community_id
+func operationID(method, path string) string {
+	path = strings.TrimPrefix(path, OpenAPIPrefix)
+	parts := []string{strings.ToLower(method)}
+	for _, seg := range strings.Split(path, "/") {
+		seg = strings.TrimLeft(seg, ":*")
+		if seg != "" {
+			parts = append(parts, seg)
+		}
+	}
+	return strings.Join(parts, "_")
+}
+
+// OpenAPIHandler 返回输出文档的处理函数
+func OpenAPIHandler(doc *openapi.Document) (gin.HandlerFunc, error) {
+	h, err := openapi.Handler(doc)
+	if err != nil {
+		return nil, err
+	}
+	return gin.WrapH(h), nil
+}
+
+// SwaggerUIHandler 返回Swagger UI页面的处理函数，specURL为文档的地址
+func SwaggerUIHandler(specURL string) gin.HandlerFunc {
+	return gin.WrapH(openapi.UIHandler("bluebell API", specURL))
+}
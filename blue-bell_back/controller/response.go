@@ -13,34 +13,69 @@ import (
 	"data": {},		// 数据
 }
 
+HTTP状态码由code决定，见 status.go
+
 */
 
+// CtxResCodeKey 是在 Gin 上下文中保存本次响应业务码的键，供监控等中间件使用
+const CtxResCodeKey = "resCode"
+
 type ResponseData struct {
 	Code ResCode     `json:"code"`
 	Msg  interface{} `json:"msg"`
-	Data interface{} `json:"data, omitempty"` //如果data为空，则不返回data字段
+	Data interface{} `json:"data,omitempty"` //如果data为空，则不返回data字段
 }
 
+// 响应中的提示信息按请求协商出的语言返回，见 GetLocale
+
 func ResponseError(c *gin.Context, code ResCode) {
-	c.JSON(http.StatusOK, &ResponseData{
+	c.Set(CtxResCodeKey, code)
+	c.JSON(httpStatus(code), &ResponseData{
 		Code: code,
-		Msg:  code.Msg(),
+		Msg:  code.MsgIn(GetLocale(c)),
 		Data: nil,
 	})
 }
 
+// ResponseErrorWithMsg 返回错误码和自定义的提示信息
+// msg为信息目录中messages的键时返回翻译后的信息，否则原样返回
 func ResponseErrorWithMsg(c *gin.Context, code ResCode, msg interface{}) {
-	c.JSON(http.StatusOK, &ResponseData{
+	c.Set(CtxResCodeKey, code)
+	if key, ok := msg.(string); ok {
+		msg = translate(GetLocale(c), key)
+	}
+	c.JSON(httpStatus(code), &ResponseData{
 		Code: code,
 		Msg:  msg,
 		Data: nil,
 	})
 }
 
+// ResponseErrorWithData 返回错误码和附加数据，如参数校验失败的字段列表
+func ResponseErrorWithData(c *gin.Context, code ResCode, data interface{}) {
+	c.Set(CtxResCodeKey, code)
+	c.JSON(httpStatus(code), &ResponseData{
+		Code: code,
+		Msg:  code.MsgIn(GetLocale(c)),
+		Data: data,
+	})
+}
+
 func ResponseSuccess(c *gin.Context, data interface{}) {
+	c.Set(CtxResCodeKey, CodeSuccess)
 	c.JSON(http.StatusOK, &ResponseData{
 		Code: CodeSuccess,
-		Msg:  CodeSuccess.Msg(),
+		Msg:  CodeSuccess.MsgIn(GetLocale(c)),
 		Data: data,
 	})
 }
+
+// RecoveryHandler 请求处理发生panic时返回的响应，供 logger.GinRecovery 使用
+// 无论是否开启 response.legacy_status 都返回500，和之前的行为一致
+func RecoveryHandler(c *gin.Context) {
+	c.Set(CtxResCodeKey, CodeServerBusy)
+	c.AbortWithStatusJSON(http.StatusInternalServerError, &ResponseData{
+		Code: CodeServerBusy,
+		Msg:  CodeServerBusy.MsgIn(GetLocale(c)),
+	})
+}
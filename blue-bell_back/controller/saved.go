@@ -0,0 +1,73 @@
+package controller
+
+import (
+	"blue-bell_back/logger"
+	"blue-bell_back/logic"
+	"blue-bell_back/models"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// 收藏帖子
+
+// SavePostHandler 收藏帖子
+func SavePostHandler(c *gin.Context) {
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	postID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	if err := logic.SavePost(c.Request.Context(), userID, postID); err != nil {
+		logger.FromContext(c.Request.Context()).Error("logic.SavePost failed", zap.Int64("post_id", postID), zap.Error(err))
+		ResponseErrorFrom(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// UnsavePostHandler 取消收藏
+func UnsavePostHandler(c *gin.Context) {
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	postID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	if err := logic.UnsavePost(c.Request.Context(), userID, postID); err != nil {
+		logger.FromContext(c.Request.Context()).Error("logic.UnsavePost failed", zap.Int64("post_id", postID), zap.Error(err))
+		ResponseErrorFrom(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// SavedPostListHandler 分页查询当前用户收藏的帖子
+func SavedPostListHandler(c *gin.Context) {
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	p := &models.ParamPage{Page: models.DefaultPage, Size: models.DefaultSize}
+	if !bindQuery(c, p) {
+		return
+	}
+	data, err := logic.GetSavedPosts(c.Request.Context(), userID, p.Page, p.Size)
+	if err != nil {
+		logger.FromContext(c.Request.Context()).Error("logic.GetSavedPosts failed", zap.Error(err))
+		ResponseErrorFrom(c, err)
+		return
+	}
+	ResponseSuccess(c, data)
+}
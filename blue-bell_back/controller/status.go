@@ -0,0 +1,58 @@
+package controller
+
+import (
+	"blue-bell_back/settings"
+	"net/http"
+)
+
+// codeHTTPStatus 各ResCode对应的HTTP状态码，没有列出的返回500
+var codeHTTPStatus = map[ResCode]int{
+	CodeSuccess:         http.StatusOK,
+	CodeInvalidParam:    http.StatusBadRequest,
+	CodeUserExist:       http.StatusConflict,
+	CodeUserNotExist:    http.StatusNotFound,
+	CodeInvalidPassword: http.StatusUnauthorized,
+	CodeServerBusy:      http.StatusInternalServerError,
+
+	CodeNeedLogin:    http.StatusUnauthorized,
+	CodeInvalidToken: http.StatusUnauthorized,
+
+	CodeInsertFailed: http.StatusInternalServerError,
+	CodePostInvalid:  http.StatusNotFound,
+
+	CodeNoPermission:    http.StatusForbidden,
+	CodeTooManyRequests: http.StatusTooManyRequests,
+	CodeAccountLocked:   http.StatusTooManyRequests,
+
+	CodeEmailExist:        http.StatusConflict,
+	CodeEmailNotVerified:  http.StatusForbidden,
+	CodeInvalidEmailToken: http.StatusBadRequest,
+
+	CodeOAuthFailed:   http.StatusUnauthorized,
+	CodeIdentityExist: http.StatusConflict,
+
+	CodeCommunityNotExist: http.StatusNotFound,
+	CodeVoteExpired:       http.StatusGone,
+	CodeVoteRepeated:      http.StatusConflict,
+
+	CodeNotFound: http.StatusNotFound,
+	CodeConflict: http.StatusConflict,
+	CodeExpired:  http.StatusGone,
+}
+
+// HTTPStatus 返回ResCode对应的HTTP状态码
+func (c ResCode) HTTPStatus() int {
+	if status, ok := codeHTTPStatus[c]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// httpStatus 返回本次响应使用的HTTP状态码
+// 开启 response.legacy_status 时始终返回200
+func httpStatus(code ResCode) int {
+	if conf := settings.Get().ResponseConfig; conf != nil && conf.LegacyStatus {
+		return http.StatusOK
+	}
+	return code.HTTPStatus()
+}
@@ -0,0 +1,76 @@
+package controller
+
+import (
+	"blue-bell_back/logger"
+	"blue-bell_back/logic"
+	"blue-bell_back/models"
+	"io"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// 实时事件推送，使用Server-Sent Events
+// 事件名为事件类型（post.created、vote.changed），数据为 models.Event 的JSON
+
+// streamHeartbeat 心跳间隔，防止代理因连接空闲而断开
+const streamHeartbeat = 30 * time.Second
+
+// StreamTicketHandler 签发连接事件流使用的一次性票据
+func StreamTicketHandler(c *gin.Context) {
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	ticket, err := logic.IssueStreamTicket(c.Request.Context(), userID)
+	if err != nil {
+		logger.FromContext(c.Request.Context()).Error("logic.IssueStreamTicket failed", zap.Error(err))
+		ResponseErrorFrom(c, err)
+		return
+	}
+	ResponseSuccess(c, gin.H{"ticket": ticket})
+}
+
+// StreamHandler 推送订阅社区中的新帖子和投票变化
+func StreamHandler(c *gin.Context) {
+	p := new(models.ParamStream)
+	if !bindQuery(c, p) {
+		return
+	}
+	stream, err := logic.SubscribeEventStream(p.CommunityIDs)
+	if err != nil {
+		ResponseErrorFrom(c, err)
+		return
+	}
+	defer stream.Close()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // 关闭nginx的响应缓冲
+	c.Set(CtxResCodeKey, CodeSuccess)
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+	// 先发送一个注释，让客户端立即收到响应头
+	_, _ = io.WriteString(c.Writer, ": connected\n\n")
+	c.Writer.Flush()
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case e, ok := <-stream.C:
+			if !ok {
+				// 服务正在关闭
+				return false
+			}
+			c.SSEvent(e.Type, e)
+			return true
+		case <-heartbeat.C:
+			_, err := io.WriteString(w, ": ping\n\n")
+			return err == nil
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+}
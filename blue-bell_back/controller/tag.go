@@ -0,0 +1,49 @@
+package controller
+
+import (
+	"blue-bell_back/logger"
+	"blue-bell_back/logic"
+	"blue-bell_back/models"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// 帖子标签
+
+// TagPostListHandler 根据标签返回帖子，支持按时间和分数排序
+func TagPostListHandler(c *gin.Context) {
+	p := &models.ParamTagPostList{
+		ParamOrderList: &models.ParamOrderList{
+			Page:  models.DefaultPage,
+			Size:  models.DefaultSize,
+			Order: models.OrderTime,
+		},
+	}
+	if !bindQuery(c, p) {
+		return
+	}
+	p.Tag = c.Param("name")
+	list, err := logic.GetTagPostList(c.Request.Context(), p)
+	if err != nil {
+		logger.FromContext(c.Request.Context()).Error("logic.GetTagPostList failed", zap.Error(err))
+		ResponseErrorFrom(c, err)
+		return
+	}
+	ResponseSuccess(c, list)
+}
+
+// TagListHandler 按使用次数倒序返回标签
+func TagListHandler(c *gin.Context) {
+	p := new(models.ParamTagList)
+	if !bindQuery(c, p) {
+		return
+	}
+	list, err := logic.GetTags(c.Request.Context(), p)
+	if err != nil {
+		logger.FromContext(c.Request.Context()).Error("logic.GetTags failed", zap.Error(err))
+		ResponseErrorFrom(c, err)
+		return
+	}
+	ResponseSuccess(c, list)
+}
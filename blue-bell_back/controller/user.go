@@ -1,14 +1,14 @@
 package controller
 
 import (
-	"blue-bell_back/dao/mysql"
+	"blue-bell_back/logger"
 	"blue-bell_back/logic"
 	"blue-bell_back/models"
 	"errors"
-	"fmt"
+	"math"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
-	"github.com/go-playground/validator/v10"
 	"go.uber.org/zap"
 )
 
@@ -17,16 +17,8 @@ import (
 func SignUpHandler(c *gin.Context) {
 	//1. 获取参数和参数校验
 	p := new(models.ParamSignUp)
-	if err := c.ShouldBindBodyWithJSON(p); err != nil {
-		// 请求参数有误，直接返回响应
-		zap.L().Error("SignUp with invalid param", zap.Error(err))
-		// 判断err是不是validator.ValidationErrors 类型
-		errs, ok := err.(validator.ValidationErrors)
-		if !ok {
-			ResponseError(c, CodeInvalidPassword)
-			return
-		}
-		ResponseErrorWithMsg(c, CodeInvalidPassword, removeTopStruct(errs.Translate(trans)))
+	if !bindJSON(c, p) {
+		// 请求参数有误，已返回字段错误信息
 		return
 	}
 	// 手动对请求参数进行详细的业务规则校验
@@ -39,15 +31,10 @@ func SignUpHandler(c *gin.Context) {
 	//	return
 	//}
 
-	fmt.Println(p)
 	//2. 业务处理
-	if err := logic.SignUp(p); err != nil {
-		zap.L().Error("login.SignUp failed", zap.Error(err))
-		if errors.Is(err, mysql.ErrorInvalidPassword) {
-			ResponseError(c, CodeUserExist)
-			return
-		}
-		ResponseError(c, CodeServerBusy)
+	if err := logic.SignUp(c.Request.Context(), p); err != nil {
+		logger.FromContext(c.Request.Context()).Error("login.SignUp failed", zap.Error(err))
+		ResponseErrorFrom(c, err)
 		return
 	}
 	//3. 返回响应
@@ -56,29 +43,23 @@ func SignUpHandler(c *gin.Context) {
 func LoginHandler(c *gin.Context) {
 	// 1.获取请求参数及参数校验
 	p := new(models.ParamLogin)
-	if err := c.ShouldBindJSON(p); err != nil {
-		// 请求参数有误，直接返回响应
-		zap.L().Error("Login with invalid param", zap.Error(err))
-		// 判断err是不是validator.ValidationErrors 类型
-		errs, ok := err.(validator.ValidationErrors)
-		if !ok {
-			ResponseError(c, CodeInvalidParam)
-			return
-		}
-		ResponseErrorWithMsg(c, CodeInvalidParam, removeTopStruct(errs.Translate(trans)))
+	if !bindJSON(c, p) {
 		return
 	}
 
 	// 2.业务逻辑处理
-	// if err := logic.Login(p); err != nil {
-	user, err := logic.Login(p)
+	// if err := logic.Login(c.Request.Context(), p); err != nil {
+	user, err := logic.Login(c.Request.Context(), p, &models.LoginMeta{
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	})
 	if err != nil {
-		zap.L().Error("logic.Login failed", zap.String("username", p.UserName), zap.Error(err))
-		if errors.Is(err, mysql.ErrorUserNotExist) {
-			ResponseError(c, CodeUserNotExist)
-			return
+		logger.FromContext(c.Request.Context()).Error("logic.Login failed", zap.String("username", p.UserName), zap.Error(err))
+		var lockErr *logic.AccountLockedError
+		if errors.As(err, &lockErr) {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(lockErr.RetryAfter.Seconds()))))
 		}
-		ResponseError(c, CodeInvalidPassword)
+		ResponseErrorFrom(c, err)
 		return
 	}
 	// 3.返回响应
@@ -88,3 +69,23 @@ func LoginHandler(c *gin.Context) {
 		"token":     user.Token,
 	})
 }
+
+// LoginHistoryHandler 查询当前用户的登录记录
+func LoginHistoryHandler(c *gin.Context) {
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	p := &models.ParamPage{Page: models.DefaultPage, Size: models.DefaultSize}
+	if !bindQuery(c, p) {
+		return
+	}
+	list, err := logic.GetLoginHistory(c.Request.Context(), userID, p.Page, p.Size)
+	if err != nil {
+		logger.FromContext(c.Request.Context()).Error("logic.GetLoginHistory failed", zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(c, list)
+}
@@ -15,64 +15,66 @@ import (
 	zhTranslations "github.com/go-playground/validator/v10/translations/zh"
 )
 
-var trans ut.Translator
+// uni 包含所有支持语言的翻译器，每个请求按协商出的语言选择
+var uni *ut.UniversalTranslator
 
 // InitTrans 初始化翻译器
-// 参数: locale - 语言环境
+// 参数: locale - 默认语言，客户端没有通过 Accept-Language 指定支持的语言时使用
 // 返回值: 错误对象，如果初始化失败则返回错误
 func InitTrans(locale string) (err error) {
+	if !supportedLocale(locale) {
+		return fmt.Errorf("unsupported locale %q", locale)
+	}
+	defaultLocale = locale
+
 	//修改gin框架中的Validator引擎属性，实现自定制
-	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
 
-		//注册一个获取json tag的自定义方法
-		v.RegisterTagNameFunc(func(fld reflect.StructField) string {
-			name := strings.SplitN(fld.Tag.Get("json"), ",", 2)[0]
+	//注册一个获取json tag的自定义方法
+	v.RegisterTagNameFunc(func(fld reflect.StructField) string {
+		name := strings.SplitN(fld.Tag.Get("json"), ",", 2)[0]
 
-			if name == "-" {
-				return ""
-			}
-			return name
-		})
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
 
-		//为SignUpPrarm注册自定义校验方法
-		v.RegisterStructValidation(SignUpParamStructLevelValidation, models.ParamSignUp{})
+	//为SignUpPrarm注册自定义校验方法
+	v.RegisterStructValidation(SignUpParamStructLevelValidation, models.ParamSignUp{})
 
-		zhT := zh.New() //中文
-		enT := en.New() //英文翻译器
+	zhT := zh.New() //中文
+	enT := en.New() //英文翻译器
 
-		//初始化统一翻译器
-		uni := ut.New(enT, zhT, enT)
+	//初始化统一翻译器
+	uni = ut.New(enT, zhT, enT)
 
-		//获取指定语言环境的翻译器
-		var ok bool
-		trans, ok = uni.GetTranslator(locale)
+	//为每种语言注册翻译
+	for lang, register := range map[string]func(*validator.Validate, ut.Translator) error{
+		LocaleEN: enTranslations.RegisterDefaultTranslations,
+		LocaleZH: zhTranslations.RegisterDefaultTranslations,
+	} {
+		trans, ok := uni.GetTranslator(lang)
 		if !ok {
-			return fmt.Errorf("uni.GetTranslator(%s) failed", locale)
+			return fmt.Errorf("uni.GetTranslator(%s) failed", lang)
 		}
-
-		//注册翻译器
-		switch locale {
-		case "en":
-			err = enTranslations.RegisterDefaultTranslations(v, trans)
-		case "zh":
-			err = zhTranslations.RegisterDefaultTranslations(v, trans)
-		default:
-			err = enTranslations.RegisterDefaultTranslations(v, trans)
+		if err = register(v, trans); err != nil {
+			return
 		}
 	}
 	return
 }
 
-// removeTopStruct 去除提示信息中的结构体名称
-// 参数: fields - 包含错误提示信息的映射
-// 返回值: 去除结构体名称后的错误提示信息映射
-
-func removeTopStruct(fields map[string]string) map[string]string {
-	res := map[string]string{}
-	for field, err := range fields {
-		res[field[strings.Index(field, ".")+1:]] = err
+// getTranslator 返回指定语言的翻译器
+func getTranslator(locale string) ut.Translator {
+	if trans, ok := uni.GetTranslator(locale); ok {
+		return trans
 	}
-	return res
+	trans, _ := uni.GetTranslator(defaultLocale)
+	return trans
 }
 
 // SignUpParamStructLevelValidation 自定义SignUpParam结构体校验函数
@@ -1,12 +1,12 @@
 package controller
 
 import (
+	"blue-bell_back/logger"
 	"blue-bell_back/logic"
 	"blue-bell_back/models"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
-	"github.com/go-playground/validator/v10"
 	"go.uber.org/zap"
 )
 
@@ -16,15 +16,8 @@ import (
 func CommunityVote(c *gin.Context) {
 	//参数校验
 	p := new(models.ParamCommunityVote)
-	if err := c.ShouldBindJSON(p); err != nil {
-		//类型断言
-		errs, ok := err.(validator.ValidationErrors)
-		if !ok {
-			ResponseError(c, CodeInvalidParam)
-			return
-		}
-		errData := removeTopStruct(errs.Translate(trans))
-		ResponseErrorWithMsg(c, CodeInvalidParam, errData)
+	if !bindJSON(c, p) {
+		return
 	}
 
 	//获取用户id
@@ -35,9 +28,9 @@ func CommunityVote(c *gin.Context) {
 	}
 
 	//具体的投票业务逻辑
-	if err := logic.CommunityVote(strconv.FormatUint(uint64(userID), 10), p); err != nil {
-		zap.L().Error(" service.CommunityVote failed.", zap.Error(err))
-		ResponseError(c, CodeServerBusy)
+	if err := logic.CommunityVote(c.Request.Context(), strconv.FormatUint(uint64(userID), 10), p); err != nil {
+		logger.FromContext(c.Request.Context()).Error(" service.CommunityVote failed.", zap.Error(err))
+		ResponseErrorFrom(c, err)
 		return
 	}
 
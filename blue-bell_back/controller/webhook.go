@@ -0,0 +1,119 @@
+package controller
+
+import (
+	"blue-bell_back/logger"
+	"blue-bell_back/logic"
+	"blue-bell_back/models"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// 社区事件推送的订阅
+
+// CreateWebhookHandler 创建订阅，响应中的secret只返回这一次
+func CreateWebhookHandler(c *gin.Context) {
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	p := new(models.ParamCreateWebhook)
+	if !bindJSON(c, p) {
+		return
+	}
+	w, err := logic.CreateWebhook(c.Request.Context(), userID, p)
+	if err != nil {
+		logger.FromContext(c.Request.Context()).Error("logic.CreateWebhook failed", zap.Error(err))
+		ResponseErrorFrom(c, err)
+		return
+	}
+	ResponseSuccess(c, w)
+}
+
+// WebhookListHandler 查询当前用户创建的订阅
+func WebhookListHandler(c *gin.Context) {
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	list, err := logic.GetWebhooks(c.Request.Context(), userID)
+	if err != nil {
+		logger.FromContext(c.Request.Context()).Error("logic.GetWebhooks failed", zap.Error(err))
+		ResponseErrorFrom(c, err)
+		return
+	}
+	ResponseSuccess(c, list)
+}
+
+// DeleteWebhookHandler 删除订阅
+func DeleteWebhookHandler(c *gin.Context) {
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	webhookID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	if err := logic.DeleteWebhook(c.Request.Context(), userID, webhookID); err != nil {
+		logger.FromContext(c.Request.Context()).Error("logic.DeleteWebhook failed", zap.Error(err))
+		ResponseErrorFrom(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// WebhookDeliveriesHandler 分页查询订阅的推送记录
+func WebhookDeliveriesHandler(c *gin.Context) {
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	webhookID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	p := new(models.ParamWebhookDeliveries)
+	if !bindQuery(c, p) {
+		return
+	}
+	list, err := logic.GetWebhookDeliveries(c.Request.Context(), userID, webhookID, p)
+	if err != nil {
+		logger.FromContext(c.Request.Context()).Error("logic.GetWebhookDeliveries failed", zap.Error(err))
+		ResponseErrorFrom(c, err)
+		return
+	}
+	ResponseSuccess(c, list)
+}
+
+// ReplayWebhookDeliveryHandler 重新发送一条推送记录
+func ReplayWebhookDeliveryHandler(c *gin.Context) {
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	webhookID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	deliveryID, err := strconv.ParseInt(c.Param("delivery_id"), 10, 64)
+	if err != nil {
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	if err := logic.ReplayWebhookDelivery(c.Request.Context(), userID, webhookID, deliveryID); err != nil {
+		logger.FromContext(c.Request.Context()).Error("logic.ReplayWebhookDelivery failed", zap.Error(err))
+		ResponseErrorFrom(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
@@ -1,8 +1,12 @@
 package mysql
 
 import (
+	"blue-bell_back/logger"
 	"blue-bell_back/models"
+	"blue-bell_back/pkg/errs"
+	"context"
 	"database/sql"
+	"errors"
 	"strings"
 
 	"github.com/jmoiron/sqlx"
@@ -13,14 +17,17 @@ import (
 //此函数从数据库中查询所有社区的信息，并返回社区列表
 // 如果查询结果为空， 则不会返回错误，而是将错误置空
 
-func GetCommunityList() (communityList []*models.Community, err error) {
+func GetCommunityList(ctx context.Context) (communityList []*models.Community, err error) {
 	sqlStr := "select community_id, community_name from community"
-	if err := db.Select(&communityList, sqlStr); err != nil {
+	ctx, span := startSpan(ctx, "GetCommunityList", sqlStr)
+	defer span.Finish(&err)
+	if err = db.SelectContext(ctx, &communityList, sqlStr); err != nil {
 		//如果查询为空
-		if err == sql.ErrNoRows {
-			zap.L().Warn("no result from community table")
-			err = nil
+		if errors.Is(err, sql.ErrNoRows) {
+			logger.FromContext(ctx).Warn("no result from community table")
+			return nil, nil
 		}
+		return nil, errs.Wrap(err, "mysql.GetCommunityList")
 	}
 	return
 }
@@ -28,84 +35,118 @@ func GetCommunityList() (communityList []*models.Community, err error) {
 // GetCommunityByID 根据社区ID查询社区详情
 // 此函数根据给定的社区ID查询社区的详细信息。
 // 如果ID有效且找到对应的社区，则返回社区详情。
-// 如果ID无效或没有找到对应的社区，则返回ErrorInvalidID错误。
-func GetCommunityByID(id int64) (communityDetail *models.CommunityDetail, err error) {
+// 如果没有找到对应的社区，则返回ErrorCommunityNotExist错误。
+func GetCommunityByID(ctx context.Context, id int64) (communityDetail *models.CommunityDetail, err error) {
 	//申请内存
 	communityDetail = new(models.CommunityDetail)
-	sqlStr := "select community_id, community_name, introducion, create_time from community where id = ?"
+	sqlStr := "select community_id, community_name, introduction, create_time from community where community_id = ?"
+	ctx, span := startSpan(ctx, "GetCommunityByID", sqlStr)
+	defer span.Finish(&err)
 
-	if err := db.Get(communityDetail, sqlStr, id); err != nil {
-		//判断id是否有效
-		if err == sql.ErrNoRows {
-			err = ErrorInvalidID
-			return nil, err
-		}
+	err = db.GetContext(ctx, communityDetail, sqlStr, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrorCommunityNotExist
+	}
+	if err != nil {
+		return nil, errs.Wrap(err, "mysql.GetCommunityByID")
 	}
 	return
 }
 
 // CreateCommunityPost 创建社区的帖子
-// 参数： post：指向包含帖子信息的CommunityPost结构体的指针，包含帖子ID、标题、作者ID、社区ID和内容
-// 返回值：如果插入操作成功，则返回nil；否则返回错误信息
-func CreateCommunityPost(post *models.CommunityPost) (err error) {
+// 参数： post：指向包含帖子信息的CommunityPost结构体的指针，包含帖子ID、标题、作者ID、社区ID、内容和标签
+// 返回值：在同一个事务中写入的发件箱消息，由logic层同步到Redis；插入失败时返回错误信息
+func CreateCommunityPost(ctx context.Context, post *models.CommunityPost) (msg *models.OutboxMessage, err error) {
 	//定义sql语句来插入帖子信息到数据库
-	sqlStr := "insert into post(post_id, title, author_id, community_id, content) value(?,?,?,?,?)"
+	sqlStr := "insert into post(post_id, title, author_id, community_id, content, create_time) value(?,?,?,?,?,?)"
+	ctx, span := startSpan(ctx, "CreateCommunityPost", sqlStr)
+	defer span.Finish(&err)
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
 	//执行sql语句，插入帖子信息，并检查是否有错误发生
-	_, err = db.Exec(sqlStr, post.ID, post.Title, post.AuthorID, post.CommunityID, post.Content)
-
+	_, err = tx.ExecContext(ctx, sqlStr, post.ID, post.Title, post.AuthorID, post.CommunityID, post.Content, post.CreateTime)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	return
+	if err = insertPostTags(ctx, tx, post.ID, post.Tags); err != nil {
+		return nil, err
+	}
+	msg, err = insertOutbox(ctx, tx, models.OutboxPostCreated, &models.OutboxPost{
+		PostID:      post.ID,
+		CommunityID: post.CommunityID,
+		AuthorID:    post.AuthorID,
+		Tags:        post.Tags,
+		CreateTime:  post.CreateTime.Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return msg, tx.Commit()
 }
 
 // GetAuthorNameById 根据用户id查询用户名称
-func GetAuthorNameById(userID uint64) (user *models.User, err error) {
+func GetAuthorNameById(ctx context.Context, userID uint64) (user *models.User, err error) {
 	user = new(models.User)
 	sqlStr := "select user_id, username from user where user_id = ?"
-	if err := db.Get(user, sqlStr, userID); err != nil {
-		//判断id是否有效
-		if err == sql.ErrNoRows {
-			err = ErrorInvalidID
-			return nil, err
-		}
+	ctx, span := startSpan(ctx, "GetAuthorNameById", sqlStr)
+	defer span.Finish(&err)
+	err = db.GetContext(ctx, user, sqlStr, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrorUserNotExist
+	}
+	if err != nil {
+		return nil, errs.Wrap(err, "mysql.GetAuthorNameById")
 	}
 	return
 }
 
 // GetPostDetailByID 根据帖子ID查询帖子详情
-func GetPostDetailByID(postId uint64) (postDetail *models.CommunityPost, err error) {
+func GetPostDetailByID(ctx context.Context, postId uint64) (postDetail *models.CommunityPost, err error) {
 	postDetail = new(models.CommunityPost)
-	sqlStr := "select post_id, title, content, author_id, community_id, status, create_time from post where post_id = ?"
+	sqlStr := "select post_id, title, content, author_id, community_id, status, create_time from post where post_id = ? and status = ?"
+	ctx, span := startSpan(ctx, "GetPostDetailByID", sqlStr)
+	defer span.Finish(&err)
 	//执行sql查询，并将结果存储到postDetail中
-	if err := db.Get(postDetail, sqlStr, postId); err != nil {
-		if err == sql.ErrNoRows {
-			err = ErrorInvalidID
-			return nil, err
-		}
+	err = db.GetContext(ctx, postDetail, sqlStr, postId, models.PostStatusNormal)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrorPostNotExist
+	}
+	if err != nil {
+		return nil, errs.Wrap(err, "mysql.GetPostDetailByID")
 	}
 	return
 }
 
 //GetPostList查询帖子列表
 
-func GetPostList(page, size int64) (list []*models.CommunityPost, err error) {
+func GetPostList(ctx context.Context, page, size int64) (list []*models.CommunityPost, err error) {
 	list = make([]*models.CommunityPost, 0, 2)
-	sqlStr := "select post_id, title, content, author_id, community_id, status, create_time from post order by create_time desc limit ?,?"
-	err = db.Select(&list, sqlStr, (page-1)*size, size)
+	sqlStr := "select post_id, title, content, author_id, community_id, status, create_time from post where status = ? order by create_time desc limit ?,?"
+	ctx, span := startSpan(ctx, "GetPostList", sqlStr)
+	defer span.Finish(&err)
+	err = db.SelectContext(ctx, &list, sqlStr, models.PostStatusNormal, (page-1)*size, size)
 	return
 }
 
 //CheckPostExist 检查帖子是否存在
 
-func CheckPostExist(id string) (exist bool, err error) {
+func CheckPostExist(ctx context.Context, id string) (exist bool, err error) {
 	var count int
 
-	sqlStr := "select count(community_id) from post where post_id = ?"
-	if err := db.Get(&count, sqlStr, id); err != nil {
+	sqlStr := "select count(community_id) from post where post_id = ? and status = ?"
+	ctx, span := startSpan(ctx, "CheckPostExist", sqlStr)
+	defer span.Finish(&err)
+	if err := db.GetContext(ctx, &count, sqlStr, id, models.PostStatusNormal); err != nil {
 		return exist, err
 	}
-	zap.L().Debug("select count from post", zap.Int("count:", count))
+	logger.FromContext(ctx).Debug("select count from post", zap.Int("count:", count))
 	if count > 0 {
 		return true, nil
 	}
@@ -114,13 +155,15 @@ func CheckPostExist(id string) (exist bool, err error) {
 }
 
 // GetPostOrderList 根据redis查询的id查询对应的帖子详情
-func GetPostOrderList(ids []string) (postList []*models.CommunityPost, err error) {
+func GetPostOrderList(ctx context.Context, ids []string) (postList []*models.CommunityPost, err error) {
 	sqlStr := `select post_id, title, content, author_id, community_id, create_time
 	from post 
-	where post_id in (?)
+	where post_id in (?) and status = ?
 	order by FIND_IN_SET(post_id, ?)`
+	ctx, span := startSpan(ctx, "GetPostOrderList", sqlStr)
+	defer span.Finish(&err)
 
-	query, args, err := sqlx.In(sqlStr, ids, strings.Join(ids, ","))
+	query, args, err := sqlx.In(sqlStr, ids, models.PostStatusNormal, strings.Join(ids, ","))
 	/*
 		FIND_IN_SET(str,strList)
 		str 要查询的字符串
@@ -139,6 +182,68 @@ func GetPostOrderList(ids []string) (postList []*models.CommunityPost, err error
 
 	//sqlx.In 返回带`?` bind-var的查询语句，我们使用Rebind()重新绑定
 	query = db.Rebind(query)
-	err = db.Select(&postList, query, args...)
+	err = db.SelectContext(ctx, &postList, query, args...)
+	return
+}
+
+// GetPostsByAuthor 查询用户发布的所有未删除帖子，只包含帖子ID、作者ID和社区ID
+func GetPostsByAuthor(ctx context.Context, authorID int64) (list []*models.CommunityPost, err error) {
+	sqlStr := "select post_id, author_id, community_id from post where author_id = ? and status = ?"
+	ctx, span := startSpan(ctx, "GetPostsByAuthor", sqlStr)
+	defer span.Finish(&err)
+	err = db.SelectContext(ctx, &list, sqlStr, authorID, models.PostStatusNormal)
 	return
 }
+
+// GetPostsAfter 按帖子ID顺序查询afterID之后的未删除帖子，只包含帖子ID、作者ID和发帖时间，用于遍历所有帖子
+func GetPostsAfter(ctx context.Context, afterID, size int64) (list []*models.CommunityPost, err error) {
+	sqlStr := "select post_id, author_id, create_time from post where post_id > ? and status = ? order by post_id limit ?"
+	ctx, span := startSpan(ctx, "GetPostsAfter", sqlStr)
+	defer span.Finish(&err)
+	err = db.SelectContext(ctx, &list, sqlStr, afterID, models.PostStatusNormal, size)
+	return
+}
+
+// SoftDeletePosts 把帖子标记为已删除，在同一个事务中减少标签的使用次数并为每个帖子写入发件箱消息
+// posts 需要包含帖子的标签
+func SoftDeletePosts(ctx context.Context, posts []*models.CommunityPost) (msgs []*models.OutboxMessage, err error) {
+	sqlStr := "update post set status = ? where post_id = ? and status = ?"
+	ctx, span := startSpan(ctx, "SoftDeletePosts", sqlStr)
+	defer span.Finish(&err)
+	if len(posts) == 0 {
+		return
+	}
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	msgs = make([]*models.OutboxMessage, 0, len(posts))
+	for _, p := range posts {
+		res, err := tx.ExecContext(ctx, sqlStr, models.PostStatusDeleted, p.ID, models.PostStatusNormal)
+		if err != nil {
+			return nil, err
+		}
+		// 已经删除过的帖子不重复减少标签的使用次数
+		if n, _ := res.RowsAffected(); n > 0 {
+			if err = decrTagCounts(ctx, tx, p.Tags); err != nil {
+				return nil, err
+			}
+		}
+		msg, err := insertOutbox(ctx, tx, models.OutboxPostDeleted, &models.OutboxPost{
+			PostID:      p.ID,
+			CommunityID: p.CommunityID,
+			AuthorID:    p.AuthorID,
+			Tags:        p.Tags,
+		})
+		if err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs, tx.Commit()
+}
@@ -1,10 +1,27 @@
 package mysql
 
-import "errors"
+import (
+	"blue-bell_back/pkg/errs"
+	"errors"
+
+	"github.com/go-sql-driver/mysql"
+)
 
 var (
-	ErrorUserExist       = errors.New("用户已存在")
-	ErrorUserNotExist    = errors.New("用户不存在")
-	ErrorInvalidPassword = errors.New("用户名或密码错误")
-	ErrorInvalidID       = errors.New("无效的ID")
+	ErrorUserExist         = errs.Conflict("用户已存在")
+	ErrorUserNotExist      = errs.NotFound("用户不存在")
+	ErrorInvalidPassword   = errors.New("用户名或密码错误")
+	ErrorPasswordNotSet    = errs.Forbidden("账户未设置密码")
+	ErrorEmailExist        = errs.Conflict("邮箱已被注册")
+	ErrorPostNotExist      = errs.NotFound("帖子不存在")
+	ErrorCommunityNotExist = errs.NotFound("社区不存在")
 )
+
+// mysqlErrDupEntry 唯一索引冲突的错误码
+const mysqlErrDupEntry = 1062
+
+// isDuplicateEntry 判断是否是唯一索引冲突
+func isDuplicateEntry(err error) bool {
+	var me *mysql.MySQLError
+	return errors.As(err, &me) && me.Number == mysqlErrDupEntry
+}
@@ -0,0 +1,62 @@
+package mysql
+
+import (
+	"context"
+)
+
+// Follow 关注用户，已经关注过时不做修改
+func Follow(ctx context.Context, followerID, followeeID int64) (err error) {
+	sqlStr := `insert ignore into follow(follower_id, followee_id) values(?,?)`
+	ctx, span := startSpan(ctx, "Follow", sqlStr)
+	defer span.Finish(&err)
+	_, err = db.ExecContext(ctx, sqlStr, followerID, followeeID)
+	return
+}
+
+// Unfollow 取消关注
+func Unfollow(ctx context.Context, followerID, followeeID int64) (err error) {
+	sqlStr := `delete from follow where follower_id = ? and followee_id = ?`
+	ctx, span := startSpan(ctx, "Unfollow", sqlStr)
+	defer span.Finish(&err)
+	_, err = db.ExecContext(ctx, sqlStr, followerID, followeeID)
+	return
+}
+
+// IsFollowing 判断是否已经关注
+func IsFollowing(ctx context.Context, followerID, followeeID int64) (ok bool, err error) {
+	sqlStr := `select count(*) from follow where follower_id = ? and followee_id = ?`
+	ctx, span := startSpan(ctx, "IsFollowing", sqlStr)
+	defer span.Finish(&err)
+	var n int64
+	err = db.GetContext(ctx, &n, sqlStr, followerID, followeeID)
+	return n > 0, err
+}
+
+// CountFollows 查询用户的粉丝数和关注数
+func CountFollows(ctx context.Context, userID int64) (followers, following int64, err error) {
+	sqlStr := `select
+	(select count(*) from follow where followee_id = ?) as followers,
+	(select count(*) from follow where follower_id = ?) as following`
+	ctx, span := startSpan(ctx, "CountFollows", sqlStr)
+	defer span.Finish(&err)
+	err = db.QueryRowxContext(ctx, sqlStr, userID, userID).Scan(&followers, &following)
+	return
+}
+
+// GetFolloweeIDs 查询用户关注的所有用户ID
+func GetFolloweeIDs(ctx context.Context, userID int64) (ids []int64, err error) {
+	sqlStr := `select followee_id from follow where follower_id = ?`
+	ctx, span := startSpan(ctx, "GetFolloweeIDs", sqlStr)
+	defer span.Finish(&err)
+	err = db.SelectContext(ctx, &ids, sqlStr, userID)
+	return
+}
+
+// DeleteFollowsByUser 删除用户关注别人和被别人关注的记录
+func DeleteFollowsByUser(ctx context.Context, userID int64) (err error) {
+	sqlStr := `delete from follow where follower_id = ? or followee_id = ?`
+	ctx, span := startSpan(ctx, "DeleteFollowsByUser", sqlStr)
+	defer span.Finish(&err)
+	_, err = db.ExecContext(ctx, sqlStr, userID, userID)
+	return
+}
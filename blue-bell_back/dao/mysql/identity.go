@@ -0,0 +1,92 @@
+package mysql
+
+import (
+	"blue-bell_back/models"
+	"blue-bell_back/pkg/errs"
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// ErrorIdentityExist 第三方账户已经绑定了其他用户
+var ErrorIdentityExist = errs.Conflict("第三方账户已被绑定")
+
+// GetUserIDByIdentity 根据第三方账户查询绑定的用户ID，没有绑定时返回ErrorUserNotExist
+func GetUserIDByIdentity(ctx context.Context, provider, subject string) (userID int64, err error) {
+	sqlStr := `select user_id from user_identity where provider = ? and subject = ?`
+	ctx, span := startSpan(ctx, "GetUserIDByIdentity", sqlStr)
+	defer span.Finish(&err)
+	err = db.GetContext(ctx, &userID, sqlStr, provider, subject)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrorUserNotExist
+	}
+	return
+}
+
+// InsertIdentity 为已有用户绑定第三方账户
+func InsertIdentity(ctx context.Context, identity *models.UserIdentity) (err error) {
+	sqlStr := `insert into user_identity(user_id, provider, subject, email) values(?,?,?,?)`
+	ctx, span := startSpan(ctx, "InsertIdentity", sqlStr)
+	defer span.Finish(&err)
+	_, err = db.ExecContext(ctx, sqlStr, identity.UserID, identity.Provider, identity.Subject, identity.Email)
+	if isDuplicateEntry(err) {
+		return ErrorIdentityExist
+	}
+	return
+}
+
+// InsertUserWithIdentity 在同一个事务中创建用户并绑定第三方账户
+// 第三方登录创建的用户没有密码，只能通过第三方登录或找回密码设置密码后登录
+// 邮箱已被注册时返回 ErrorEmailExist，第三方账户已绑定时返回 ErrorIdentityExist
+func InsertUserWithIdentity(ctx context.Context, user *models.User, identity *models.UserIdentity) (err error) {
+	sqlStr := `insert into user(user_id, username, password, email, email_verified) values(?,?,'',?,?)`
+	ctx, span := startSpan(ctx, "InsertUserWithIdentity", sqlStr)
+	defer span.Finish(&err)
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	var email interface{}
+	if user.Email != "" {
+		email = user.Email
+	}
+	if _, err = tx.ExecContext(ctx, sqlStr, user.UserID, user.UserName, email, user.EmailVerified); err != nil {
+		if isDuplicateEntry(err) {
+			err = ErrorEmailExist
+		}
+		return
+	}
+	_, err = tx.ExecContext(ctx, `insert into user_identity(user_id, provider, subject, email) values(?,?,?,?)`,
+		user.UserID, identity.Provider, identity.Subject, identity.Email)
+	if isDuplicateEntry(err) {
+		return ErrorIdentityExist
+	}
+	if err != nil {
+		return
+	}
+	return tx.Commit()
+}
+
+// GetIdentitiesByUser 查询用户绑定的所有第三方账户
+func GetIdentitiesByUser(ctx context.Context, userID int64) (list []*models.UserIdentity, err error) {
+	sqlStr := `select id, user_id, provider, subject, email, create_time from user_identity where user_id = ? order by id`
+	ctx, span := startSpan(ctx, "GetIdentitiesByUser", sqlStr)
+	defer span.Finish(&err)
+	list = make([]*models.UserIdentity, 0)
+	err = db.SelectContext(ctx, &list, sqlStr, userID)
+	return
+}
+
+// DeleteIdentitiesByUser 解除用户绑定的所有第三方账户
+func DeleteIdentitiesByUser(ctx context.Context, userID int64) (err error) {
+	sqlStr := `delete from user_identity where user_id = ?`
+	ctx, span := startSpan(ctx, "DeleteIdentitiesByUser", sqlStr)
+	defer span.Finish(&err)
+	_, err = db.ExecContext(ctx, sqlStr, userID)
+	return
+}
@@ -0,0 +1,29 @@
+package mysql
+
+import (
+	"blue-bell_back/models"
+	"context"
+)
+
+// InsertLoginHistory 记录一次登录
+func InsertLoginHistory(ctx context.Context, h *models.LoginHistory) (err error) {
+	sqlStr := `insert into login_history(user_id, ip, user_agent, success) values(?,?,?,?)`
+	ctx, span := startSpan(ctx, "InsertLoginHistory", sqlStr)
+	defer span.Finish(&err)
+	_, err = db.ExecContext(ctx, sqlStr, h.UserID, h.IP, h.UserAgent, h.Success)
+	return
+}
+
+// GetLoginHistory 按时间倒序分页查询用户的登录记录
+func GetLoginHistory(ctx context.Context, userID, page, size int64) (list []*models.LoginHistory, err error) {
+	sqlStr := `select id, user_id, ip, user_agent, success, create_time
+	from login_history
+	where user_id = ?
+	order by id desc
+	limit ?,?`
+	ctx, span := startSpan(ctx, "GetLoginHistory", sqlStr)
+	defer span.Finish(&err)
+	list = make([]*models.LoginHistory, 0)
+	err = db.SelectContext(ctx, &list, sqlStr, userID, (page-1)*size, size)
+	return
+}
@@ -2,10 +2,13 @@ package mysql
 
 import (
 	"blue-bell_back/settings"
+	"context"
 	"fmt"
 
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/jmoiron/sqlx"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 	"go.uber.org/zap"
 )
 
@@ -39,9 +42,61 @@ func Init(cfg *settings.MySQLConfig) (err error) {
 	db.SetMaxOpenConns(cfg.MaxOpenConns)
 	//设置数据库连接吃的最大空闲连接数
 	db.SetMaxIdleConns(cfg.MaxIdleConns)
+
+	//连接池大小支持热加载
+	settings.Subscribe(onConfigChange)
+	registerMetrics()
 	return
 }
 
+// registerMetrics 注册连接池状态指标，采集时从 db.Stats() 读取
+func registerMetrics() {
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "bluebell_mysql_open_connections",
+		Help: "Number of established MySQL connections, both in use and idle.",
+	}, func() float64 { return float64(db.Stats().OpenConnections) })
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "bluebell_mysql_in_use_connections",
+		Help: "Number of MySQL connections currently in use.",
+	}, func() float64 { return float64(db.Stats().InUse) })
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "bluebell_mysql_idle_connections",
+		Help: "Number of idle MySQL connections.",
+	}, func() float64 { return float64(db.Stats().Idle) })
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "bluebell_mysql_max_open_connections",
+		Help: "Maximum number of open MySQL connections.",
+	}, func() float64 { return float64(db.Stats().MaxOpenConnections) })
+	promauto.NewCounterFunc(prometheus.CounterOpts{
+		Name: "bluebell_mysql_wait_count_total",
+		Help: "Total number of MySQL connections waited for.",
+	}, func() float64 { return float64(db.Stats().WaitCount) })
+	promauto.NewCounterFunc(prometheus.CounterOpts{
+		Name: "bluebell_mysql_wait_duration_seconds_total",
+		Help: "Total time blocked waiting for a new MySQL connection.",
+	}, func() float64 { return db.Stats().WaitDuration.Seconds() })
+}
+
+// onConfigChange 配置热加载时调整连接池大小
+func onConfigChange(oldConf, newConf *settings.AppConfig) {
+	if oldConf.MySQLConfig == nil || newConf.MySQLConfig == nil {
+		return
+	}
+	if newConf.MaxOpenConns != oldConf.MaxOpenConns {
+		db.SetMaxOpenConns(newConf.MaxOpenConns)
+		zap.L().Info("mysql max_open_conns changed", zap.Int("max_open_conns", newConf.MaxOpenConns))
+	}
+	if newConf.MaxIdleConns != oldConf.MaxIdleConns {
+		db.SetMaxIdleConns(newConf.MaxIdleConns)
+		zap.L().Info("mysql max_idle_conns changed", zap.Int("max_idle_conns", newConf.MaxIdleConns))
+	}
+}
+
 func Close() {
 	_ = db.Close()
 }
+
+// Ping 检查数据库连接是否可用
+func Ping(ctx context.Context) error {
+	return db.PingContext(ctx)
+}
@@ -0,0 +1,143 @@
+package mysql
+
+import (
+	"blue-bell_back/models"
+	"context"
+	"strconv"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// notifyAggKey 未读通知的聚合键，同一个帖子的同类未读通知只有一条
+func notifyAggKey(typ string, postID int64) string {
+	return typ + ":" + strconv.FormatInt(postID, 10)
+}
+
+// UpsertNotification 添加一条通知，已有同类未读通知时聚合人数加1
+func UpsertNotification(ctx context.Context, userID int64, typ string, postID, actorID int64) (err error) {
+	sqlStr := `insert into notification(user_id, type, post_id, last_actor_id, agg_key) values(?,?,?,?,?)
+	on duplicate key update actor_count = actor_count + 1, last_actor_id = values(last_actor_id)`
+	ctx, span := startSpan(ctx, "UpsertNotification", sqlStr)
+	defer span.Finish(&err)
+	_, err = db.ExecContext(ctx, sqlStr, userID, typ, postID, actorID, notifyAggKey(typ, postID))
+	return
+}
+
+// GetNotifications 按更新时间倒序分页查询用户的通知，同时查询帖子标题
+func GetNotifications(ctx context.Context, userID, page, size int64) (list []*models.Notification, err error) {
+	sqlStr := `select n.id, n.user_id, n.type, n.post_id, ifnull(p.title, '') as post_title, n.actor_count,
+	n.last_actor_id, n.is_read, n.create_time, n.update_time
+	from notification n left join post p on p.post_id = n.post_id
+	where n.user_id = ?
+	order by n.update_time desc, n.id desc
+	limit ?,?`
+	ctx, span := startSpan(ctx, "GetNotifications", sqlStr)
+	defer span.Finish(&err)
+	list = make([]*models.Notification, 0)
+	err = db.SelectContext(ctx, &list, sqlStr, userID, (page-1)*size, size)
+	return
+}
+
+// CountUnreadNotifications 查询用户的未读通知数量
+func CountUnreadNotifications(ctx context.Context, userID int64) (count int64, err error) {
+	sqlStr := `select count(*) from notification where user_id = ? and is_read = 0`
+	ctx, span := startSpan(ctx, "CountUnreadNotifications", sqlStr)
+	defer span.Finish(&err)
+	err = db.GetContext(ctx, &count, sqlStr, userID)
+	return
+}
+
+// GetUnreadNotifications 查询用户的未读通知，只包含ID、类型和帖子ID，ids为空时查询全部
+func GetUnreadNotifications(ctx context.Context, userID int64, ids []int64) (list []*models.Notification, err error) {
+	sqlStr := `select id, type, post_id from notification where user_id = ? and is_read = 0`
+	args := []interface{}{userID}
+	if len(ids) > 0 {
+		sqlStr += ` and id in (?)`
+		args = append(args, ids)
+	}
+	ctx, span := startSpan(ctx, "GetUnreadNotifications", sqlStr)
+	defer span.Finish(&err)
+	query, args, err := sqlx.In(sqlStr, args...)
+	if err != nil {
+		return nil, err
+	}
+	list = make([]*models.Notification, 0)
+	err = db.SelectContext(ctx, &list, db.Rebind(query), args...)
+	return
+}
+
+// MarkNotificationsRead 把用户的通知标记为已读，ids为空时标记全部，返回标记的数量
+// 已读的通知清除聚合键，之后的同类通知会新建一条
+func MarkNotificationsRead(ctx context.Context, userID int64, ids []int64) (affected int64, err error) {
+	sqlStr := `update notification set is_read = 1, agg_key = null where user_id = ? and is_read = 0`
+	args := []interface{}{userID}
+	if len(ids) > 0 {
+		sqlStr += ` and id in (?)`
+		args = append(args, ids)
+	}
+	ctx, span := startSpan(ctx, "MarkNotificationsRead", sqlStr)
+	defer span.Finish(&err)
+	query, args, err := sqlx.In(sqlStr, args...)
+	if err != nil {
+		return 0, err
+	}
+	res, err := db.ExecContext(ctx, db.Rebind(query), args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// GetNotifyPreferences 查询用户修改过的通知设置，没有记录的类型由调用方使用默认值
+func GetNotifyPreferences(ctx context.Context, userID int64) (prefs models.NotifyPreferences, err error) {
+	sqlStr := `select type, enabled from notification_preference where user_id = ?`
+	ctx, span := startSpan(ctx, "GetNotifyPreferences", sqlStr)
+	defer span.Finish(&err)
+	var rows []struct {
+		Type    string `db:"type"`
+		Enabled bool   `db:"enabled"`
+	}
+	if err = db.SelectContext(ctx, &rows, sqlStr, userID); err != nil {
+		return nil, err
+	}
+	prefs = make(models.NotifyPreferences, len(rows))
+	for _, r := range rows {
+		prefs[r.Type] = r.Enabled
+	}
+	return prefs, nil
+}
+
+// SetNotifyPreferences 在同一个事务中保存用户的通知设置
+func SetNotifyPreferences(ctx context.Context, userID int64, prefs models.NotifyPreferences) (err error) {
+	sqlStr := `insert into notification_preference(user_id, type, enabled) values(?,?,?)
+	on duplicate key update enabled = values(enabled)`
+	ctx, span := startSpan(ctx, "SetNotifyPreferences", sqlStr)
+	defer span.Finish(&err)
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	for typ, enabled := range prefs {
+		if _, err = tx.ExecContext(ctx, sqlStr, userID, typ, enabled); err != nil {
+			return
+		}
+	}
+	return tx.Commit()
+}
+
+// DeleteNotificationsByUser 删除用户的所有通知和通知设置
+func DeleteNotificationsByUser(ctx context.Context, userID int64) (err error) {
+	sqlStr := `delete from notification where user_id = ?`
+	ctx, span := startSpan(ctx, "DeleteNotificationsByUser", sqlStr)
+	defer span.Finish(&err)
+	if _, err = db.ExecContext(ctx, sqlStr, userID); err != nil {
+		return
+	}
+	_, err = db.ExecContext(ctx, `delete from notification_preference where user_id = ?`, userID)
+	return
+}
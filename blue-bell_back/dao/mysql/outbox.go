@@ -0,0 +1,77 @@
+package mysql
+
+import (
+	"blue-bell_back/models"
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// 事务发件箱
+// 需要同步到Redis的变更和业务数据在同一个事务中写入outbox表，提交后由logic层同步，失败时由后台任务重试
+
+// outboxInitialDelay 新消息被后台任务处理前的等待时间，提交后立即同步的过程中不会被后台任务同时处理
+const outboxInitialDelay = 30 * time.Second
+
+// insertOutbox 在事务中写入一条消息
+func insertOutbox(ctx context.Context, tx *sqlx.Tx, typ string, payload interface{}) (*models.OutboxMessage, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	res, err := tx.ExecContext(ctx, `insert into outbox(type, payload, next_retry_time)
+	values(?, ?, date_add(now(), interval ? second))`, typ, data, int64(outboxInitialDelay.Seconds()))
+	if err != nil {
+		return nil, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	return &models.OutboxMessage{ID: id, Type: typ, Payload: string(data)}, nil
+}
+
+// GetDueOutboxMessages 查询到了处理时间的消息
+func GetDueOutboxMessages(ctx context.Context, limit int) (list []*models.OutboxMessage, err error) {
+	sqlStr := `select id, type, payload, attempts from outbox where next_retry_time <= now()
+	order by next_retry_time, id limit ?`
+	ctx, span := startSpan(ctx, "GetDueOutboxMessages", sqlStr)
+	defer span.Finish(&err)
+	err = db.SelectContext(ctx, &list, sqlStr, limit)
+	return
+}
+
+// ClaimOutboxMessage 把到期的消息推迟一个租期，返回是否成功，多个实例同时处理时只有一个成功
+func ClaimOutboxMessage(ctx context.Context, id int64, lease time.Duration) (claimed bool, err error) {
+	sqlStr := `update outbox set next_retry_time = date_add(now(), interval ? second)
+	where id = ? and next_retry_time <= now()`
+	ctx, span := startSpan(ctx, "ClaimOutboxMessage", sqlStr)
+	defer span.Finish(&err)
+	res, err := db.ExecContext(ctx, sqlStr, int64(lease.Seconds()), id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// DeleteOutboxMessage 删除已经处理完成的消息
+func DeleteOutboxMessage(ctx context.Context, id int64) (err error) {
+	sqlStr := `delete from outbox where id = ?`
+	ctx, span := startSpan(ctx, "DeleteOutboxMessage", sqlStr)
+	defer span.Finish(&err)
+	_, err = db.ExecContext(ctx, sqlStr, id)
+	return
+}
+
+// RetryOutboxMessage 记录处理失败的原因，delay后再次处理
+func RetryOutboxMessage(ctx context.Context, id int64, delay time.Duration, lastError string) (err error) {
+	sqlStr := `update outbox set attempts = attempts + 1, last_error = ?,
+	next_retry_time = date_add(now(), interval ? second) where id = ?`
+	ctx, span := startSpan(ctx, "RetryOutboxMessage", sqlStr)
+	defer span.Finish(&err)
+	_, err = db.ExecContext(ctx, sqlStr, lastError, int64(delay.Seconds()), id)
+	return
+}
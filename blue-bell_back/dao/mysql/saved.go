@@ -0,0 +1,43 @@
+package mysql
+
+import (
+	"blue-bell_back/models"
+	"context"
+	"time"
+)
+
+// SavePost 收藏帖子，已经收藏过时不修改收藏时间
+func SavePost(ctx context.Context, userID, postID int64, saveTime time.Time) (err error) {
+	sqlStr := `insert ignore into saved_post(user_id, post_id, create_time) values(?,?,?)`
+	ctx, span := startSpan(ctx, "SavePost", sqlStr)
+	defer span.Finish(&err)
+	_, err = db.ExecContext(ctx, sqlStr, userID, postID, saveTime)
+	return
+}
+
+// UnsavePost 取消收藏
+func UnsavePost(ctx context.Context, userID, postID int64) (err error) {
+	sqlStr := `delete from saved_post where user_id = ? and post_id = ?`
+	ctx, span := startSpan(ctx, "UnsavePost", sqlStr)
+	defer span.Finish(&err)
+	_, err = db.ExecContext(ctx, sqlStr, userID, postID)
+	return
+}
+
+// GetSavedPosts 查询用户收藏的所有帖子，用于重建Redis中的收藏列表
+func GetSavedPosts(ctx context.Context, userID int64) (list []*models.SavedPost, err error) {
+	sqlStr := `select post_id, create_time from saved_post where user_id = ?`
+	ctx, span := startSpan(ctx, "GetSavedPosts", sqlStr)
+	defer span.Finish(&err)
+	err = db.SelectContext(ctx, &list, sqlStr, userID)
+	return
+}
+
+// DeleteSavedPostsByUser 删除用户的所有收藏
+func DeleteSavedPostsByUser(ctx context.Context, userID int64) (err error) {
+	sqlStr := `delete from saved_post where user_id = ?`
+	ctx, span := startSpan(ctx, "DeleteSavedPostsByUser", sqlStr)
+	defer span.Finish(&err)
+	_, err = db.ExecContext(ctx, sqlStr, userID)
+	return
+}
@@ -0,0 +1,66 @@
+package mysql
+
+import (
+	"blue-bell_back/models"
+	"context"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// insertPostTags 在事务中保存帖子的标签，并增加标签的使用次数
+func insertPostTags(ctx context.Context, tx *sqlx.Tx, postID int64, tags []string) error {
+	for _, tag := range tags {
+		if _, err := tx.ExecContext(ctx, `insert into post_tag(post_id, tag) values(?,?)`, postID, tag); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `insert into tag(name, post_count) values(?, 1)
+		on duplicate key update post_count = post_count + 1`, tag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decrTagCounts 在事务中减少标签的使用次数，帖子和标签的对应关系保留
+func decrTagCounts(ctx context.Context, tx *sqlx.Tx, tags []string) error {
+	for _, tag := range tags {
+		if _, err := tx.ExecContext(ctx, `update tag set post_count = post_count - 1
+		where name = ? and post_count > 0`, tag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetTagsByPostIDs 查询帖子的标签，返回帖子ID到标签列表的映射
+func GetTagsByPostIDs(ctx context.Context, postIDs []int64) (tags map[int64][]string, err error) {
+	sqlStr := `select post_id, tag from post_tag where post_id in (?) order by post_id, tag`
+	ctx, span := startSpan(ctx, "GetTagsByPostIDs", sqlStr)
+	defer span.Finish(&err)
+	tags = make(map[int64][]string)
+	if len(postIDs) == 0 {
+		return
+	}
+	query, args, err := sqlx.In(sqlStr, postIDs)
+	if err != nil {
+		return nil, err
+	}
+	var rows []*models.PostTag
+	if err = db.SelectContext(ctx, &rows, db.Rebind(query), args...); err != nil {
+		return nil, err
+	}
+	for _, r := range rows {
+		tags[r.PostID] = append(tags[r.PostID], r.Tag)
+	}
+	return tags, nil
+}
+
+// GetPopularTags 按使用次数倒序查询标签
+func GetPopularTags(ctx context.Context, size int64) (list []*models.Tag, err error) {
+	sqlStr := `select name, post_count from tag where post_count > 0 order by post_count desc, name limit ?`
+	ctx, span := startSpan(ctx, "GetPopularTags", sqlStr)
+	defer span.Finish(&err)
+	list = make([]*models.Tag, 0, size)
+	err = db.SelectContext(ctx, &list, sqlStr, size)
+	return
+}
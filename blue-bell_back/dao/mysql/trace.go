@@ -0,0 +1,14 @@
+package mysql
+
+import (
+	"blue-bell_back/pkg/trace"
+	"context"
+)
+
+// startSpan 为一次数据库操作创建链路节点
+func startSpan(ctx context.Context, name, sqlStr string) (context.Context, *trace.Span) {
+	return trace.StartWithKind(ctx, "mysql."+name, trace.KindClient,
+		trace.String("db.system", "mysql"),
+		trace.String("db.statement", sqlStr),
+	)
+}
@@ -2,10 +2,12 @@ package mysql
 
 import (
 	"blue-bell_back/models"
+	"context"
 	"crypto/md5"
 	"database/sql"
 	"encoding/hex"
 	"errors"
+	"fmt"
 )
 
 // secret 是用于密码加密的密钥
@@ -13,10 +15,12 @@ const secret = "https://gitee.com/Visitor-go/forum"
 
 // 参数: username - 待检查的用户名
 // 返回值: 如果用户名已存在或查询过程中出错，返回相应的错误
-func CheckUserExist(username string) (err error) {
+func CheckUserExist(ctx context.Context, username string) (err error) {
 	sqlStr := `select count(user_id) from user where username=?`
+	ctx, span := startSpan(ctx, "CheckUserExist", sqlStr)
+	defer span.Finish(&err)
 	var count int64
-	if err := db.Get(&count, sqlStr, username); err != nil {
+	if err := db.GetContext(ctx, &count, sqlStr, username); err != nil {
 		return err
 	}
 	if count > 0 {
@@ -26,11 +30,17 @@ func CheckUserExist(username string) (err error) {
 }
 
 // 参数:user - 包含用户信息的结构体指针
-// 返回值:如果插入过程中出错，返回相应的错误
-func InsertUser(user *models.User) (err error) {
+// 返回值:如果插入过程中出错，返回相应的错误，邮箱已被注册时返回 ErrorEmailExist
+func InsertUser(ctx context.Context, user *models.User) (err error) {
 	user.Password = encryptPassword(user.Password)
-	sqlStr := `insert into user(user_id, username, password) values(?,?,?)`
-	_, err = db.Exec(sqlStr, user.UserID, user.UserName, user.Password)
+	sqlStr := `insert into user(user_id, username, password, email) values(?,?,?,?)`
+	ctx, span := startSpan(ctx, "InsertUser", sqlStr)
+	defer span.Finish(&err)
+	_, err = db.ExecContext(ctx, sqlStr, user.UserID, user.UserName, user.Password, user.Email)
+	// user表只有邮箱有唯一索引，CheckEmailExist之后被并发注册时在这里冲突
+	if isDuplicateEntry(err) {
+		return ErrorEmailExist
+	}
 	return
 }
 
@@ -44,10 +54,12 @@ func encryptPassword(oPassword string) string {
 
 // 参数:user - 包含用户登录信息的结构体指针
 // 返回值:如果用户不存在或密码错误，返回相应的错误
-func Login(user *models.User) (err error) {
+func Login(ctx context.Context, user *models.User) (err error) {
 	oPassword := user.Password
 	sqlStr := `select user_id, username, password from user where username=?`
-	err = db.Get(user, sqlStr, user.UserName)
+	ctx, span := startSpan(ctx, "Login", sqlStr)
+	defer span.Finish(&err)
+	err = db.GetContext(ctx, user, sqlStr, user.UserName)
 
 	if errors.Is(err, sql.ErrNoRows) {
 		return ErrorUserNotExist
@@ -65,3 +77,119 @@ func Login(user *models.User) (err error) {
 	}
 	return
 }
+
+// CheckEmailExist 检查邮箱是否已被注册
+func CheckEmailExist(ctx context.Context, email string) (err error) {
+	sqlStr := `select count(user_id) from user where email=?`
+	ctx, span := startSpan(ctx, "CheckEmailExist", sqlStr)
+	defer span.Finish(&err)
+	var count int64
+	if err := db.GetContext(ctx, &count, sqlStr, email); err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrorEmailExist
+	}
+	return
+}
+
+// GetUserByID 根据用户id查询用户信息（不包含密码）
+func GetUserByID(ctx context.Context, userID int64) (user *models.User, err error) {
+	sqlStr := `select user_id, username, ifnull(email, '') as email, email_verified from user where user_id = ?`
+	ctx, span := startSpan(ctx, "GetUserByID", sqlStr)
+	defer span.Finish(&err)
+	user = new(models.User)
+	err = db.GetContext(ctx, user, sqlStr, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrorUserNotExist
+	}
+	return
+}
+
+// GetUserByEmail 根据邮箱查询用户信息（不包含密码）
+func GetUserByEmail(ctx context.Context, email string) (user *models.User, err error) {
+	sqlStr := `select user_id, username, ifnull(email, '') as email, email_verified from user where email = ?`
+	ctx, span := startSpan(ctx, "GetUserByEmail", sqlStr)
+	defer span.Finish(&err)
+	user = new(models.User)
+	err = db.GetContext(ctx, user, sqlStr, email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrorUserNotExist
+	}
+	return
+}
+
+// SetEmailVerified 把用户的邮箱标记为已验证
+func SetEmailVerified(ctx context.Context, userID int64) (err error) {
+	sqlStr := `update user set email_verified = 1 where user_id = ?`
+	ctx, span := startSpan(ctx, "SetEmailVerified", sqlStr)
+	defer span.Finish(&err)
+	_, err = db.ExecContext(ctx, sqlStr, userID)
+	return
+}
+
+// UpdatePassword 修改用户密码
+// 参数 password 为明文密码，保存前加密
+func UpdatePassword(ctx context.Context, userID int64, password string) (err error) {
+	sqlStr := `update user set password = ? where user_id = ?`
+	ctx, span := startSpan(ctx, "UpdatePassword", sqlStr)
+	defer span.Finish(&err)
+	_, err = db.ExecContext(ctx, sqlStr, encryptPassword(password), userID)
+	return
+}
+
+// CheckPassword 校验用户的密码
+// 参数 password 为明文密码
+func CheckPassword(ctx context.Context, userID int64, password string) (err error) {
+	sqlStr := `select password from user where user_id = ?`
+	ctx, span := startSpan(ctx, "CheckPassword", sqlStr)
+	defer span.Finish(&err)
+	var hashed string
+	err = db.GetContext(ctx, &hashed, sqlStr, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrorUserNotExist
+	}
+	if err != nil {
+		return err
+	}
+	// 第三方登录创建的用户没有密码
+	if hashed == "" {
+		return ErrorPasswordNotSet
+	}
+	if encryptPassword(password) != hashed {
+		return ErrorInvalidPassword
+	}
+	return
+}
+
+// AnonymizeUser 注销账户时清除用户的个人信息
+// 保留user_id，帖子等数据仍然可以关联；密码置空后无法再登录
+func AnonymizeUser(ctx context.Context, userID int64) (err error) {
+	sqlStr := `update user set username = ?, password = '', email = NULL, email_verified = 0, gender = 0, locale = '',
+	delete_time = now() where user_id = ?`
+	ctx, span := startSpan(ctx, "AnonymizeUser", sqlStr)
+	defer span.Finish(&err)
+	_, err = db.ExecContext(ctx, sqlStr, fmt.Sprintf("deleted_%d", userID), userID)
+	return
+}
+
+// GetUserLocale 查询用户设置的界面语言
+func GetUserLocale(ctx context.Context, userID int64) (locale string, err error) {
+	sqlStr := `select locale from user where user_id = ?`
+	ctx, span := startSpan(ctx, "GetUserLocale", sqlStr)
+	defer span.Finish(&err)
+	err = db.GetContext(ctx, &locale, sqlStr, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrorUserNotExist
+	}
+	return
+}
+
+// SetUserLocale 修改用户设置的界面语言
+func SetUserLocale(ctx context.Context, userID int64, locale string) (err error) {
+	sqlStr := `update user set locale = ? where user_id = ?`
+	ctx, span := startSpan(ctx, "SetUserLocale", sqlStr)
+	defer span.Finish(&err)
+	_, err = db.ExecContext(ctx, sqlStr, locale, userID)
+	return
+}
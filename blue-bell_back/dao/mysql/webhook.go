@@ -0,0 +1,167 @@
+package mysql
+
+import (
+	"blue-bell_back/models"
+	"blue-bell_back/pkg/errs"
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+var (
+	ErrorWebhookNotExist  = errs.NotFound("订阅不存在")
+	ErrorDeliveryNotExist = errs.NotFound("推送记录不存在")
+)
+
+// InsertWebhook 创建订阅
+func InsertWebhook(ctx context.Context, w *models.Webhook) (err error) {
+	sqlStr := `insert into webhook(webhook_id, user_id, community_id, url, events, secret) values(?,?,?,?,?,?)`
+	ctx, span := startSpan(ctx, "InsertWebhook", sqlStr)
+	defer span.Finish(&err)
+	_, err = db.ExecContext(ctx, sqlStr, w.ID, w.UserID, w.CommunityID, w.URL, w.Events, w.Secret)
+	return
+}
+
+// GetWebhooksByUser 查询用户创建的所有订阅，不包含密钥
+func GetWebhooksByUser(ctx context.Context, userID int64) (list []*models.Webhook, err error) {
+	sqlStr := `select webhook_id, user_id, community_id, url, events, create_time
+	from webhook where user_id = ? order by id desc`
+	ctx, span := startSpan(ctx, "GetWebhooksByUser", sqlStr)
+	defer span.Finish(&err)
+	list = make([]*models.Webhook, 0)
+	err = db.SelectContext(ctx, &list, sqlStr, userID)
+	return
+}
+
+// GetWebhookByID 查询订阅，包含密钥
+func GetWebhookByID(ctx context.Context, webhookID int64) (w *models.Webhook, err error) {
+	sqlStr := `select webhook_id, user_id, community_id, url, events, secret, create_time
+	from webhook where webhook_id = ?`
+	ctx, span := startSpan(ctx, "GetWebhookByID", sqlStr)
+	defer span.Finish(&err)
+	w = new(models.Webhook)
+	err = db.GetContext(ctx, w, sqlStr, webhookID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrorWebhookNotExist
+	}
+	return
+}
+
+// GetWebhooksByCommunity 查询社区的所有订阅，包含密钥
+func GetWebhooksByCommunity(ctx context.Context, communityID int64) (list []*models.Webhook, err error) {
+	sqlStr := `select webhook_id, user_id, community_id, url, events, secret, create_time
+	from webhook where community_id = ?`
+	ctx, span := startSpan(ctx, "GetWebhooksByCommunity", sqlStr)
+	defer span.Finish(&err)
+	err = db.SelectContext(ctx, &list, sqlStr, communityID)
+	return
+}
+
+// DeleteWebhook 在同一个事务中删除用户的订阅和它的推送记录，订阅不存在或不属于该用户时返回ErrorWebhookNotExist
+func DeleteWebhook(ctx context.Context, userID, webhookID int64) (err error) {
+	sqlStr := `delete from webhook where webhook_id = ? and user_id = ?`
+	ctx, span := startSpan(ctx, "DeleteWebhook", sqlStr)
+	defer span.Finish(&err)
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	res, err := tx.ExecContext(ctx, sqlStr, webhookID, userID)
+	if err != nil {
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrorWebhookNotExist
+	}
+	if _, err = tx.ExecContext(ctx, `delete from webhook_delivery where webhook_id = ?`, webhookID); err != nil {
+		return
+	}
+	return tx.Commit()
+}
+
+// DeleteWebhooksByUser 删除用户的所有订阅和推送记录
+func DeleteWebhooksByUser(ctx context.Context, userID int64) (err error) {
+	sqlStr := `delete d, w from webhook w left join webhook_delivery d on d.webhook_id = w.webhook_id where w.user_id = ?`
+	ctx, span := startSpan(ctx, "DeleteWebhooksByUser", sqlStr)
+	defer span.Finish(&err)
+	_, err = db.ExecContext(ctx, sqlStr, userID)
+	return
+}
+
+// InsertDelivery 创建推送记录
+func InsertDelivery(ctx context.Context, d *models.WebhookDelivery) (err error) {
+	sqlStr := `insert into webhook_delivery(delivery_id, webhook_id, event, payload, status) values(?,?,?,?,?)`
+	ctx, span := startSpan(ctx, "InsertDelivery", sqlStr)
+	defer span.Finish(&err)
+	_, err = db.ExecContext(ctx, sqlStr, d.ID, d.WebhookID, d.Event, d.Payload, d.Status)
+	return
+}
+
+// GetDeliveryByID 查询推送记录
+func GetDeliveryByID(ctx context.Context, deliveryID int64) (d *models.WebhookDelivery, err error) {
+	sqlStr := `select delivery_id, webhook_id, event, payload, status, attempts, response_code, last_error,
+	create_time, update_time from webhook_delivery where delivery_id = ?`
+	ctx, span := startSpan(ctx, "GetDeliveryByID", sqlStr)
+	defer span.Finish(&err)
+	d = new(models.WebhookDelivery)
+	err = db.GetContext(ctx, d, sqlStr, deliveryID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrorDeliveryNotExist
+	}
+	return
+}
+
+// GetDeliveries 按创建时间倒序分页查询订阅的推送记录，status为空时查询全部
+func GetDeliveries(ctx context.Context, webhookID int64, status string, page, size int64) (list []*models.WebhookDelivery, err error) {
+	sqlStr := `select delivery_id, webhook_id, event, payload, status, attempts, response_code, last_error,
+	create_time, update_time from webhook_delivery where webhook_id = ?`
+	args := []interface{}{webhookID}
+	if status != "" {
+		sqlStr += ` and status = ?`
+		args = append(args, status)
+	}
+	sqlStr += ` order by id desc limit ?,?`
+	args = append(args, (page-1)*size, size)
+	ctx, span := startSpan(ctx, "GetDeliveries", sqlStr)
+	defer span.Finish(&err)
+	list = make([]*models.WebhookDelivery, 0, size)
+	err = db.SelectContext(ctx, &list, sqlStr, args...)
+	return
+}
+
+// GetStalePendingDeliveryIDs 按ID顺序查询afterID之后、至少age没有更新的待发送推送记录ID
+func GetStalePendingDeliveryIDs(ctx context.Context, age time.Duration, afterID, size int64) (ids []int64, err error) {
+	sqlStr := `select delivery_id from webhook_delivery
+	where status = ? and delivery_id > ? and update_time < now() - interval ? second
+	order by delivery_id limit ?`
+	ctx, span := startSpan(ctx, "GetStalePendingDeliveryIDs", sqlStr)
+	defer span.Finish(&err)
+	ids = make([]int64, 0, size)
+	err = db.SelectContext(ctx, &ids, sqlStr, models.DeliveryStatusPending, afterID, int64(age.Seconds()), size)
+	return
+}
+
+// UpdateDeliveryResult 记录一次推送的结果
+func UpdateDeliveryResult(ctx context.Context, d *models.WebhookDelivery) (err error) {
+	sqlStr := `update webhook_delivery set status = ?, attempts = ?, response_code = ?, last_error = ?
+	where delivery_id = ?`
+	ctx, span := startSpan(ctx, "UpdateDeliveryResult", sqlStr)
+	defer span.Finish(&err)
+	_, err = db.ExecContext(ctx, sqlStr, d.Status, d.Attempts, d.ResponseCode, d.LastError, d.ID)
+	return
+}
+
+// ResetDelivery 把推送记录恢复为待发送，重新计算重试次数
+func ResetDelivery(ctx context.Context, deliveryID int64) (err error) {
+	sqlStr := `update webhook_delivery set status = ?, attempts = 0 where delivery_id = ?`
+	ctx, span := startSpan(ctx, "ResetDelivery", sqlStr)
+	defer span.Finish(&err)
+	_, err = db.ExecContext(ctx, sqlStr, models.DeliveryStatusPending, deliveryID)
+	return
+}
@@ -0,0 +1,114 @@
+package redis
+
+import (
+	"blue-bell_back/models"
+	"blue-bell_back/pkg/jwt"
+	"blue-bell_back/pkg/trace"
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// RevokeTokens 吊销用户在此之前签发的所有令牌，返回吊销的时间（Unix毫秒），签发时间不晚于这个时间的令牌都失效
+// 记录的时间在令牌的最长有效期之后过期，那时旧令牌已经全部失效
+func RevokeTokens(ctx context.Context, userID int64) (validAfter int64, err error) {
+	ctx, span := startSpan(ctx, "RevokeTokens", trace.Int64("user_id", userID))
+	defer span.Finish(&err)
+	key := getRedisKey(KeyTokenValidPreFix + strconv.FormatInt(userID, 10))
+	validAfter = time.Now().UnixMilli()
+	err = client().WithContext(ctx).Set(key, validAfter, jwt.TokenExpireDuration).Err()
+	return
+}
+
+// legacyValidAfterMax 之前按Unix秒记录吊销时间，小于这个值的记录是秒
+const legacyValidAfterMax = 1e11
+
+// GetTokenValidAfter 返回用户令牌的吊销时间（Unix毫秒），没有吊销记录时返回0
+func GetTokenValidAfter(ctx context.Context, userID int64) (validAfter int64, err error) {
+	ctx, span := startSpan(ctx, "GetTokenValidAfter", trace.Int64("user_id", userID))
+	defer span.Finish(&err)
+	key := getRedisKey(KeyTokenValidPreFix + strconv.FormatInt(userID, 10))
+	validAfter, err = client().WithContext(ctx).Get(key).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	// 按秒记录的吊销时间，同一秒内签发的令牌同样失效
+	if validAfter > 0 && validAfter < legacyValidAfterMax {
+		validAfter = validAfter*1000 + 999
+	}
+	return
+}
+
+// removeVoteScript 删除用户在一个帖子上的投票并扣除对应的分数和净票数
+// 按时间段统计的净票数会在过期后自然消失，不做扣除
+// KEYS[1] 帖子的投票记录 KEYS[2] 帖子分数 KEYS[3] 帖子的净票数 ARGV[1] 用户ID ARGV[2] 帖子ID ARGV[3] 每票的分数
+var removeVoteScript = redis.NewScript(`
+local v = redis.call('ZSCORE', KEYS[1], ARGV[1])
+if not v then
+	return 0
+end
+redis.call('ZREM', KEYS[1], ARGV[1])
+if tonumber(v) ~= 0 then
+	redis.call('ZINCRBY', KEYS[2], -tonumber(v) * tonumber(ARGV[3]), ARGV[2])
+	redis.call('ZINCRBY', KEYS[3], -tonumber(v), ARGV[2])
+end
+return 1
+`)
+
+// RemoveUserVotes 删除用户的所有投票，返回删除的数量
+// 只遍历 user:voted:{用户ID} 中记录的帖子，帖子已被删除时投票记录不存在，直接跳过
+func RemoveUserVotes(ctx context.Context, userID int64) (removed int, err error) {
+	ctx, span := startSpan(ctx, "RemoveUserVotes", trace.Int64("user_id", userID))
+	defer span.Finish(&err)
+	cli := client().WithContext(ctx)
+	scoreKey := getRedisKey(KeyPostScoreZSet)
+	votesKey := getRedisKey(KeyPostVotesZSet)
+	member := strconv.FormatInt(userID, 10)
+	votedKey := getRedisKey(KeyUserVotedSetPreFix + member)
+
+	postIDs, err := cli.SMembers(votedKey).Result()
+	if err != nil {
+		return
+	}
+	for _, postID := range postIDs {
+		key := getRedisKey(KeyPostVoteZSetPreFix + postID)
+		n, err := removeVoteScript.Run(cli, []string{key, scoreKey, votesKey}, member, postID, oneTicketScore).Int()
+		if err != nil {
+			return removed, err
+		}
+		removed += n
+	}
+	err = cli.Del(votedKey).Err()
+	return
+}
+
+// RemovePosts 把帖子从时间、分数、净票数排行、按时间段的排行、社区和标签中移除，并删除帖子的投票记录
+// 否则按时间段的排行中的帖子要到过期后才消失，查询详情时跳过已删除的帖子，返回的数量不足一页
+func RemovePosts(ctx context.Context, posts []*models.CommunityPost) (err error) {
+	ctx, span := startSpan(ctx, "RemovePosts", trace.Int("db.redis.posts", len(posts)))
+	defer span.Finish(&err)
+	if len(posts) == 0 {
+		return
+	}
+	rankKeys := liveRankKeys(time.Now().Unix())
+	pipeline := client().WithContext(ctx).TxPipeline()
+	for _, p := range posts {
+		postID := strconv.FormatInt(p.ID, 10)
+		for _, key := range rankKeys {
+			pipeline.ZRem(key, postID)
+		}
+		pipeline.ZRem(getRedisKey(KeyPostTimeZSet), postID)
+		pipeline.ZRem(getRedisKey(KeyPostScoreZSet), postID)
+		pipeline.ZRem(getRedisKey(KeyPostVotesZSet), postID)
+		pipeline.SRem(getRedisKey(KeyCommunitySetPreFix+strconv.FormatInt(p.CommunityID, 10)), postID)
+		pipeline.ZRem(authorPostsKey(p.AuthorID), postID)
+		for _, tag := range p.Tags {
+			pipeline.SRem(getRedisKey(KeyTagSetPreFix+tag), postID)
+		}
+		pipeline.Del(getRedisKey(KeyPostVoteZSetPreFix + postID))
+	}
+	_, err = pipeline.Exec()
+	return
+}
@@ -0,0 +1,91 @@
+package redis
+
+import (
+	"blue-bell_back/models"
+	"blue-bell_back/pkg/jwt"
+	"context"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRevokeTokens(t *testing.T) {
+	mr.FlushAll()
+	ctx := context.Background()
+	before := time.Now()
+	validAfter, err := RevokeTokens(ctx, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := GetTokenValidAfter(ctx, 7)
+	if err != nil || got != validAfter {
+		t.Fatalf("GetTokenValidAfter = %d, %v; want %d", got, err, validAfter)
+	}
+	// 同一毫秒之前签发的令牌失效，之后签发的有效
+	old := &jwt.MyClaims{IssuedAtMilli: before.UnixMilli()}
+	if old.IssuedAtMillis() > validAfter {
+		t.Errorf("token issued before revocation is still valid")
+	}
+	if fresh := (&jwt.MyClaims{IssuedAtMilli: validAfter + 1}); fresh.IssuedAtMillis() <= validAfter {
+		t.Errorf("token issued after revocation is rejected")
+	}
+}
+
+func TestGetTokenValidAfterLegacySeconds(t *testing.T) {
+	mr.FlushAll()
+	sec := time.Now().Unix()
+	if err := mr.Set(getRedisKey(KeyTokenValidPreFix+"7"), strconv.FormatInt(sec, 10)); err != nil {
+		t.Fatal(err)
+	}
+	got, err := GetTokenValidAfter(context.Background(), 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 没有iat_ms的令牌按秒计算，同一秒内签发的令牌失效
+	sameSecond := &jwt.MyClaims{}
+	sameSecond.IssuedAt = sec
+	if sameSecond.IssuedAtMillis() > got {
+		t.Errorf("token issued in the revoked second is still valid, valid after %d", got)
+	}
+	nextSecond := &jwt.MyClaims{}
+	nextSecond.IssuedAt = sec + 1
+	if nextSecond.IssuedAtMillis() <= got {
+		t.Errorf("token issued in the next second is rejected, valid after %d", got)
+	}
+}
+
+func TestRemovePostsFromRanks(t *testing.T) {
+	mr.FlushAll()
+	ctx := context.Background()
+	now := time.Now().Unix()
+	for id := int64(1); id <= 2; id++ {
+		if err := CreateCommunityPost(ctx, id, 1, 9, now, nil); err != nil {
+			t.Fatal(err)
+		}
+		if err := VoteForCommunity(ctx, "7", strconv.FormatInt(id, 10), 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 生成排行的缓存
+	for _, order := range rankOrders {
+		if _, err := getOrderKey(ctx, order); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := RemovePosts(ctx, []*models.CommunityPost{{ID: 1, CommunityID: 1, AuthorID: 9}}); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range liveRankKeys(now) {
+		if !mr.Exists(key) {
+			continue
+		}
+		members, err := mr.ZMembers(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(members) != 1 || members[0] != "2" {
+			t.Errorf("%s = %v, want only post 2", key, members)
+		}
+	}
+}
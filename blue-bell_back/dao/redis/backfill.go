@@ -0,0 +1,155 @@
+package redis
+
+import (
+	"blue-bell_back/models"
+	"context"
+	"strings"
+
+	"github.com/go-redis/redis"
+)
+
+// 一次性的数据补全，用于新增的索引覆盖升级前已有的数据
+// 重复执行结果不变
+
+// backfillScanCount 每次SCAN返回的数量
+const backfillScanCount = 200
+
+// scanPostVoteKeys 遍历所有帖子的投票记录的key post:voted:{帖子ID}
+func scanPostVoteKeys(ctx context.Context, fn func(postID, key string) error) error {
+	cli := client().WithContext(ctx)
+	prefix := getRedisKey(KeyPostVoteZSetPreFix)
+	var cursor uint64
+	for {
+		keys, next, err := cli.Scan(cursor, prefix+"*", backfillScanCount).Result()
+		if err != nil {
+			return err
+		}
+		for _, key := range keys {
+			if err := fn(strings.TrimPrefix(key, prefix), key); err != nil {
+				return err
+			}
+		}
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+// scanPostVotes 遍历所有帖子的投票记录 post:voted:{帖子ID}
+func scanPostVotes(ctx context.Context, fn func(postID string, votes []redis.Z) error) error {
+	cli := client().WithContext(ctx)
+	return scanPostVoteKeys(ctx, func(postID, key string) error {
+		votes, err := cli.ZRangeWithScores(key, 0, -1).Result()
+		if err != nil {
+			return err
+		}
+		return fn(postID, votes)
+	})
+}
+
+// BackfillUserVoted 根据帖子的投票记录补全 user:voted:{用户ID}，返回处理的帖子数量
+func BackfillUserVoted(ctx context.Context) (posts int, err error) {
+	ctx, span := startSpan(ctx, "BackfillUserVoted")
+	defer span.Finish(&err)
+	cli := client().WithContext(ctx)
+	err = scanPostVotes(ctx, func(postID string, votes []redis.Z) error {
+		pipeline := cli.Pipeline()
+		for _, v := range votes {
+			if v.Score == 0 {
+				continue
+			}
+			pipeline.SAdd(getRedisKey(KeyUserVotedSetPreFix+v.Member.(string)), postID)
+		}
+		if _, err := pipeline.Exec(); err != nil {
+			return err
+		}
+		posts++
+		return nil
+	})
+	return
+}
+
+// sumVotesScript 按帖子的投票记录计算净票数并写入 post:votes，在脚本中执行，不会和同时进行的投票冲突
+// 已从 post:time 移除（已删除）的帖子跳过
+// KEYS[1] 帖子的投票记录 KEYS[2] 帖子的净票数 KEYS[3] 帖子发布时间 ARGV[1] 帖子ID
+var sumVotesScript = redis.NewScript(`
+if not redis.call('ZSCORE', KEYS[3], ARGV[1]) then
+	return 0
+end
+local votes = redis.call('ZRANGE', KEYS[1], 0, -1, 'WITHSCORES')
+local sum = 0
+for i = 2, #votes, 2 do
+	sum = sum + tonumber(votes[i])
+end
+redis.call('ZADD', KEYS[2], sum, ARGV[1])
+return 1
+`)
+
+// BackfillPostVotes 补全 post:votes，返回按投票记录更新的帖子数量
+// 有投票记录的帖子按记录计算净票数，其余帖子的净票数为0，否则总得票排行中缺少升级前发布的帖子
+func BackfillPostVotes(ctx context.Context) (posts int, err error) {
+	ctx, span := startSpan(ctx, "BackfillPostVotes")
+	defer span.Finish(&err)
+	cli := client().WithContext(ctx)
+	timeKey := getRedisKey(KeyPostTimeZSet)
+	votesKey := getRedisKey(KeyPostVotesZSet)
+
+	// 1.有投票记录的帖子
+	err = scanPostVoteKeys(ctx, func(postID, key string) error {
+		n, err := sumVotesScript.Run(cli, []string{key, votesKey, timeKey}, postID).Int()
+		posts += n
+		return err
+	})
+	if err != nil {
+		return posts, err
+	}
+
+	// 2.没有投票的帖子，已有的净票数不覆盖
+	var cursor uint64
+	for {
+		members, next, err := cli.ZScan(timeKey, cursor, "", backfillScanCount).Result()
+		if err != nil {
+			return posts, err
+		}
+		pipeline := cli.Pipeline()
+		// ZSCAN返回的是成员和分数交替的列表
+		for i := 0; i < len(members); i += 2 {
+			pipeline.ZAddNX(votesKey, redis.Z{Score: 0, Member: members[i]})
+		}
+		if _, err := pipeline.Exec(); err != nil {
+			return posts, err
+		}
+		if next == 0 {
+			return posts, nil
+		}
+		cursor = next
+	}
+}
+
+// addAuthorPostScript 帖子仍在 post:time 中时才加入作者的帖子列表，避免补全期间被删除的帖子重新出现在动态中
+// KEYS[1] 作者的帖子列表 KEYS[2] 帖子发布时间 ARGV[1] 发帖时间 ARGV[2] 帖子ID
+var addAuthorPostScript = redis.NewScript(`
+if not redis.call('ZSCORE', KEYS[2], ARGV[2]) then
+	return 0
+end
+redis.call('ZADD', KEYS[1], ARGV[1], ARGV[2])
+return 1
+`)
+
+// BackfillAuthorPosts 把MySQL中的帖子加入 user:posts:{作者ID}，返回加入的帖子数量
+// 升级前发布的帖子不在作者的帖子列表中，关注这些作者时动态为空
+func BackfillAuthorPosts(ctx context.Context, posts []*models.CommunityPost) (n int, err error) {
+	ctx, span := startSpan(ctx, "BackfillAuthorPosts")
+	defer span.Finish(&err)
+	cli := client().WithContext(ctx)
+	timeKey := getRedisKey(KeyPostTimeZSet)
+	for _, p := range posts {
+		added, err := addAuthorPostScript.Run(cli, []string{authorPostsKey(p.AuthorID), timeKey}, p.CreateTime.Unix(), p.ID).Int()
+		if err != nil {
+			return n, err
+		}
+		n += added
+	}
+	return n, nil
+}
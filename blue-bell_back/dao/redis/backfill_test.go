@@ -0,0 +1,89 @@
+package redis
+
+import (
+	"blue-bell_back/models"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestBackfillPostVotes(t *testing.T) {
+	mr.FlushAll()
+	ctx := context.Background()
+	now := time.Now().Unix()
+	for id := int64(1); id <= 3; id++ {
+		if err := CreateCommunityPost(ctx, id, 1, 9, now, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, v := range []struct {
+		user, post string
+		value      float64
+	}{
+		{"7", "1", 1},
+		{"8", "1", 1},
+		{"9", "1", -1},
+		{"7", "2", -1},
+	} {
+		if err := VoteForCommunity(ctx, v.user, v.post, v.value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 模拟升级前的数据：post:votes 不存在，已删除的帖子只剩投票记录
+	votesKey := getRedisKey(KeyPostVotesZSet)
+	mr.Del(votesKey)
+	if _, err := mr.ZAdd(getRedisKey(KeyPostVoteZSetPreFix+"4"), 1, "7"); err != nil {
+		t.Fatal(err)
+	}
+
+	posts, err := BackfillPostVotes(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if posts != 2 {
+		t.Errorf("posts = %d, want 2", posts)
+	}
+	for post, want := range map[string]float64{"1": 1, "2": -1, "3": 0} {
+		if got, err := mr.ZScore(votesKey, post); err != nil || got != want {
+			t.Errorf("post %s votes = %v (err %v), want %v", post, got, err, want)
+		}
+	}
+	if members, _ := mr.ZMembers(votesKey); len(members) != 3 {
+		t.Errorf("post:votes members = %v, want posts 1-3", members)
+	}
+
+	// 重复执行结果不变
+	if _, err := BackfillPostVotes(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := zscore(t, votesKey, "1"); got != 1 {
+		t.Errorf("post 1 votes after second run = %v, want 1", got)
+	}
+}
+
+func TestBackfillAuthorPosts(t *testing.T) {
+	mr.FlushAll()
+	ctx := context.Background()
+	created := time.Now().Add(-time.Hour)
+	// 升级前发布的帖子没有作者ID，不在作者的帖子列表中
+	if err := CreateCommunityPost(ctx, 1, 1, 0, created.Unix(), nil); err != nil {
+		t.Fatal(err)
+	}
+	posts := []*models.CommunityPost{
+		{ID: 1, AuthorID: 9, CreateTime: created},
+		{ID: 2, AuthorID: 9, CreateTime: created}, // 已从Redis删除
+	}
+	n, err := BackfillAuthorPosts(ctx, posts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("added %d posts, want 1", n)
+	}
+	if got := zscore(t, authorPostsKey(9), "1"); got != float64(created.Unix()) {
+		t.Errorf("post 1 time = %v, want %v", got, created.Unix())
+	}
+	if members, _ := mr.ZMembers(authorPostsKey(9)); len(members) != 1 {
+		t.Errorf("user:posts members = %v, want only post 1", members)
+	}
+}
@@ -1,7 +1,10 @@
 package redis
 
 import (
+	"blue-bell_back/logger"
 	"blue-bell_back/models"
+	"blue-bell_back/pkg/trace"
+	"context"
 	"strconv"
 	"time"
 
@@ -9,28 +12,31 @@ import (
 	"go.uber.org/zap"
 )
 
-func getIDsFormKey(key string, page, size int64) ([]string, error) {
+func getIDsFormKey(ctx context.Context, key string, page, size int64) (ids []string, err error) {
+	ctx, span := startSpan(ctx, "ZRevRange", trace.String("db.redis.key", key))
+	defer span.Finish(&err)
 	//1.确定查询到索引起始点
 	start := (page - 1) * size
 	end := start + size - 1
 	//2.zrevrange查询(按分数从大到小的顺序查询指定数量的元素)
-	return rdb.ZRevRange(key, start, end).Result()
+	return client().WithContext(ctx).ZRevRange(key, start, end).Result()
 }
 
-func GetPostListByID(p *models.ParamOrderList) ([]string, error) {
+func GetPostListByID(ctx context.Context, p *models.ParamOrderList) ([]string, error) {
 
-	key := getRedisKey(KeyPostTimeZSet)
-
-	if p.Order == models.OrderScore {
-		key = getRedisKey(KeyPostScoreZSet)
+	key, err := getOrderKey(ctx, p.Order)
+	if err != nil {
+		return nil, err
 	}
 
-	return getIDsFormKey(key, p.Page, p.Size)
+	return getIDsFormKey(ctx, key, p.Page, p.Size)
 }
 
 //GetPostVoteData 根据帖子的id去redis查询对应赞成票的数量
 
-func GetPostVoteData(ids []string) (data []int64, err error) {
+func GetPostVoteData(ctx context.Context, ids []string) (data []int64, err error) {
+	ctx, span := startSpan(ctx, "GetPostVoteData", trace.Int("db.redis.keys", len(ids)))
+	defer span.Finish(&err)
 	// 初始化切片
 	// data = make([]int64, 0, len(ids))
 	// // 遍历ids获取id的投票数
@@ -44,7 +50,7 @@ func GetPostVoteData(ids []string) (data []int64, err error) {
 	//	}
 	//
 	// 使用pipeline一次发送多条命令 减少RTT
-	pipeline := rdb.TxPipeline()
+	pipeline := client().WithContext(ctx).TxPipeline()
 
 	for _, id := range ids {
 		key := getRedisKey(KeyPostVoteZSetPreFix + id)
@@ -57,7 +63,7 @@ func GetPostVoteData(ids []string) (data []int64, err error) {
 		return nil, err
 	}
 
-	zap.L().Debug("cmders:", zap.Any("cmders", cmders))
+	logger.FromContext(ctx).Debug("cmders:", zap.Any("cmders", cmders))
 
 	data = make([]int64, 0, len(cmders))
 
@@ -66,15 +72,31 @@ func GetPostVoteData(ids []string) (data []int64, err error) {
 		data = append(data, v)
 	}
 
-	zap.L().Debug("data:", zap.Any("data", data))
+	logger.FromContext(ctx).Debug("data:", zap.Any("data", data))
+	return
+}
+
+// zInterStore 计算社区帖子和排序ZSet的交集并缓存60秒
+// 社区的set权重为0，结果的分数和排序ZSet中的分数相同
+func zInterStore(ctx context.Context, key, communityKey, orderKey string) (err error) {
+	ctx, span := startSpan(ctx, "ZInterStore", trace.String("db.redis.key", key))
+	defer span.Finish(&err)
+	pipeline := client().WithContext(ctx).TxPipeline()
+	pipeline.ZInterStore(key, redis.ZStore{
+		Weights:   []float64{0, 1},
+		Aggregate: "SUM",
+	}, communityKey, orderKey)
+	//设置超时时间
+	pipeline.Expire(key, time.Second*60)
+	_, err = pipeline.Exec()
 	return
 }
 
 // GetCommunityPostListByID 根据社区id返回对应的帖子id列表
-func GetCommunityPostListByID(p *models.ParamCommunityPostList) ([]string, error) {
-	orderKey := getRedisKey(KeyPostTimeZSet)
-	if p.Order == models.OrderScore {
-		orderKey = getRedisKey(KeyPostScoreZSet)
+func GetCommunityPostListByID(ctx context.Context, p *models.ParamCommunityPostList) ([]string, error) {
+	orderKey, err := getOrderKey(ctx, p.Order)
+	if err != nil {
+		return nil, err
 	}
 
 	//1.使用ZInterStore把分区的帖子set和帖子分数的ZSet生成一个新的ZSet
@@ -86,19 +108,31 @@ func GetCommunityPostListByID(p *models.ParamCommunityPostList) ([]string, error
 	//3.使用缓存key减少ZInterStore执行的次数
 	key := orderKey + strconv.FormatInt(p.CommunityID, 10)
 
-	if rdb.Exists(key).Val() < 1 {
+	if client().WithContext(ctx).Exists(key).Val() < 1 {
 		//不存在 计算
-		pipeline := rdb.TxPipeline()
-		pipeline.ZInterStore(key, redis.ZStore{
-			Aggregate: "MAX",
-		}, communityKey, orderKey)
-		//设置超时时间
-		pipeline.Expire(key, time.Second*60)
-		_, err := pipeline.Exec()
-		if err != nil {
+		if err := zInterStore(ctx, key, communityKey, orderKey); err != nil {
 			return nil, err
 		}
 	}
 	//如果存在就直接根据key查询对应的ids
-	return getIDsFormKey(key, p.Page, p.Size)
+	return getIDsFormKey(ctx, key, p.Page, p.Size)
+}
+
+// GetTagPostListByID 根据标签返回对应的帖子id列表
+func GetTagPostListByID(ctx context.Context, p *models.ParamTagPostList) ([]string, error) {
+	orderKey, err := getOrderKey(ctx, p.Order)
+	if err != nil {
+		return nil, err
+	}
+
+	//和社区一样，把标签的set和排序的ZSet求交集并缓存
+	tagKey := getRedisKey(KeyTagSetPreFix + p.Tag)
+	key := orderKey + KeyTagSetPreFix + p.Tag
+
+	if client().WithContext(ctx).Exists(key).Val() < 1 {
+		if err := zInterStore(ctx, key, tagKey, orderKey); err != nil {
+			return nil, err
+		}
+	}
+	return getIDsFormKey(ctx, key, p.Page, p.Size)
 }
@@ -0,0 +1,71 @@
+package redis
+
+import (
+	"blue-bell_back/models"
+	"blue-bell_back/pkg/trace"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// 实时事件通过发布订阅在各个实例之间广播
+
+// PublishEvent 发布实时事件
+func PublishEvent(ctx context.Context, e *models.Event) (err error) {
+	ctx, span := startSpan(ctx, "PublishEvent", trace.String("event.type", e.Type))
+	defer span.Finish(&err)
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return client().WithContext(ctx).Publish(getRedisKey(KeyEventChannel), data).Err()
+}
+
+// EventSubscription 实时事件频道的订阅，使用独立的连接
+type EventSubscription struct {
+	ps *redis.PubSub
+}
+
+// SubscribeEvents 订阅实时事件频道，收到订阅确认后返回
+func SubscribeEvents() (*EventSubscription, error) {
+	ps := client().Subscribe(getRedisKey(KeyEventChannel))
+	if _, err := ps.Receive(); err != nil {
+		_ = ps.Close()
+		return nil, err
+	}
+	return &EventSubscription{ps: ps}, nil
+}
+
+// Receive 等待下一个事件
+// timeout内没有收到事件时发送PING检测连接，连接正常返回nil, nil
+// 消息无法解析时返回ErrUnexpectedReply，订阅仍然可用；返回其他错误时需要关闭后重新订阅
+func (s *EventSubscription) Receive(timeout time.Duration) (*models.Event, error) {
+	msg, err := s.ps.ReceiveTimeout(timeout)
+	if err != nil {
+		var ne net.Error
+		if errors.As(err, &ne) && ne.Timeout() {
+			return nil, s.ps.Ping()
+		}
+		return nil, err
+	}
+	m, ok := msg.(*redis.Message)
+	if !ok {
+		// 订阅确认和PONG
+		return nil, nil
+	}
+	e := new(models.Event)
+	if err := json.Unmarshal([]byte(m.Payload), e); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnexpectedReply, err)
+	}
+	return e, nil
+}
+
+// Close 取消订阅并关闭连接
+func (s *EventSubscription) Close() error {
+	return s.ps.Close()
+}
@@ -0,0 +1,61 @@
+package redis
+
+import (
+	"blue-bell_back/pkg/trace"
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// 关注的人的动态
+// 每个作者的帖子保存在各自的ZSet中，分数为发帖时间，读取时合并关注的作者的ZSet并缓存一段时间
+
+// feedCacheTTL 合并后的动态的缓存时间
+const feedCacheTTL = 60 * time.Second
+
+func authorPostsKey(authorID int64) string {
+	return getRedisKey(KeyAuthorPostsZSetPreFix + strconv.FormatInt(authorID, 10))
+}
+
+func feedKey(userID int64) string {
+	return getRedisKey(KeyFeedZSetPreFix + strconv.FormatInt(userID, 10))
+}
+
+// GetFollowingFeedIDs 按发帖时间倒序分页查询关注的作者发布的帖子ID
+// 合并结果缓存feedCacheTTL，缓存期间新发布的帖子不会出现，关注关系变化时调用ClearFollowingFeed
+func GetFollowingFeedIDs(ctx context.Context, userID int64, authorIDs []int64, page, size int64) (ids []string, err error) {
+	if len(authorIDs) == 0 {
+		return nil, nil
+	}
+	key := feedKey(userID)
+	if client().WithContext(ctx).Exists(key).Val() < 1 {
+		if err = zUnionStore(ctx, key, authorIDs); err != nil {
+			return nil, err
+		}
+	}
+	return getIDsFormKey(ctx, key, page, size)
+}
+
+// zUnionStore 合并作者的帖子ZSet
+func zUnionStore(ctx context.Context, key string, authorIDs []int64) (err error) {
+	ctx, span := startSpan(ctx, "ZUnionStore", trace.String("db.redis.key", key), trace.Int("db.redis.keys", len(authorIDs)))
+	defer span.Finish(&err)
+	keys := make([]string, 0, len(authorIDs))
+	for _, id := range authorIDs {
+		keys = append(keys, authorPostsKey(id))
+	}
+	pipeline := client().WithContext(ctx).TxPipeline()
+	pipeline.ZUnionStore(key, redis.ZStore{Aggregate: "MAX"}, keys...)
+	pipeline.Expire(key, feedCacheTTL)
+	_, err = pipeline.Exec()
+	return
+}
+
+// ClearFollowingFeed 删除合并后的动态缓存
+func ClearFollowingFeed(ctx context.Context, userID int64) (err error) {
+	ctx, span := startSpan(ctx, "ClearFollowingFeed")
+	defer span.Finish(&err)
+	return client().WithContext(ctx).Del(feedKey(userID)).Err()
+}
@@ -3,11 +3,31 @@ package redis
 //redis key
 
 const (
-	KeyPreFix             = "Forum:"
-	KeyPostTimeZSet       = "post:time"   //帖子及发帖时间
-	KeyPostScoreZSet      = "post:score"  //帖子及投票的分数
-	KeyPostVoteZSetPreFix = "post:voted:" //记录用户的投票类型
-	KeyCommunitySetPreFix = "community:"  // 保存每个分区下的帖子id
+	KeyPreFix                = "Forum:"
+	KeyPostTimeZSet          = "post:time"          //帖子及发帖时间
+	KeyPostScoreZSet         = "post:score"         //帖子及投票的分数
+	KeyPostVoteZSetPreFix    = "post:voted:"        //记录用户的投票类型
+	KeyCommunitySetPreFix    = "community:"         // 保存每个分区下的帖子id
+	KeyRateLimitPreFix       = "ratelimit:"         // 限流记录 ratelimit:{策略}:{user|ip}:{标识}
+	KeyLoginFailPreFix       = "login:fail:"        // 登录失败次数 login:fail:{user|ip}:{标识}
+	KeyLoginLockPreFix       = "login:lock:"        // 登录锁定 login:lock:{user|ip}:{标识}
+	KeyTokenPreFix           = "token:"             // 一次性令牌 token:{用途}:{令牌哈希}
+	KeyTokenValidPreFix      = "token:valid_after:" // 用户令牌的吊销时间（Unix毫秒） token:valid_after:{用户ID}
+	KeyOAuthStatePreFix      = "oauth:state:"       // 第三方登录的授权请求 oauth:state:{state}
+	KeyUserLocalePreFix      = "user:locale:"       // 用户界面语言的缓存 user:locale:{用户ID}
+	KeyEventChannel          = "channel:events"     // 实时事件的发布订阅频道
+	KeyNotifyActorsPreFix    = "notify:actors:"     // 未读通知已计入的用户 notify:actors:{用户ID}:{类型}:{帖子ID}
+	KeyWebhookQueueZSet      = "webhook:queue"      // 等待推送的记录及下次发送的时间（毫秒）
+	KeySavedZSetPreFix       = "user:saved:"        // 用户收藏的帖子及收藏时间 user:saved:{用户ID}
+	KeyAuthorPostsZSetPreFix = "user:posts:"        // 用户发布的帖子及发帖时间 user:posts:{用户ID}
+	KeyFeedZSetPreFix        = "feed:following:"    // 关注的人的帖子合并后的缓存 feed:following:{用户ID}
+	KeyTagSetPreFix          = "tag:"               // 保存每个标签下的帖子id tag:{标签}
+	KeyPostVotesZSet         = "post:votes"         // 帖子的净票数（赞成票减反对票）
+	KeyPostVotesHourPreFix   = "post:votes:hour:"   // 每小时内帖子得到的净票数 post:votes:hour:{Unix时间/3600}
+	KeyPostVotesDayPreFix    = "post:votes:day:"    // 每天内帖子得到的净票数 post:votes:day:{Unix时间/86400}
+	KeyPostRankPreFix        = "post:rank:"         // 按时间段合并后的排行的缓存 post:rank:{排序方式}
+	KeyPostHTMLPreFix        = "post:html:"         // 帖子内容渲染结果的缓存 post:html:{帖子ID}:{版本}
+	KeyUserVotedSetPreFix    = "user:voted:"        // 用户投过票的帖子id user:voted:{用户ID}
 )
 
 func getRedisKey(key string) string {
@@ -0,0 +1,35 @@
+package redis
+
+import (
+	"blue-bell_back/pkg/trace"
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// userLocaleTTL 用户界面语言缓存的过期时间
+const userLocaleTTL = 24 * time.Hour
+
+// GetUserLocale 读取缓存的用户界面语言
+// 返回值 found 为false表示没有缓存；用户没有设置语言时缓存的是空字符串
+func GetUserLocale(ctx context.Context, userID int64) (locale string, found bool, err error) {
+	ctx, span := startSpan(ctx, "GetUserLocale", trace.Int64("user_id", userID))
+	defer span.Finish(&err)
+	locale, err = client().WithContext(ctx).Get(getRedisKey(KeyUserLocalePreFix + strconv.FormatInt(userID, 10))).Result()
+	if err == redis.Nil {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return locale, true, nil
+}
+
+// SetUserLocale 缓存用户界面语言
+func SetUserLocale(ctx context.Context, userID int64, locale string) (err error) {
+	ctx, span := startSpan(ctx, "SetUserLocale", trace.Int64("user_id", userID))
+	defer span.Finish(&err)
+	return client().WithContext(ctx).Set(getRedisKey(KeyUserLocalePreFix+strconv.FormatInt(userID, 10)), locale, userLocaleTTL).Err()
+}
@@ -0,0 +1,60 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// 登录失败次数统计和账户锁定
+
+// 登录限制的对象
+const (
+	LoginSubjectUser = "user:"
+	LoginSubjectIP   = "ip:"
+)
+
+// incrWithExpireScript 计数加1，第一次计数时设置过期时间，保证窗口从第一次失败开始计算
+var incrWithExpireScript = redis.NewScript(`
+local n = redis.call('INCR', KEYS[1])
+if n == 1 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+return n
+`)
+
+// IncrLoginFailure 记录一次登录失败，返回窗口内的失败次数
+// 参数 subject 为 LoginSubjectUser 或 LoginSubjectIP，id 为用户名或IP
+func IncrLoginFailure(ctx context.Context, subject, id string, window time.Duration) (n int64, err error) {
+	ctx, span := startSpan(ctx, "IncrLoginFailure")
+	defer span.Finish(&err)
+	key := getRedisKey(KeyLoginFailPreFix + subject + id)
+	return incrWithExpireScript.Run(client().WithContext(ctx), []string{key}, window.Milliseconds()).Int64()
+}
+
+// ResetLoginFailure 登录成功后清除失败次数
+func ResetLoginFailure(ctx context.Context, subject, id string) (err error) {
+	ctx, span := startSpan(ctx, "ResetLoginFailure")
+	defer span.Finish(&err)
+	return client().WithContext(ctx).Del(getRedisKey(KeyLoginFailPreFix + subject + id)).Err()
+}
+
+// LockLogin 在d时间内禁止登录
+func LockLogin(ctx context.Context, subject, id string, d time.Duration) (err error) {
+	ctx, span := startSpan(ctx, "LockLogin")
+	defer span.Finish(&err)
+	return client().WithContext(ctx).Set(getRedisKey(KeyLoginLockPreFix+subject+id), 1, d).Err()
+}
+
+// GetLoginLockTTL 返回剩余的锁定时间，未锁定时返回0
+func GetLoginLockTTL(ctx context.Context, subject, id string) (ttl time.Duration, err error) {
+	ctx, span := startSpan(ctx, "GetLoginLockTTL")
+	defer span.Finish(&err)
+	ttl, err = client().WithContext(ctx).PTTL(getRedisKey(KeyLoginLockPreFix + subject + id)).Result()
+	if err != nil || ttl < 0 {
+		// key不存在时返回-2，没有过期时间时返回-1
+		return 0, err
+	}
+	return
+}
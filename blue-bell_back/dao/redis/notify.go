@@ -0,0 +1,43 @@
+package redis
+
+import (
+	"blue-bell_back/models"
+	"context"
+	"strconv"
+	"time"
+)
+
+// notifyActorsTTL 未读通知的用户集合的过期时间，超过后同一个用户会被重复计入
+const notifyActorsTTL = 30 * 24 * time.Hour
+
+func notifyActorsKey(userID int64, typ string, postID int64) string {
+	return getRedisKey(KeyNotifyActorsPreFix + strconv.FormatInt(userID, 10) + ":" + typ + ":" + strconv.FormatInt(postID, 10))
+}
+
+// AddNotifyActor 记录触发未读通知的用户，返回该用户是否第一次计入
+func AddNotifyActor(ctx context.Context, userID int64, typ string, postID, actorID int64) (added bool, err error) {
+	ctx, span := startSpan(ctx, "AddNotifyActor")
+	defer span.Finish(&err)
+	key := notifyActorsKey(userID, typ, postID)
+	pipeline := client().WithContext(ctx).TxPipeline()
+	sadd := pipeline.SAdd(key, actorID)
+	pipeline.Expire(key, notifyActorsTTL)
+	if _, err = pipeline.Exec(); err != nil {
+		return false, err
+	}
+	return sadd.Val() == 1, nil
+}
+
+// ClearNotifyActors 通知已读后清除对应的用户集合
+func ClearNotifyActors(ctx context.Context, userID int64, list []*models.Notification) (err error) {
+	if len(list) == 0 {
+		return nil
+	}
+	ctx, span := startSpan(ctx, "ClearNotifyActors")
+	defer span.Finish(&err)
+	keys := make([]string, 0, len(list))
+	for _, n := range list {
+		keys = append(keys, notifyActorsKey(userID, n.Type, n.PostID))
+	}
+	return client().WithContext(ctx).Del(keys...).Err()
+}
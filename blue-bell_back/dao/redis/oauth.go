@@ -0,0 +1,50 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// OAuthState 发起第三方登录时保存的授权请求，回调时取出校验
+type OAuthState struct {
+	Provider     string `json:"provider"`
+	CodeVerifier string `json:"code_verifier"`
+	Nonce        string `json:"nonce"`
+	UserID       int64  `json:"user_id,omitempty"` // 不为0时表示为已登录用户绑定第三方账户或再次验证身份
+	Reauth       bool   `json:"reauth,omitempty"`  // 为已登录用户再次验证身份
+}
+
+// SaveOAuthState 保存授权请求，ttl后过期
+func SaveOAuthState(ctx context.Context, state string, s *OAuthState, ttl time.Duration) (err error) {
+	ctx, span := startSpan(ctx, "SaveOAuthState")
+	defer span.Finish(&err)
+	data, err := json.Marshal(s)
+	if err != nil {
+		return
+	}
+	return client().WithContext(ctx).Set(getRedisKey(KeyOAuthStatePreFix+state), data, ttl).Err()
+}
+
+// TakeOAuthState 取出授权请求，state只能使用一次
+// state不存在或已过期时返回ErrTokenInvalid
+func TakeOAuthState(ctx context.Context, state string) (s *OAuthState, err error) {
+	ctx, span := startSpan(ctx, "TakeOAuthState")
+	defer span.Finish(&err)
+	v, err := takeTokenScript.Run(client().WithContext(ctx), []string{getRedisKey(KeyOAuthStatePreFix + state)}).Result()
+	if err == redis.Nil {
+		return nil, ErrTokenInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	data, ok := v.(string)
+	if !ok {
+		return nil, ErrTokenInvalid
+	}
+	s = new(OAuthState)
+	err = json.Unmarshal([]byte(data), s)
+	return
+}
@@ -0,0 +1,100 @@
+package redis
+
+import (
+	"blue-bell_back/models"
+	"blue-bell_back/pkg/trace"
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// 按时间段的排行
+// 投票时把净票数同时累加到总数、当前小时和当前天的ZSet中，小时和天的ZSet过期后自动删除
+// 查询时合并最近的若干个时间段并缓存rankCacheTTL
+
+const (
+	hourSeconds   = 3600
+	daySeconds    = 24 * hourSeconds
+	trendingHours = 6 // 热门趋势统计的小时数
+
+	hourBucketTTL = (24 + 1) * hourSeconds // 小时的ZSet保留到不再参与最近24小时的统计
+	dayBucketTTL  = (7 + 1) * daySeconds   // 天的ZSet保留到不再参与最近7天的统计
+	rankCacheTTL  = 60 * time.Second       // 合并后的排行的缓存时间
+)
+
+func hourBucketKey(hour int64) string {
+	return getRedisKey(KeyPostVotesHourPreFix + strconv.FormatInt(hour, 10))
+}
+
+func dayBucketKey(day int64) string {
+	return getRedisKey(KeyPostVotesDayPreFix + strconv.FormatInt(day, 10))
+}
+
+// rankOrders 按时间段合并并缓存的排序方式
+var rankOrders = []string{models.OrderTopDay, models.OrderTopWeek, models.OrderTrending}
+
+// liveRankKeys 返回还没有过期的小时和天的ZSet，以及合并后的排行的缓存，用于删除帖子
+func liveRankKeys(now int64) []string {
+	keys := make([]string, 0, hourBucketTTL/hourSeconds+dayBucketTTL/daySeconds+len(rankOrders))
+	for i := int64(0); i < hourBucketTTL/hourSeconds; i++ {
+		keys = append(keys, hourBucketKey(now/hourSeconds-i))
+	}
+	for i := int64(0); i < dayBucketTTL/daySeconds; i++ {
+		keys = append(keys, dayBucketKey(now/daySeconds-i))
+	}
+	for _, order := range rankOrders {
+		keys = append(keys, getRedisKey(KeyPostRankPreFix+order))
+	}
+	return keys
+}
+
+// getOrderKey 返回排序方式对应的ZSet，按时间段的排序先合并最近的时间段
+func getOrderKey(ctx context.Context, order string) (string, error) {
+	switch order {
+	case models.OrderScore:
+		return getRedisKey(KeyPostScoreZSet), nil
+	case models.OrderTopAll:
+		return getRedisKey(KeyPostVotesZSet), nil
+	case models.OrderTopDay, models.OrderTopWeek, models.OrderTrending:
+		key := getRedisKey(KeyPostRankPreFix + order)
+		if client().WithContext(ctx).Exists(key).Val() < 1 {
+			if err := storeRank(ctx, key, order, time.Now().Unix()); err != nil {
+				return "", err
+			}
+		}
+		return key, nil
+	}
+	return getRedisKey(KeyPostTimeZSet), nil
+}
+
+// storeRank 合并排序方式对应的时间段
+// 最近24小时合并24个小时的ZSet，最近7天合并7个天的ZSet（包括当天）
+// 热门趋势合并最近trendingHours个小时的ZSet，当前小时的权重为1，之后每早一个小时权重线性递减
+func storeRank(ctx context.Context, key, order string, now int64) (err error) {
+	ctx, span := startSpan(ctx, "ZUnionStore", trace.String("db.redis.key", key))
+	defer span.Finish(&err)
+	var keys []string
+	var weights []float64
+	switch order {
+	case models.OrderTopDay:
+		for i := int64(0); i < 24; i++ {
+			keys = append(keys, hourBucketKey(now/hourSeconds-i))
+		}
+	case models.OrderTopWeek:
+		for i := int64(0); i < 7; i++ {
+			keys = append(keys, dayBucketKey(now/daySeconds-i))
+		}
+	case models.OrderTrending:
+		for i := int64(0); i < trendingHours; i++ {
+			keys = append(keys, hourBucketKey(now/hourSeconds-i))
+			weights = append(weights, float64(trendingHours-i)/trendingHours)
+		}
+	}
+	pipeline := client().WithContext(ctx).TxPipeline()
+	pipeline.ZUnionStore(key, redis.ZStore{Weights: weights, Aggregate: "SUM"}, keys...)
+	pipeline.Expire(key, rankCacheTTL)
+	_, err = pipeline.Exec()
+	return
+}
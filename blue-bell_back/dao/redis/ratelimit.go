@@ -0,0 +1,64 @@
+package redis
+
+import (
+	"blue-bell_back/pkg/trace"
+	"context"
+	"errors"
+	"math/rand"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// ErrUnexpectedReply Lua脚本返回的数据格式不符合预期
+var ErrUnexpectedReply = errors.New("redis返回的数据格式错误")
+
+// slidingWindowScript 滑动窗口限流
+// 使用ZSet记录窗口内每次请求的时间（毫秒），先清理窗口外的记录再计数
+// 返回 {是否允许, 需要等待的毫秒数}
+var slidingWindowScript = redis.NewScript(`
+local key = KEYS[1]
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+local member = ARGV[4]
+
+redis.call('ZREMRANGEBYSCORE', key, 0, now - window)
+local count = redis.call('ZCARD', key)
+if count < limit then
+	redis.call('ZADD', key, now, member)
+	redis.call('PEXPIRE', key, window)
+	return {1, 0}
+end
+
+local retry = window
+local oldest = redis.call('ZRANGE', key, 0, 0, 'WITHSCORES')
+if oldest[2] then
+	retry = tonumber(oldest[2]) + window - now
+end
+return {0, retry}
+`)
+
+// AllowRequest 判断在window时间内对key的请求次数是否超过limit
+// 返回值: allowed 是否允许本次请求，retryAfter 被拒绝时需要等待的时间
+func AllowRequest(ctx context.Context, key string, limit int, window time.Duration) (allowed bool, retryAfter time.Duration, err error) {
+	ctx, span := startSpan(ctx, "AllowRequest", trace.String("db.redis.key", key))
+	defer span.Finish(&err)
+
+	now := time.Now().UnixMilli()
+	member := strconv.FormatInt(now, 10) + "-" + strconv.FormatInt(rand.Int63(), 36)
+	res, err := slidingWindowScript.Run(client().WithContext(ctx),
+		[]string{getRedisKey(KeyRateLimitPreFix + key)},
+		now, window.Milliseconds(), limit, member).Result()
+	if err != nil {
+		return false, 0, err
+	}
+	values, ok := res.([]interface{})
+	if !ok || len(values) != 2 {
+		return false, 0, ErrUnexpectedReply
+	}
+	allowedN, _ := values[0].(int64)
+	retryMs, _ := values[1].(int64)
+	return allowedN == 1, time.Duration(retryMs) * time.Millisecond, nil
+}
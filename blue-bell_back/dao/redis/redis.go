@@ -2,19 +2,78 @@ package redis
 
 import (
 	"blue-bell_back/settings"
+	"context"
 	"fmt"
+	"sync/atomic"
+	"time"
 
 	"github.com/go-redis/redis"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
 )
 
-var rdb *redis.Client //rdb是全局的Redis客户端实例
+// rdb是全局的Redis客户端实例
+// go-redis的连接池大小在创建后不能修改，热加载时会创建新的客户端整体替换
+var rdb atomic.Pointer[redis.Client]
+
+// closeDelay 替换客户端后，旧客户端延迟关闭的时间，保证正在执行的命令完成
+const closeDelay = 30 * time.Second
+
+// client 返回当前的Redis客户端
+func client() *redis.Client {
+	return rdb.Load()
+}
 
 // Init 初始化 Redis 客户端
 // 参数 cfg 是 Redis 服务器的配置信息
 // 返回可能的错误
 func Init(cfg *settings.RedisConfig) (err error) {
-	//根据配置信息创建Redis客户端实例
-	rdb = redis.NewClient(&redis.Options{
+	c := newClient(cfg)
+	//测试链接是否成果
+	if _, err = c.Ping().Result(); err != nil {
+		return
+	}
+	rdb.Store(c)
+
+	//连接池大小支持热加载
+	settings.Subscribe(onConfigChange)
+	registerMetrics()
+	return
+}
+
+// registerMetrics 注册连接池状态指标，采集时从 PoolStats() 读取
+// 累计的次数使用Counter，热加载替换客户端后计数会从0重新开始，rate()会把它识别为计数重置
+func registerMetrics() {
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "bluebell_redis_pool_total_connections",
+		Help: "Number of connections in the Redis pool.",
+	}, func() float64 { return float64(client().PoolStats().TotalConns) })
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "bluebell_redis_pool_idle_connections",
+		Help: "Number of idle connections in the Redis pool.",
+	}, func() float64 { return float64(client().PoolStats().IdleConns) })
+	promauto.NewCounterFunc(prometheus.CounterOpts{
+		Name: "bluebell_redis_pool_stale_connections_total",
+		Help: "Number of stale connections removed from the Redis pool.",
+	}, func() float64 { return float64(client().PoolStats().StaleConns) })
+	promauto.NewCounterFunc(prometheus.CounterOpts{
+		Name: "bluebell_redis_pool_hits_total",
+		Help: "Number of times a free connection was found in the Redis pool.",
+	}, func() float64 { return float64(client().PoolStats().Hits) })
+	promauto.NewCounterFunc(prometheus.CounterOpts{
+		Name: "bluebell_redis_pool_misses_total",
+		Help: "Number of times a free connection was not found in the Redis pool.",
+	}, func() float64 { return float64(client().PoolStats().Misses) })
+	promauto.NewCounterFunc(prometheus.CounterOpts{
+		Name: "bluebell_redis_pool_timeouts_total",
+		Help: "Number of times a wait timeout occurred in the Redis pool.",
+	}, func() float64 { return float64(client().PoolStats().Timeouts) })
+}
+
+// newClient 根据配置信息创建Redis客户端实例
+func newClient(cfg *settings.RedisConfig) *redis.Client {
+	return redis.NewClient(&redis.Options{
 		//使用viper读取配置信息，格式转化为host:port形式
 		Addr: fmt.Sprintf("%s:%d",
 			// viper.GetString("redis.host"),
@@ -34,13 +93,33 @@ func Init(cfg *settings.RedisConfig) (err error) {
 		DB:       cfg.DB,
 		PoolSize: cfg.PoolSize,
 	})
+}
 
-	//测试链接是否成果
-	_, err = rdb.Ping().Result()
-	return
+// onConfigChange 配置热加载时按新的连接池大小重建客户端
+func onConfigChange(oldConf, newConf *settings.AppConfig) {
+	if oldConf.RedisConfig == nil || newConf.RedisConfig == nil ||
+		newConf.PoolSize == oldConf.PoolSize {
+		return
+	}
+	c := newClient(newConf.RedisConfig)
+	if _, err := c.Ping().Result(); err != nil {
+		zap.L().Error("redis client with new pool_size ping failed, keep current client", zap.Error(err))
+		_ = c.Close()
+		return
+	}
+	old := rdb.Swap(c)
+	time.AfterFunc(closeDelay, func() {
+		_ = old.Close()
+	})
+	zap.L().Info("redis pool_size changed", zap.Int("pool_size", newConf.PoolSize))
+}
+
+// Ping 检查Redis连接是否可用
+func Ping(ctx context.Context) error {
+	return client().WithContext(ctx).Ping().Err()
 }
 
 // Close 关闭 Redis 客户端连接
 func Close() {
-	_ = rdb.Close()
+	_ = client().Close()
 }
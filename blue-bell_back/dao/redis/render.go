@@ -0,0 +1,37 @@
+package redis
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// 帖子内容渲染结果的缓存
+// 缓存的key包含内容和渲染规则的版本，内容或规则变化后使用新的key，旧的缓存过期后自动清除
+
+// postHTMLTTL 渲染结果的过期时间，每次读取时不延长
+const postHTMLTTL = 7 * 24 * time.Hour
+
+func postHTMLKey(postID int64, revision string) string {
+	return getRedisKey(KeyPostHTMLPreFix + strconv.FormatInt(postID, 10) + ":" + revision)
+}
+
+// GetPostHTML 查询缓存的渲染结果，没有缓存时ok为false
+func GetPostHTML(ctx context.Context, postID int64, revision string) (html string, ok bool, err error) {
+	ctx, span := startSpan(ctx, "GetPostHTML")
+	defer span.Finish(&err)
+	html, err = client().WithContext(ctx).Get(postHTMLKey(postID, revision)).Result()
+	if err == redis.Nil {
+		return "", false, nil
+	}
+	return html, err == nil, err
+}
+
+// SetPostHTML 缓存渲染结果
+func SetPostHTML(ctx context.Context, postID int64, revision, html string) (err error) {
+	ctx, span := startSpan(ctx, "SetPostHTML")
+	defer span.Finish(&err)
+	return client().WithContext(ctx).Set(postHTMLKey(postID, revision), html, postHTMLTTL).Err()
+}
@@ -0,0 +1,88 @@
+package redis
+
+import (
+	"blue-bell_back/models"
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// 用户收藏的帖子
+// Redis中的收藏列表是MySQL的缓存，不存在时从MySQL重建，过期后自动清除
+
+// savedTTL 收藏列表的过期时间，每次读取时延长
+const savedTTL = 7 * 24 * time.Hour
+
+func savedKey(userID int64) string {
+	return getRedisKey(KeySavedZSetPreFix + strconv.FormatInt(userID, 10))
+}
+
+// addSavedScript 收藏列表已经缓存时才加入，未缓存时等读取时从MySQL重建，避免只缓存部分收藏
+// 已经收藏过的帖子保持原来的收藏时间，和MySQL一致
+var addSavedScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 1 then
+	redis.call('ZADD', KEYS[1], 'NX', ARGV[1], ARGV[2])
+end
+return 0
+`)
+
+// AddSavedPost 把帖子加入已缓存的收藏列表
+func AddSavedPost(ctx context.Context, userID, postID int64, saveTime time.Time) (err error) {
+	ctx, span := startSpan(ctx, "AddSavedPost")
+	defer span.Finish(&err)
+	return addSavedScript.Run(client().WithContext(ctx), []string{savedKey(userID)}, saveTime.Unix(), postID).Err()
+}
+
+// RemoveSavedPost 把帖子移出收藏列表
+func RemoveSavedPost(ctx context.Context, userID, postID int64) (err error) {
+	ctx, span := startSpan(ctx, "RemoveSavedPost")
+	defer span.Finish(&err)
+	return client().WithContext(ctx).ZRem(savedKey(userID), postID).Err()
+}
+
+// HasSavedPosts 判断收藏列表是否已经缓存
+func HasSavedPosts(ctx context.Context, userID int64) (ok bool, err error) {
+	ctx, span := startSpan(ctx, "HasSavedPosts")
+	defer span.Finish(&err)
+	n, err := client().WithContext(ctx).Exists(savedKey(userID)).Result()
+	return n == 1, err
+}
+
+// CacheSavedPosts 用MySQL中的收藏重建收藏列表
+func CacheSavedPosts(ctx context.Context, userID int64, list []*models.SavedPost) (err error) {
+	if len(list) == 0 {
+		return nil
+	}
+	ctx, span := startSpan(ctx, "CacheSavedPosts")
+	defer span.Finish(&err)
+	key := savedKey(userID)
+	members := make([]redis.Z, 0, len(list))
+	for _, s := range list {
+		members = append(members, redis.Z{Score: float64(s.CreateTime.Unix()), Member: s.PostID})
+	}
+	pipeline := client().WithContext(ctx).TxPipeline()
+	pipeline.Del(key)
+	pipeline.ZAdd(key, members...)
+	pipeline.Expire(key, savedTTL)
+	_, err = pipeline.Exec()
+	return
+}
+
+// GetSavedPostIDs 按收藏时间倒序分页查询收藏的帖子ID
+func GetSavedPostIDs(ctx context.Context, userID, page, size int64) (ids []string, err error) {
+	key := savedKey(userID)
+	if ids, err = getIDsFormKey(ctx, key, page, size); err != nil {
+		return nil, err
+	}
+	client().WithContext(ctx).Expire(key, savedTTL)
+	return ids, nil
+}
+
+// DeleteSavedPosts 删除用户的收藏列表
+func DeleteSavedPosts(ctx context.Context, userID int64) (err error) {
+	ctx, span := startSpan(ctx, "DeleteSavedPosts")
+	defer span.Finish(&err)
+	return client().WithContext(ctx).Del(savedKey(userID)).Err()
+}
@@ -0,0 +1,24 @@
+package redis
+
+import (
+	"blue-bell_back/models"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestAddSavedPostKeepsSaveTime(t *testing.T) {
+	mr.FlushAll()
+	ctx := context.Background()
+	saved := time.Now().Add(-time.Hour)
+	if err := CacheSavedPosts(ctx, 7, []*models.SavedPost{{PostID: 1, CreateTime: saved}}); err != nil {
+		t.Fatal(err)
+	}
+	// 重复收藏不改变收藏时间
+	if err := AddSavedPost(ctx, 7, 1, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if got := zscore(t, savedKey(7), "1"); got != float64(saved.Unix()) {
+		t.Errorf("save time = %v, want %v", got, saved.Unix())
+	}
+}
@@ -0,0 +1,65 @@
+package redis
+
+import (
+	"blue-bell_back/pkg/errs"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// 一次性令牌，用于邮箱验证、重置密码、确认注销账户和连接事件流
+// Redis中只保存令牌的哈希值，令牌本身只交给用户（邮件或接口响应）
+
+// 令牌的用途
+const (
+	TokenEmailVerify   = "email_verify:"
+	TokenPasswordReset = "password_reset:"
+	TokenStreamTicket  = "stream_ticket:"
+	TokenAccountDelete = "account_delete:"
+)
+
+// ErrTokenInvalid 令牌不存在、已过期或已被使用
+var ErrTokenInvalid = errs.Expired("令牌无效或已过期")
+
+// takeTokenScript 读取并删除令牌，保证令牌只能使用一次
+var takeTokenScript = redis.NewScript(`
+local v = redis.call('GET', KEYS[1])
+if v then
+	redis.call('DEL', KEYS[1])
+end
+return v
+`)
+
+func tokenKey(kind, token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return getRedisKey(KeyTokenPreFix + kind + hex.EncodeToString(sum[:]))
+}
+
+// SaveToken 保存令牌，ttl后过期
+func SaveToken(ctx context.Context, kind, token string, userID int64, ttl time.Duration) (err error) {
+	ctx, span := startSpan(ctx, "SaveToken")
+	defer span.Finish(&err)
+	return client().WithContext(ctx).Set(tokenKey(kind, token), userID, ttl).Err()
+}
+
+// TakeToken 取出令牌对应的用户ID，令牌随即失效
+func TakeToken(ctx context.Context, kind, token string) (userID int64, err error) {
+	ctx, span := startSpan(ctx, "TakeToken")
+	defer span.Finish(&err)
+	v, err := takeTokenScript.Run(client().WithContext(ctx), []string{tokenKey(kind, token)}).Result()
+	if err == redis.Nil {
+		return 0, ErrTokenInvalid
+	}
+	if err != nil {
+		return 0, err
+	}
+	s, ok := v.(string)
+	if !ok {
+		return 0, ErrTokenInvalid
+	}
+	return strconv.ParseInt(s, 10, 64)
+}
@@ -0,0 +1,12 @@
+package redis
+
+import (
+	"blue-bell_back/pkg/trace"
+	"context"
+)
+
+// startSpan 为一次Redis操作创建链路节点
+func startSpan(ctx context.Context, name string, attrs ...trace.Attr) (context.Context, *trace.Span) {
+	attrs = append(attrs, trace.String("db.system", "redis"))
+	return trace.StartWithKind(ctx, "redis."+name, trace.KindClient, attrs...)
+}
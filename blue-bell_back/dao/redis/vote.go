@@ -1,8 +1,9 @@
 package redis
 
 import (
-	"errors"
-	"math"
+	"blue-bell_back/pkg/errs"
+	"blue-bell_back/pkg/trace"
+	"context"
 	"strconv"
 	"time"
 
@@ -41,26 +42,44 @@ const (
 )
 
 var (
-	ErrVoteExpire      = errors.New("距离帖子发出时间已超过7天,不可点赞")
-	ErrVoteRepestition = errors.New("不允许重复投票")
+	ErrVoteExpire      = errs.Expired("距离帖子发出时间已超过7天,不可点赞")
+	ErrVoteRepestition = errs.Conflict("不允许重复投票")
 )
 
 // 创建帖子存储时间
-func CreateCommunityPost(postID, communityID int64) (err error) {
+// createTime为发帖时间（Unix秒），重复执行时结果不变，已有的分数不会被覆盖
+func CreateCommunityPost(ctx context.Context, postID, communityID, authorID, createTime int64, tags []string) (err error) {
+	ctx, span := startSpan(ctx, "CreateCommunityPost", trace.Int64("post_id", postID))
+	defer span.Finish(&err)
 	//使用事务更新redis数据
-	pipeline := rdb.TxPipeline()
+	pipeline := client().WithContext(ctx).TxPipeline()
 	//更新帖子时间
 	pipeline.ZAdd(getRedisKey(KeyPostTimeZSet), redis.Z{
-		Score:  float64(time.Now().Unix()),
+		Score:  float64(createTime),
 		Member: postID,
 	})
 	//把帖子id加入到社区的set中
 	communityKey := getRedisKey(KeyCommunitySetPreFix + strconv.FormatInt(communityID, 10))
 	pipeline.SAdd(communityKey, postID)
+	//把帖子id加入到每个标签的set中
+	for _, tag := range tags {
+		pipeline.SAdd(getRedisKey(KeyTagSetPreFix+tag), postID)
+	}
+	//把帖子id加入到作者的帖子列表中，用于关注的人的动态
+	if authorID > 0 {
+		pipeline.ZAdd(authorPostsKey(authorID), redis.Z{
+			Score:  float64(createTime),
+			Member: postID,
+		})
+	}
 
-	//更新帖子分数
-	pipeline.ZAdd(getRedisKey(KeyPostScoreZSet), redis.Z{
-		Score:  float64(time.Now().Unix()),
+	//初始化帖子分数和净票数，已经有投票时不覆盖
+	pipeline.ZAddNX(getRedisKey(KeyPostScoreZSet), redis.Z{
+		Score:  float64(createTime),
+		Member: postID,
+	})
+	pipeline.ZAddNX(getRedisKey(KeyPostVotesZSet), redis.Z{
+		Score:  0,
 		Member: postID,
 	})
 
@@ -68,46 +87,65 @@ func CreateCommunityPost(postID, communityID int64) (err error) {
 	return
 }
 
-func VoteForCommunity(userID, postID string, value float64) (err error) {
-	//1.判断投票限制
-	//从redis获取帖子发布的时间
-	postTime := rdb.ZScore(getRedisKey(KeyPostTimeZSet), postID).Val()
-
-	if float64(time.Now().Unix())-postTime > oneWeekSeconds {
-		return ErrVoteExpire
-	}
-
-	//2.更新帖子分数
-	//查询当前用户给当前帖子的投票记录
-
-	oldValue := rdb.ZScore(getRedisKey(KeyPostVoteZSetPreFix+postID), userID).Val()
-
-	var op float64
-
-	//检验是否重复投票
-	if value == oldValue {
-		return ErrVoteRepestition
+// voteScript 在一个脚本中完成投票的检查和更新，同一个用户的并发投票不会重复计分
+// KEYS: 帖子发布时间 帖子分数 帖子的投票记录 帖子的净票数 当前小时的净票数 当天的净票数 用户投过票的帖子
+// ARGV: 帖子ID 用户ID 投票方向 当前时间 投票期限 每票的分数 小时的保留时间 天的保留时间
+// 返回 1投票成功 0和上次投票相同 -1投票期已过
+var voteScript = redis.NewScript(`
+local postTime = redis.call('ZSCORE', KEYS[1], ARGV[1])
+if not postTime or tonumber(ARGV[4]) - tonumber(postTime) > tonumber(ARGV[5]) then
+	return -1
+end
+local value = tonumber(ARGV[3])
+local old = tonumber(redis.call('ZSCORE', KEYS[3], ARGV[2]) or '0')
+if value == old then
+	return 0
+end
+local delta = value - old
+redis.call('ZINCRBY', KEYS[2], delta * tonumber(ARGV[6]), ARGV[1])
+redis.call('ZINCRBY', KEYS[4], delta, ARGV[1])
+redis.call('ZINCRBY', KEYS[5], delta, ARGV[1])
+redis.call('EXPIRE', KEYS[5], ARGV[7])
+redis.call('ZINCRBY', KEYS[6], delta, ARGV[1])
+redis.call('EXPIRE', KEYS[6], ARGV[8])
+if value == 0 then
+	redis.call('ZREM', KEYS[3], ARGV[2])
+	redis.call('SREM', KEYS[7], ARGV[1])
+else
+	redis.call('ZADD', KEYS[3], value, ARGV[2])
+	redis.call('SADD', KEYS[7], ARGV[1])
+end
+return 1
+`)
+
+// VoteForCommunity 记录用户的投票并更新帖子分数
+// 检查投票期限、比较上次投票和更新分数在Redis中原子执行，重复提交同样的投票返回ErrVoteRepestition且不改变分数
+// 净票数的变化同时计入当前小时和当天的ZSet，用于按时间段的排行
+func VoteForCommunity(ctx context.Context, userID, postID string, value float64) (err error) {
+	ctx, span := startSpan(ctx, "VoteForCommunity", trace.String("post_id", postID))
+	defer span.Finish(&err)
+	now := time.Now().Unix()
+	keys := []string{
+		getRedisKey(KeyPostTimeZSet),
+		getRedisKey(KeyPostScoreZSet),
+		getRedisKey(KeyPostVoteZSetPreFix + postID),
+		getRedisKey(KeyPostVotesZSet),
+		hourBucketKey(now / hourSeconds),
+		dayBucketKey(now / daySeconds),
+		getRedisKey(KeyUserVotedSetPreFix + userID),
 	}
-	if value > oldValue {
-		//当前的分数大于历史分数
-		op = 1
-	} else {
-		op = -1
+	res, err := voteScript.Run(client().WithContext(ctx), keys,
+		postID, userID, value, now, oneWeekSeconds, oneTicketScore, hourBucketTTL, dayBucketTTL).Int64()
+	if err != nil {
+		return err
 	}
-	diff := math.Abs(oldValue - value) // 计算两次投票的差值
-	pipeline := rdb.TxPipeline()
-	pipeline.ZIncrBy(getRedisKey(KeyPostScoreZSet), op*diff*oneTicketScore, postID)
-
-	// 3.记录用户为该帖子投票的数据
-	if value == 0 {
-		// 取消投票
-		pipeline.ZRem(getRedisKey(KeyPostVoteZSetPreFix+postID), postID)
-	} else {
-		pipeline.ZAdd(getRedisKey(KeyPostVoteZSetPreFix+postID), redis.Z{
-			Score:  value, // 赞成/反对
-			Member: userID,
-		})
+	switch res {
+	case 1:
+		return nil
+	case 0:
+		return ErrVoteRepestition
+	case -1:
+		return ErrVoteExpire
 	}
-	_, err = pipeline.Exec()
-	return
+	return ErrUnexpectedReply
 }
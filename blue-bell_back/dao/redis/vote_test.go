@@ -0,0 +1,146 @@
+package redis
+
+import (
+	"blue-bell_back/settings"
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+var mr *miniredis.Miniredis
+
+func TestMain(m *testing.M) {
+	var err error
+	mr, err = miniredis.Run()
+	if err != nil {
+		panic(err)
+	}
+	port, _ := strconv.Atoi(mr.Port())
+	if err = Init(&settings.RedisConfig{Host: mr.Host(), Port: port, PoolSize: 64}); err != nil {
+		panic(err)
+	}
+	code := m.Run()
+	mr.Close()
+	os.Exit(code)
+}
+
+// zscore 读取ZSet中成员的分数，不存在时返回0
+func zscore(t *testing.T, key, member string) float64 {
+	t.Helper()
+	if !mr.Exists(key) {
+		return 0
+	}
+	score, err := mr.ZScore(key, member)
+	if err != nil {
+		return 0
+	}
+	return score
+}
+
+func TestVoteConcurrentDuplicates(t *testing.T) {
+	mr.FlushAll()
+	ctx := context.Background()
+	now := time.Now().Unix()
+	if err := CreateCommunityPost(ctx, 1, 1, 9, now, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// 同一个用户并发提交同样的投票，只有一次生效
+	const n = 50
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	start := make(chan struct{})
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			errs <- VoteForCommunity(ctx, "7", "1", 1)
+		}()
+	}
+	close(start)
+	wg.Wait()
+	close(errs)
+
+	var ok, repeated int
+	for err := range errs {
+		switch {
+		case err == nil:
+			ok++
+		case errors.Is(err, ErrVoteRepestition):
+			repeated++
+		default:
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if ok != 1 || repeated != n-1 {
+		t.Fatalf("got %d successful and %d repeated votes, want 1 and %d", ok, repeated, n-1)
+	}
+
+	if got, want := zscore(t, getRedisKey(KeyPostScoreZSet), "1"), float64(now)+oneTicketScore; got != want {
+		t.Errorf("post score = %v, want %v", got, want)
+	}
+	if got := zscore(t, getRedisKey(KeyPostVotesZSet), "1"); got != 1 {
+		t.Errorf("post votes = %v, want 1", got)
+	}
+	if got := zscore(t, hourBucketKey(now/hourSeconds), "1"); got != 1 {
+		t.Errorf("hour bucket votes = %v, want 1", got)
+	}
+	if got := zscore(t, dayBucketKey(now/daySeconds), "1"); got != 1 {
+		t.Errorf("day bucket votes = %v, want 1", got)
+	}
+	if got := zscore(t, getRedisKey(KeyPostVoteZSetPreFix+"1"), "7"); got != 1 {
+		t.Errorf("recorded vote = %v, want 1", got)
+	}
+}
+
+func TestVoteChangeDirection(t *testing.T) {
+	mr.FlushAll()
+	ctx := context.Background()
+	now := time.Now().Unix()
+	if err := CreateCommunityPost(ctx, 1, 1, 9, now, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		value float64
+		votes float64 // 之后帖子的净票数
+	}{
+		{1, 1},
+		{-1, -1},
+		{0, 0},
+		{-1, -1},
+	}
+	for _, s := range steps {
+		if err := VoteForCommunity(ctx, "7", "1", s.value); err != nil {
+			t.Fatalf("vote %v: %v", s.value, err)
+		}
+		if got := zscore(t, getRedisKey(KeyPostVotesZSet), "1"); got != s.votes {
+			t.Fatalf("after vote %v: post votes = %v, want %v", s.value, got, s.votes)
+		}
+		if got, want := zscore(t, getRedisKey(KeyPostScoreZSet), "1"), float64(now)+s.votes*oneTicketScore; got != want {
+			t.Fatalf("after vote %v: post score = %v, want %v", s.value, got, want)
+		}
+	}
+}
+
+func TestVoteExpired(t *testing.T) {
+	mr.FlushAll()
+	ctx := context.Background()
+	created := time.Now().Unix() - oneWeekSeconds - 1
+	if err := CreateCommunityPost(ctx, 1, 1, 9, created, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := VoteForCommunity(ctx, "7", "1", 1); !errors.Is(err, ErrVoteExpire) {
+		t.Fatalf("got %v, want ErrVoteExpire", err)
+	}
+	if got := zscore(t, getRedisKey(KeyPostVotesZSet), "1"); got != 0 {
+		t.Errorf("post votes = %v, want 0", got)
+	}
+}
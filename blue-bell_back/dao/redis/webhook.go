@@ -0,0 +1,83 @@
+package redis
+
+import (
+	"blue-bell_back/pkg/trace"
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// 事件推送的重试队列
+// 队列是一个ZSet，成员为推送记录ID，分数为下次发送的时间
+// 取出时不删除，而是把分数推迟一个租期，实例在发送过程中退出时，租期过后其他实例会重新发送
+
+// claimDeliveriesScript 取出到期的推送记录并把它们推迟lease毫秒
+var claimDeliveriesScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local lease = tonumber(ARGV[2])
+local ids = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', now, 'LIMIT', 0, tonumber(ARGV[3]))
+for _, id in ipairs(ids) do
+	redis.call('ZADD', KEYS[1], now + lease, id)
+end
+return ids
+`)
+
+// EnqueueDelivery 把推送记录加入队列，at时发送，已在队列中时更新发送时间
+func EnqueueDelivery(ctx context.Context, deliveryID int64, at time.Time) (err error) {
+	ctx, span := startSpan(ctx, "EnqueueDelivery", trace.Int64("webhook.delivery_id", deliveryID))
+	defer span.Finish(&err)
+	return client().WithContext(ctx).ZAdd(getRedisKey(KeyWebhookQueueZSet), redis.Z{
+		Score:  float64(at.UnixMilli()),
+		Member: deliveryID,
+	}).Err()
+}
+
+// RequeueDeliveries 把不在队列中的推送记录加入队列立即发送，已在队列中的保持原来的发送时间，返回加入的数量
+func RequeueDeliveries(ctx context.Context, deliveryIDs []int64) (n int64, err error) {
+	if len(deliveryIDs) == 0 {
+		return 0, nil
+	}
+	ctx, span := startSpan(ctx, "RequeueDeliveries", trace.Int("webhook.deliveries", len(deliveryIDs)))
+	defer span.Finish(&err)
+	now := float64(time.Now().UnixMilli())
+	members := make([]redis.Z, 0, len(deliveryIDs))
+	for _, id := range deliveryIDs {
+		members = append(members, redis.Z{Score: now, Member: id})
+	}
+	return client().WithContext(ctx).ZAddNX(getRedisKey(KeyWebhookQueueZSet), members...).Result()
+}
+
+// ClaimDeliveries 取出最多limit个到期的推送记录，lease内不会被再次取出
+func ClaimDeliveries(ctx context.Context, lease time.Duration, limit int) (ids []int64, err error) {
+	ctx, span := startSpan(ctx, "ClaimDeliveries")
+	defer span.Finish(&err)
+	keys := []string{getRedisKey(KeyWebhookQueueZSet)}
+	v, err := claimDeliveriesScript.Run(client().WithContext(ctx), keys,
+		time.Now().UnixMilli(), lease.Milliseconds(), limit).Result()
+	if err != nil {
+		return nil, err
+	}
+	members, ok := v.([]interface{})
+	if !ok {
+		return nil, ErrUnexpectedReply
+	}
+	ids = make([]int64, 0, len(members))
+	for _, m := range members {
+		s, _ := m.(string)
+		id, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, ErrUnexpectedReply
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// RemoveDelivery 把推送记录移出队列
+func RemoveDelivery(ctx context.Context, deliveryID int64) (err error) {
+	ctx, span := startSpan(ctx, "RemoveDelivery", trace.Int64("webhook.delivery_id", deliveryID))
+	defer span.Finish(&err)
+	return client().WithContext(ctx).ZRem(getRedisKey(KeyWebhookQueueZSet), deliveryID).Err()
+}
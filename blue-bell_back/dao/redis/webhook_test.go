@@ -0,0 +1,35 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRequeueDeliveries(t *testing.T) {
+	mr.FlushAll()
+	ctx := context.Background()
+	retryAt := time.Now().Add(time.Hour)
+	if err := EnqueueDelivery(ctx, 1, retryAt); err != nil {
+		t.Fatal(err)
+	}
+	n, err := RequeueDeliveries(ctx, []int64{1, 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("requeued %d deliveries, want 1", n)
+	}
+	// 等待重试的记录保持原来的发送时间，遗漏的记录立即发送
+	key := getRedisKey(KeyWebhookQueueZSet)
+	if got := zscore(t, key, "1"); got != float64(retryAt.UnixMilli()) {
+		t.Errorf("delivery 1 send time = %v, want %v", got, retryAt.UnixMilli())
+	}
+	ids, err := ClaimDeliveries(ctx, time.Minute, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 1 || ids[0] != 2 {
+		t.Errorf("claimed %v, want [2]", ids)
+	}
+}
@@ -0,0 +1,69 @@
+package logger
+
+import (
+	"blue-bell_back/pkg/trace"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"go.uber.org/zap"
+)
+
+// RequestIDHeader 请求ID使用的HTTP头，客户端传入时沿用，否则由服务端生成
+const RequestIDHeader = "X-Request-ID"
+
+// requestIDKey context中保存请求ID使用的键
+type requestIDKey struct{}
+
+// NewContext 返回携带请求ID的context
+func NewContext(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestIDFromContext 从context中取出请求ID，不存在时返回空字符串
+func RequestIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// FromContext 返回带有请求ID字段的logger
+// logic和dao层记录日志时统一使用它，这样同一个请求的日志可以通过request_id关联起来
+// 开启链路追踪时同时带上trace_id，方便从日志跳转到链路
+func FromContext(ctx context.Context) *zap.Logger {
+	fields := make([]zap.Field, 0, 2)
+	if id := RequestIDFromContext(ctx); id != "" {
+		fields = append(fields, zap.String("request_id", id))
+	}
+	if traceID := trace.TraceID(ctx); traceID != "" {
+		fields = append(fields, zap.String("trace_id", traceID))
+	}
+	if len(fields) == 0 {
+		return zap.L()
+	}
+	return zap.L().With(fields...)
+}
+
+// newRequestID 生成一个随机的请求ID
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// validRequestID 校验客户端传入的请求ID，避免超长或带有特殊字符的内容写入日志
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
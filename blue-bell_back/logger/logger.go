@@ -1,6 +1,7 @@
 package logger
 
 import (
+	"blue-bell_back/pkg/trace"
 	"blue-bell_back/settings"
 	"net"
 	"net/http"
@@ -18,6 +19,9 @@ import (
 	"go.uber.org/zap/zapcore"
 )
 
+// level 全局日志级别，支持在运行时修改
+var level = zap.NewAtomicLevel()
+
 // Init// Init 初始化日志系统
 // cfg: 日志配置信息，包含日志文件名、最大大小、备份文件最大数量和最大保留天数
 // func Init() (err error) {
@@ -33,24 +37,43 @@ func Init(cfg *settings.LogConfig) (err error) {
 		cfg.MaxAge,
 	)
 	encoder := getEncoder() //调用 getEncoder() 定义日志输出格式（JSON 格式
-	var l = new(zapcore.Level)
 	//err = l.UnmarshalText([]byte(viper.GetString("log.level")))
 	//将配置中的日志级别文本转换为zapcore.Level类型
-	err = l.UnmarshalText([]byte(cfg.Level))
+	err = level.UnmarshalText([]byte(cfg.Level))
 	if err != nil {
 		return
 	}
 
 	//创建zapcore.Core实例
-	core := zapcore.NewCore(encoder, writeSyncer, l)
+	core := zapcore.NewCore(encoder, writeSyncer, level)
+	//开发环境同时把日志输出到终端，方便查看
+	if cfg.Console {
+		core = zapcore.NewTee(core, zapcore.NewCore(getConsoleEncoder(), zapcore.Lock(os.Stdout), level))
+	}
 
 	//创建新的Logger实例
 	lg := zap.New(core, zap.AddCaller())
 	// 替换zap库中全局的logger
 	zap.ReplaceGlobals(lg) //将这个 logger 设为全局默认 logger，后续使用 zap.L() 即可获取。
+
+	//配置文件中的日志级别修改后立即生效
+	settings.Subscribe(onConfigChange)
 	return
 }
 
+// onConfigChange 配置热加载时更新日志级别
+func onConfigChange(oldConf, newConf *settings.AppConfig) {
+	if newConf.LogConfig == nil || oldConf.LogConfig == nil || newConf.Level == oldConf.Level {
+		return
+	}
+	if err := level.UnmarshalText([]byte(newConf.Level)); err != nil {
+		zap.L().Warn("invalid log level in config, keep current level",
+			zap.String("level", newConf.Level), zap.Error(err))
+		return
+	}
+	zap.L().Info("log level changed", zap.String("level", level.String()))
+}
+
 // getEncoder 创建一个日志编码器
 // 返回值: zapcore.Encoder类型的日志编码器
 func getEncoder() zapcore.Encoder {
@@ -73,6 +96,26 @@ func getEncoder() zapcore.Encoder {
 	// {"time":"2025-04-05T12:34:56.789+0800","level":"INFO","caller":"main.go:50","msg":"/api/users","status":200,"method":"GET",...}
 }
 
+// getConsoleEncoder 创建终端输出使用的日志编码器
+// 终端中使用易读的文本格式，日志级别带颜色
+func getConsoleEncoder() zapcore.Encoder {
+	encoderConfig := zap.NewDevelopmentEncoderConfig()
+	encoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+	encoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
+	return zapcore.NewConsoleEncoder(encoderConfig)
+}
+
+// GetLevel 返回当前的日志级别
+func GetLevel() string {
+	return level.String()
+}
+
+// SetLevel 在运行时修改日志级别
+// 参数 text 为日志级别名称，如 debug、info、warn、error
+func SetLevel(text string) error {
+	return level.UnmarshalText([]byte(text))
+}
+
 // getLogWriter 创建一个日志写入器
 // 参数: filename: 日志文件名 maxSize: 单个日志文件最大大小（MB）
 // maxBackup: 最大备份文件数量 maxAge: 最大保留天数
@@ -101,13 +144,26 @@ func GinLogger() gin.HandlerFunc {
 		path := c.Request.URL.Path
 		// 获取查询字符串
 		query := c.Request.URL.RawQuery
+		// 为每个请求分配请求ID，写入响应头并随context传递给logic和dao层
+		requestID := c.GetHeader(RequestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = newRequestID()
+		}
+		c.Header(RequestIDHeader, requestID)
+		c.Request = c.Request.WithContext(NewContext(c.Request.Context(), requestID))
 		// 调用c.Next()以继续执行链中的其他中间件和处理函数
 		c.Next()
 
 		// 计算请求处理时间
 		cost := time.Since(start)
+		// 链路追踪中间件在本中间件之后执行，此时从请求的context中取trace_id
+		traceID := trace.TraceID(c.Request.Context())
 		// 使用zap记录请求信息
 		zap.L().Info(path,
+			// 记录请求ID
+			zap.String("request_id", requestID),
+			// 记录链路ID
+			zap.String("trace_id", traceID),
 			// 记录HTTP状态码
 			zap.Int("status", c.Writer.Status()),
 			// 记录HTTP方法
@@ -132,8 +188,9 @@ func GinLogger() gin.HandlerFunc {
 
 // GinRecovery 是一个中间件，用于恢复出现的panic，防止服务崩溃。
 // 参数stack表示是否在日志中包含调用栈信息。
+// 参数handle负责写入错误响应，为nil时只返回500状态码。
 // 返回一个gin.HandlerFunc处理函数。
-func GinRecovery(stack bool) gin.HandlerFunc { //Gin Panic 恢复中间件
+func GinRecovery(stack bool, handle gin.HandlerFunc) gin.HandlerFunc { //Gin Panic 恢复中间件
 	return func(c *gin.Context) {
 		// 使用defer来捕获执行过程中的panic。
 		defer func() { //在 defer 中 recover 捕获 panic。
@@ -157,9 +214,10 @@ func GinRecovery(stack bool) gin.HandlerFunc { //Gin Panic 恢复中间件
 
 				// 尝试获取完整的HTTP请求信息。
 				httpRequest, _ := httputil.DumpRequest(c.Request, false) //记录请求头等信息
+				lg := FromContext(c.Request.Context())
 				// 如果是客户端断开连接错误，记录错误日志并终止处理。
 				if brokenPipe {
-					zap.L().Error(c.Request.URL.Path,
+					lg.Error(c.Request.URL.Path,
 						zap.Any("error", err),
 						zap.String("request", string(httpRequest)),
 					)
@@ -170,18 +228,23 @@ func GinRecovery(stack bool) gin.HandlerFunc { //Gin Panic 恢复中间件
 				}
 				// 如果不是客户端断开连接错误，根据配置决定是否记录调用栈信息。
 				if stack {
-					zap.L().Error("[Recovery from panic]",
+					lg.Error("[Recovery from panic]",
 						zap.Any("error", err),
 						zap.String("request", string(httpRequest)),
 						zap.String("stack", string(debug.Stack())),
 					)
 				} else {
-					zap.L().Error("[Recovery from panic]",
+					lg.Error("[Recovery from panic]",
 						zap.Any("error", err),
 						zap.String("request", string(httpRequest)),
 					)
 				}
 				// 终止处理并返回500内部服务器错误状态码。
+				if handle != nil {
+					handle(c)
+					c.Abort()
+					return
+				}
 				c.AbortWithStatus(http.StatusInternalServerError)
 			}
 		}()
@@ -0,0 +1,356 @@
+package logic
+
+import (
+	"blue-bell_back/dao/mysql"
+	"blue-bell_back/dao/redis"
+	"blue-bell_back/logger"
+	"blue-bell_back/models"
+	"blue-bell_back/pkg/errs"
+	"blue-bell_back/pkg/jwt"
+	"blue-bell_back/pkg/mailer"
+	"blue-bell_back/pkg/trace"
+	"blue-bell_back/settings"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// 邮箱验证、找回密码、修改密码和注销账户
+
+// 未验证邮箱账户的限制策略，对应配置 account.unverified_policy
+const (
+	UnverifiedAllow      = "allow"
+	UnverifiedReadOnly   = "read_only"
+	UnverifiedBlockLogin = "block_login"
+)
+
+// 注销账户时帖子的处理方式，对应配置 account.delete_posts
+const (
+	DeletePostsKeep       = "keep"
+	DeletePostsSoftDelete = "soft_delete"
+)
+
+const (
+	emailVerifyTokenTTL   = 24 * time.Hour
+	passwordResetTokenTTL = 30 * time.Minute
+	accountDeleteTokenTTL = 30 * time.Minute
+)
+
+var (
+	// ErrEmailNotVerified 邮箱未验证，按照配置的策略被限制
+	ErrEmailNotVerified = errs.Forbidden("邮箱未验证")
+	// ErrInvalidEmailToken 验证或重置令牌无效
+	ErrInvalidEmailToken = errs.Expired("链接无效或已过期")
+)
+
+// GetUnverifiedPolicy 返回当前的未验证账户策略，未配置时不限制
+func GetUnverifiedPolicy() string {
+	if conf := settings.Get().AccountConfig; conf != nil && conf.UnverifiedPolicy != "" {
+		return conf.UnverifiedPolicy
+	}
+	return UnverifiedAllow
+}
+
+// newToken 生成随机令牌
+func newToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// buildLink 生成邮件中的链接
+func buildLink(path, token string) string {
+	base := ""
+	if conf := settings.Get().MailConfig; conf != nil {
+		base = strings.TrimRight(conf.BaseURL, "/")
+	}
+	return base + path + "?token=" + url.QueryEscape(token)
+}
+
+// sendMailAsync 异步发送邮件，发送失败只记录日志，不影响请求
+func sendMailAsync(ctx context.Context, msg *mailer.Message) {
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		defer cancel()
+		if err := mailer.Send(ctx, msg); err != nil {
+			logger.FromContext(ctx).Error("mailer.Send failed", zap.String("subject", msg.Subject), zap.Error(err))
+		}
+	}()
+}
+
+// sendVerifyEmail 生成验证令牌并发送验证邮件
+func sendVerifyEmail(ctx context.Context, userID int64, username, email string) (err error) {
+	token, err := newToken()
+	if err != nil {
+		return
+	}
+	if err = redis.SaveToken(ctx, redis.TokenEmailVerify, token, userID, emailVerifyTokenTTL); err != nil {
+		return
+	}
+	sendMailAsync(ctx, &mailer.Message{
+		To:      email,
+		Subject: "请验证你的邮箱",
+		Body: fmt.Sprintf("%s，你好：\n\n请在24小时内打开下面的链接完成邮箱验证：\n%s\n\n如果不是你本人操作，请忽略这封邮件。\n",
+			username, buildLink("/verify-email", token)),
+	})
+	return
+}
+
+// VerifyEmail 使用邮件中的令牌验证邮箱
+func VerifyEmail(ctx context.Context, token string) (err error) {
+	ctx, span := trace.Start(ctx, "logic.VerifyEmail")
+	defer span.Finish(&err)
+	userID, err := redis.TakeToken(ctx, redis.TokenEmailVerify, token)
+	if err != nil {
+		if errors.Is(err, redis.ErrTokenInvalid) {
+			return ErrInvalidEmailToken
+		}
+		return
+	}
+	return mysql.SetEmailVerified(ctx, userID)
+}
+
+// ResendVerifyEmail 重新发送验证邮件，已验证时直接返回
+func ResendVerifyEmail(ctx context.Context, userID int64) (err error) {
+	ctx, span := trace.Start(ctx, "logic.ResendVerifyEmail")
+	defer span.Finish(&err)
+	user, err := mysql.GetUserByID(ctx, userID)
+	if err != nil {
+		return
+	}
+	if user.EmailVerified || user.Email == "" {
+		return
+	}
+	return sendVerifyEmail(ctx, user.UserID, user.UserName, user.Email)
+}
+
+// ForgotPassword 发送重置密码邮件
+// 邮箱不存在时同样返回成功，避免被用来探测已注册的邮箱
+func ForgotPassword(ctx context.Context, email string) (err error) {
+	ctx, span := trace.Start(ctx, "logic.ForgotPassword")
+	defer span.Finish(&err)
+	user, err := mysql.GetUserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, mysql.ErrorUserNotExist) {
+			return nil
+		}
+		return
+	}
+	token, err := newToken()
+	if err != nil {
+		return
+	}
+	if err = redis.SaveToken(ctx, redis.TokenPasswordReset, token, user.UserID, passwordResetTokenTTL); err != nil {
+		return
+	}
+	sendMailAsync(ctx, &mailer.Message{
+		To:      user.Email,
+		Subject: "重置密码",
+		Body: fmt.Sprintf("%s，你好：\n\n请在30分钟内打开下面的链接重置密码，链接只能使用一次：\n%s\n\n如果不是你本人操作，请忽略这封邮件。\n",
+			user.UserName, buildLink("/reset-password", token)),
+	})
+	return
+}
+
+// ResetPassword 使用邮件中的令牌重置密码
+// 能收到邮件说明邮箱属于该用户，重置成功后邮箱同时视为已验证
+func ResetPassword(ctx context.Context, token, password string) (err error) {
+	ctx, span := trace.Start(ctx, "logic.ResetPassword")
+	defer span.Finish(&err)
+	userID, err := redis.TakeToken(ctx, redis.TokenPasswordReset, token)
+	if err != nil {
+		if errors.Is(err, redis.ErrTokenInvalid) {
+			return ErrInvalidEmailToken
+		}
+		return
+	}
+	if err = mysql.UpdatePassword(ctx, userID, password); err != nil {
+		return
+	}
+	if _, err = redis.RevokeTokens(ctx, userID); err != nil {
+		return
+	}
+	return mysql.SetEmailVerified(ctx, userID)
+}
+
+// CheckEmailVerified 检查用户邮箱是否已验证，未验证时返回 ErrEmailNotVerified
+func CheckEmailVerified(ctx context.Context, userID int64) (err error) {
+	ctx, span := trace.Start(ctx, "logic.CheckEmailVerified")
+	defer span.Finish(&err)
+	user, err := mysql.GetUserByID(ctx, userID)
+	if err != nil {
+		return
+	}
+	if !user.EmailVerified {
+		return ErrEmailNotVerified
+	}
+	return
+}
+
+// ChangePassword 修改密码，成功后吊销之前签发的所有令牌
+// 第三方登录创建的用户没有密码，不需要旧密码即可设置
+// 返回新的令牌，当前客户端不需要重新登录
+func ChangePassword(ctx context.Context, userID int64, p *models.ParamChangePassword) (token string, err error) {
+	ctx, span := trace.Start(ctx, "logic.ChangePassword")
+	defer span.Finish(&err)
+	if err = mysql.CheckPassword(ctx, userID, p.OldPassword); err != nil && !errors.Is(err, mysql.ErrorPasswordNotSet) {
+		return
+	}
+	user, err := mysql.GetUserByID(ctx, userID)
+	if err != nil {
+		return
+	}
+	if err = mysql.UpdatePassword(ctx, userID, p.Password); err != nil {
+		return
+	}
+	validAfter, err := redis.RevokeTokens(ctx, userID)
+	if err != nil {
+		return
+	}
+	// 新令牌的签发时间必须晚于吊销时间
+	return jwt.GenTokenAt(user.UserID, user.UserName, time.UnixMilli(validAfter+1))
+}
+
+// newAccountDeleteToken 生成确认注销账户的一次性令牌
+func newAccountDeleteToken(ctx context.Context, userID int64) (token string, err error) {
+	token, err = newToken()
+	if err != nil {
+		return
+	}
+	err = redis.SaveToken(ctx, redis.TokenAccountDelete, token, userID, accountDeleteTokenTTL)
+	return
+}
+
+// SendAccountDeleteEmail 发送确认注销账户的邮件，用于没有密码的用户
+// 邮箱未验证时不能证明邮箱属于该用户，返回 ErrEmailNotVerified
+func SendAccountDeleteEmail(ctx context.Context, userID int64) (err error) {
+	ctx, span := trace.Start(ctx, "logic.SendAccountDeleteEmail")
+	defer span.Finish(&err)
+	user, err := mysql.GetUserByID(ctx, userID)
+	if err != nil {
+		return
+	}
+	if user.Email == "" || !user.EmailVerified {
+		return ErrEmailNotVerified
+	}
+	token, err := newAccountDeleteToken(ctx, userID)
+	if err != nil {
+		return
+	}
+	sendMailAsync(ctx, &mailer.Message{
+		To:      user.Email,
+		Subject: "确认注销账户",
+		Body: fmt.Sprintf("%s，你好：\n\n请在30分钟内打开下面的链接确认注销账户，注销后无法恢复：\n%s\n\n如果不是你本人操作，请尽快修改密码。\n",
+			user.UserName, buildLink("/delete-account", token)),
+	})
+	return
+}
+
+// checkDeleteConfirm 校验注销账户的确认方式
+// 提供令牌时使用第三方账户再次验证身份或邮件确认得到的令牌，否则校验密码
+func checkDeleteConfirm(ctx context.Context, userID int64, p *models.ParamDeleteAccount) error {
+	if p.Token == "" {
+		return mysql.CheckPassword(ctx, userID, p.Password)
+	}
+	tokenUserID, err := redis.TakeToken(ctx, redis.TokenAccountDelete, p.Token)
+	if errors.Is(err, redis.ErrTokenInvalid) || (err == nil && tokenUserID != userID) {
+		return ErrInvalidEmailToken
+	}
+	return err
+}
+
+// DeleteAccount 注销账户
+// 需要密码确认，没有密码的用户使用第三方账户再次验证身份或邮件确认得到的令牌
+// 依次删除用户的投票、按配置处理帖子、吊销令牌，最后解除第三方账户绑定并清除用户信息
+// 中途失败时用户仍然可以重新登录后再次注销
+func DeleteAccount(ctx context.Context, userID int64, p *models.ParamDeleteAccount) (err error) {
+	ctx, span := trace.Start(ctx, "logic.DeleteAccount")
+	defer span.Finish(&err)
+	if err = checkDeleteConfirm(ctx, userID, p); err != nil {
+		return
+	}
+
+	//1.删除投票并扣除帖子分数
+	removed, err := redis.RemoveUserVotes(ctx, userID)
+	if err != nil {
+		return
+	}
+	logger.FromContext(ctx).Info("user votes removed", zap.Int64("user_id", userID), zap.Int("count", removed))
+
+	//2.处理帖子
+	if getDeletePostsPolicy() == DeletePostsSoftDelete {
+		posts, err := mysql.GetPostsByAuthor(ctx, userID)
+		if err != nil {
+			return err
+		}
+		//查询帖子的标签，删除时减少标签的使用次数并从标签的set中移除
+		ids := make([]int64, 0, len(posts))
+		for _, p := range posts {
+			ids = append(ids, p.ID)
+		}
+		tags, err := mysql.GetTagsByPostIDs(ctx, ids)
+		if err != nil {
+			return err
+		}
+		for _, p := range posts {
+			p.Tags = tags[p.ID]
+		}
+		msgs, err := mysql.SoftDeletePosts(ctx, posts)
+		if err != nil {
+			return err
+		}
+		applyOutbox(ctx, msgs...)
+		for _, post := range posts {
+			publishEvent(ctx, &models.Event{
+				Type:        models.EventPostDeleted,
+				CommunityID: post.CommunityID,
+				PostID:      post.ID,
+				AuthorID:    userID,
+			})
+		}
+	}
+
+	//3.吊销令牌
+	if _, err = redis.RevokeTokens(ctx, userID); err != nil {
+		return
+	}
+
+	//4.解除第三方账户绑定，删除通知、事件订阅、关注和收藏并清除个人信息
+	if err = mysql.DeleteIdentitiesByUser(ctx, userID); err != nil {
+		return
+	}
+	if err = mysql.DeleteNotificationsByUser(ctx, userID); err != nil {
+		return
+	}
+	if err = mysql.DeleteWebhooksByUser(ctx, userID); err != nil {
+		return
+	}
+	if err = mysql.DeleteFollowsByUser(ctx, userID); err != nil {
+		return
+	}
+	if err = mysql.DeleteSavedPostsByUser(ctx, userID); err != nil {
+		return
+	}
+	if err = redis.DeleteSavedPosts(ctx, userID); err != nil {
+		return
+	}
+	return mysql.AnonymizeUser(ctx, userID)
+}
+
+// getDeletePostsPolicy 返回注销账户时帖子的处理方式，未配置时保留帖子
+func getDeletePostsPolicy() string {
+	if conf := settings.Get().AccountConfig; conf != nil && conf.DeletePosts != "" {
+		return conf.DeletePosts
+	}
+	return DeletePostsKeep
+}
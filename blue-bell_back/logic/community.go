@@ -3,8 +3,13 @@ package logic
 import (
 	"blue-bell_back/dao/mysql"
 	"blue-bell_back/dao/redis"
+	"blue-bell_back/logger"
 	"blue-bell_back/models"
 	"blue-bell_back/pkg/snowflake"
+	"blue-bell_back/pkg/trace"
+	"context"
+	"strconv"
+	"time"
 
 	"go.uber.org/zap"
 )
@@ -12,60 +17,84 @@ import (
 //	社区相关
 //	GetCommunityList 处理获取社区列表
 
-func GetCommunityList() ([]*models.Community, error) {
-	return mysql.GetCommunityList()
+func GetCommunityList(ctx context.Context) ([]*models.Community, error) {
+	return mysql.GetCommunityList(ctx)
 }
 
 // GetCommunityDetail 处理获取社区详情
-func GetCommunityDetail(id int64) (detail *models.CommunityDetail, err error) {
-	return mysql.GetCommunityByID(id)
+func GetCommunityDetail(ctx context.Context, id int64) (detail *models.CommunityDetail, err error) {
+	return mysql.GetCommunityByID(ctx, id)
 }
 
 // CreateCommunityPost 创建帖子
-func CreateCommunityPost(p *models.CommunityPost) (err error) {
-	//1.生成id
-	var id int64
-	id = snowflake.GenID()
-	p.ID = id
-	//2.保存到数据库
-	err = mysql.CreateCommunityPost(p)
+func CreateCommunityPost(ctx context.Context, p *models.CommunityPost) (err error) {
+	ctx, span := trace.Start(ctx, "logic.CreateCommunityPost")
+	defer span.Finish(&err)
+	//1.规范化标签并生成id
+	if p.Tags, err = normalizeTags(p.Tags); err != nil {
+		return err
+	}
+	p.ID = snowflake.GenID()
+	p.CreateTime = time.Now().Truncate(time.Second)
+	//2.保存帖子和标签到数据库，同时写入发件箱消息
+	msg, err := mysql.CreateCommunityPost(ctx, p)
 	if err != nil {
 		return err
 	}
 
-	//保存到redis
-	err = redis.CreateCommunityPost(int64(id), p.CommunityID)
+	//3.同步到redis，失败时由后台任务重试
+	applyOutbox(ctx, msg)
+	postCreatedTotal.Inc()
+	publishEvent(ctx, &models.Event{
+		Type:        models.EventPostCreated,
+		CommunityID: p.CommunityID,
+		PostID:      p.ID,
+		AuthorID:    p.AuthorID,
+		Title:       p.Title,
+	})
 	return
 }
 
 // GetPostDetail 获取帖子详情
-func GetPostDetail(id uint64) (detail *models.ApiPostDetail, err error) {
-	post, err := mysql.GetPostDetailByID(id)
+func GetPostDetail(ctx context.Context, id uint64) (detail *models.ApiPostDetail, err error) {
+	ctx, span := trace.Start(ctx, "logic.GetPostDetail")
+	defer span.Finish(&err)
+	post, err := mysql.GetPostDetailByID(ctx, id)
 	if err != nil {
-		zap.L().Error("mysql.GetPostDetailByID(id) failed.",
+		logger.FromContext(ctx).Error("mysql.GetPostDetailByID(id) failed.",
 			zap.Uint64("authorID:", id),
 			zap.Error(err))
 		return
 	}
 	// 1.根据作者id查询作者用户名
-	user, err := mysql.GetAuthorNameById(uint64(post.AuthorID))
+	user, err := mysql.GetAuthorNameById(ctx, uint64(post.AuthorID))
 	if err != nil {
-		zap.L().Error("mysql.GetAuthorNameById(post.AuthorID) failed.",
+		logger.FromContext(ctx).Error("mysql.GetAuthorNameById(post.AuthorID) failed.",
 			zap.Int64("authorID:", post.AuthorID),
 			zap.Error(err))
 		return
 	}
 	// 2.根据社区id查询社区名称
-	communityDetail, err := mysql.GetCommunityByID(post.CommunityID)
+	communityDetail, err := mysql.GetCommunityByID(ctx, post.CommunityID)
 	if err != nil {
-		zap.L().Error("mysql.GetCommunityNameById(post.CommunityID) failed.",
+		logger.FromContext(ctx).Error("mysql.GetCommunityNameById(post.CommunityID) failed.",
 			zap.Int64("authorID:", post.CommunityID),
 			zap.Error(err))
 		return
 	}
-	// 3.组装帖子详细信息并返回
+	// 3.查询帖子的标签
+	tags, err := mysql.GetTagsByPostIDs(ctx, []int64{post.ID})
+	if err != nil {
+		logger.FromContext(ctx).Error("mysql.GetTagsByPostIDs failed.",
+			zap.Int64("postID:", post.ID),
+			zap.Error(err))
+		return
+	}
+	post.Tags = tags[post.ID]
+	// 4.组装帖子详细信息并返回，同时返回Markdown原文和渲染后的HTML
 	detail = &models.ApiPostDetail{
 		AuthorName:      user.UserName,
+		ContentHTML:     renderPost(ctx, post),
 		CommunityDetail: communityDetail,
 		CommunityPost:   post,
 	}
@@ -73,12 +102,14 @@ func GetPostDetail(id uint64) (detail *models.ApiPostDetail, err error) {
 }
 
 // GetPostList 获取帖子列表逻辑
-func GetPostList(page, size int64) (list []*models.ApiPostDetail, err error) {
+func GetPostList(ctx context.Context, page, size int64) (list []*models.ApiPostDetail, err error) {
+	ctx, span := trace.Start(ctx, "logic.GetPostList")
+	defer span.Finish(&err)
 	// 调用mysql.GetPostList获取帖子列表
-	posts, err := mysql.GetPostList(page, size)
+	posts, err := mysql.GetPostList(ctx, page, size)
 	if err != nil {
 		// 如果获取帖子列表失败，记录错误日志并返回
-		zap.L().Error("mysql.GetPostList failed.", zap.Error(err))
+		logger.FromContext(ctx).Error("mysql.GetPostList failed.", zap.Error(err))
 		return
 	}
 	// 初始化帖子详细信息列表
@@ -86,29 +117,30 @@ func GetPostList(page, size int64) (list []*models.ApiPostDetail, err error) {
 	// 循环posts获取用户名和社区名称
 	for _, post := range posts {
 		// 1.根据作者id查询作者用户名
-		author, err := mysql.GetAuthorNameById(uint64(post.AuthorID))
+		author, err := mysql.GetAuthorNameById(ctx, uint64(post.AuthorID))
 		if err != nil {
 			// 如果获取作者用户名失败，记录错误日志并继续处理下一个帖子
-			zap.L().Error("mysql.GetAuthorNameById(post.AuthorID) failed.",
+			logger.FromContext(ctx).Error("mysql.GetAuthorNameById(post.AuthorID) failed.",
 				zap.Int64("authorID:", post.AuthorID),
 				zap.Error(err))
 			continue
 		}
 		// 2.根据社区id查询社区名称
-		community, err := mysql.GetCommunityByID(post.CommunityID)
+		community, err := mysql.GetCommunityByID(ctx, post.CommunityID)
 		if err != nil {
 			// 如果获取社区名称失败，记录错误日志并继续处理下一个帖子
-			zap.L().Error("mysql.GetAuthorNameById(post.AuthorID) failed.",
+			logger.FromContext(ctx).Error("mysql.GetAuthorNameById(post.AuthorID) failed.",
 				zap.Int64("authorID:", post.AuthorID),
 				zap.Error(err))
 			continue
 		}
-		// 3.构建帖子详细信息对象
+		// 3.构建帖子详细信息对象，列表中只返回摘要
 		apiPostDetail := &models.ApiPostDetail{
 			AuthorName:      author.UserName,
 			CommunityDetail: community,
 			CommunityPost:   post,
 		}
+		setExcerpt(apiPostDetail)
 		// 4.将帖子详细信息添加到列表中并返回
 		list = append(list, apiPostDetail)
 	}
@@ -119,9 +151,11 @@ func GetPostList(page, size int64) (list []*models.ApiPostDetail, err error) {
 // 参数p 包含查询帖子列表所需的参数，如分页信息和筛选条件
 // 返回值 data包含查询到的帖子详细信息列表， err 用于返回可能发生的错误
 
-func GetPostOrderList(p *models.ParamOrderList) (data []*models.ApiPostDetail, err error) {
+func GetPostOrderList(ctx context.Context, p *models.ParamOrderList) (data []*models.ApiPostDetail, err error) {
+	ctx, span := trace.Start(ctx, "logic.GetPostOrderList")
+	defer span.Finish(&err)
 	//1.去redis查询id列表
-	ids, err := redis.GetPostListByID(p)
+	ids, err := redis.GetPostListByID(ctx, p)
 
 	if err != nil {
 		return
@@ -129,94 +163,95 @@ func GetPostOrderList(p *models.ParamOrderList) (data []*models.ApiPostDetail, e
 
 	//处理redis.ids查询为空的
 	if len(ids) == 0 {
-		zap.L().Warn("redis.GetPostListByID(p) return 0 row")
+		logger.FromContext(ctx).Warn("redis.GetPostListByID(p) return 0 row")
 		return
 	}
 
-	zap.L().Debug("redis ids", zap.Any("ids", ids))
-
-	//2.根据id列表去mysql数据库查帖子详情
-	posts, err := mysql.GetPostOrderList(ids)
-	zap.L().Info("mysql posts", zap.Any("posts", posts))
-
-	//查询帖子的赞成票数
-	votes, err := redis.GetPostVoteData(ids)
-
-	//循环posts获取用户名和社区名称
-	for index, post := range posts {
-		//1.根据作者id查询作者用户名
-		author, err := mysql.GetAuthorNameById(uint64(post.AuthorID))
-
-		if err != nil {
-			zap.L().Error("mysql.GetAuthorNameById(post.AuthorID) failed.",
-				zap.Int64("authorID:", post.AuthorID),
-				zap.Error(err))
-			continue
-		}
-
-		//根据社区id查询社区名称
-		community, err := mysql.GetCommunityByID(post.CommunityID)
-		if err != nil {
-			zap.L().Error("mysql.GetCommunityByID(post.CommunityID) failed.",
-				zap.Int64("authorID:", post.CommunityID),
-				zap.Error(err))
-			continue
-		}
+	logger.FromContext(ctx).Debug("redis ids", zap.Any("ids", ids))
 
-		apiPostDetail := &models.ApiPostDetail{
-			AuthorName:      author.UserName,
-			VoteNum:         votes[index],
-			CommunityDetail: community,
-			CommunityPost:   post,
-		}
-		data = append(data, apiPostDetail)
-	}
-	return
+	//2.根据id列表查询帖子详情
+	return getPostDetailsByIDs(ctx, ids)
 }
 
 // GetCommunityPostList 根据社区id返回帖子
-func GetCommunityPostList(p *models.ParamCommunityPostList) (data []*models.ApiPostDetail, err error) {
+func GetCommunityPostList(ctx context.Context, p *models.ParamCommunityPostList) (data []*models.ApiPostDetail, err error) {
+	ctx, span := trace.Start(ctx, "logic.GetCommunityPostList")
+	defer span.Finish(&err)
 	//1.去redis查询id列表
-	ids, err := redis.GetCommunityPostListByID(p)
+	ids, err := redis.GetCommunityPostListByID(ctx, p)
 	if err != nil {
 		return
 	}
 
 	//
-	zap.L().Debug("redis ids", zap.Any("ids", ids))
+	logger.FromContext(ctx).Debug("redis ids", zap.Any("ids", ids))
+
+	//处理redis.ids查询为空的
+	if len(ids) == 0 {
+		return
+	}
+
+	//2.根据列表查询帖子详情
+	return getPostDetailsByIDs(ctx, ids)
+}
 
-	//2.根据列表去mysql数据库查询帖子详情
-	posts, err := mysql.GetPostOrderList(ids)
-	zap.L().Info("mysql posts", zap.Any("posts", posts))
+// getPostDetailsByIDs 按照ids的顺序查询帖子详情、标签、作者名称、社区信息和赞成票数
+// 用于帖子列表，内容只返回摘要
+// 已删除的帖子不会出现在结果中，作者或社区查询失败的帖子会被跳过
+func getPostDetailsByIDs(ctx context.Context, ids []string) (data []*models.ApiPostDetail, err error) {
+	//1.去mysql数据库查询帖子详情
+	posts, err := mysql.GetPostOrderList(ctx, ids)
+	if err != nil {
+		logger.FromContext(ctx).Error("mysql.GetPostOrderList(ids) failed.", zap.Error(err))
+		return nil, err
+	}
+	logger.FromContext(ctx).Debug("mysql posts", zap.Any("posts", posts))
 
-	//查询帖子的赞成票数
-	votes, err := redis.GetPostVoteData(ids)
+	//2.查询帖子的赞成票数，按照查到的帖子重新组织id，保证和posts一一对应
+	postIDs := make([]string, 0, len(posts))
+	ids64 := make([]int64, 0, len(posts))
+	for _, post := range posts {
+		postIDs = append(postIDs, strconv.FormatInt(post.ID, 10))
+		ids64 = append(ids64, post.ID)
+	}
+	votes, err := redis.GetPostVoteData(ctx, postIDs)
+	if err != nil {
+		logger.FromContext(ctx).Error("redis.GetPostVoteData(ids) failed.", zap.Error(err))
+		return nil, err
+	}
+	tags, err := mysql.GetTagsByPostIDs(ctx, ids64)
+	if err != nil {
+		logger.FromContext(ctx).Error("mysql.GetTagsByPostIDs(ids) failed.", zap.Error(err))
+		return nil, err
+	}
 
-	//循环posts获取用户名和社区名称
+	//3.循环posts获取用户名和社区名称
+	data = make([]*models.ApiPostDetail, 0, len(posts))
 	for index, post := range posts {
-		//1.根据作者id查询作者用户名
-		author, err := mysql.GetAuthorNameById(uint64(post.AuthorID))
+		post.Tags = tags[post.ID]
+		author, err := mysql.GetAuthorNameById(ctx, uint64(post.AuthorID))
 		if err != nil {
-			zap.L().Error("mysql.GetAuthorNameById(post.AuthorID) failed.",
+			logger.FromContext(ctx).Error("mysql.GetAuthorNameById(post.AuthorID) failed.",
 				zap.Int64("authorID:", post.AuthorID),
 				zap.Error(err))
 			continue
 		}
-		// 1.根据社区id查询社区名称
-		community, err := mysql.GetCommunityByID(post.CommunityID)
+		community, err := mysql.GetCommunityByID(ctx, post.CommunityID)
 		if err != nil {
-			zap.L().Error("mysql.GetAuthorNameById(post.AuthorID) failed.",
-				zap.Int64("authorID:", post.AuthorID),
+			logger.FromContext(ctx).Error("mysql.GetCommunityByID(post.CommunityID) failed.",
+				zap.Int64("communityID:", post.CommunityID),
 				zap.Error(err))
+			continue
 		}
 
-		apiPostDetail := &models.ApiPostDetail{
+		detail := &models.ApiPostDetail{
 			AuthorName:      author.UserName,
 			VoteNum:         votes[index],
 			CommunityDetail: community,
 			CommunityPost:   post,
 		}
-		data = append(data, apiPostDetail)
+		setExcerpt(detail)
+		data = append(data, detail)
 	}
-	return
+	return data, nil
 }
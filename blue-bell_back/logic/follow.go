@@ -0,0 +1,76 @@
+package logic
+
+import (
+	"blue-bell_back/dao/mysql"
+	"blue-bell_back/dao/redis"
+	"blue-bell_back/models"
+	"blue-bell_back/pkg/trace"
+	"context"
+	"errors"
+)
+
+// 关注用户
+
+// ErrFollowSelf 不能关注自己
+var ErrFollowSelf = errors.New("不能关注自己")
+
+// Follow 关注用户，重复关注时同样返回成功
+func Follow(ctx context.Context, userID, followeeID int64) (err error) {
+	ctx, span := trace.Start(ctx, "logic.Follow")
+	defer span.Finish(&err)
+	if userID == followeeID {
+		return ErrFollowSelf
+	}
+	if _, err = mysql.GetUserByID(ctx, followeeID); err != nil {
+		return
+	}
+	if err = mysql.Follow(ctx, userID, followeeID); err != nil {
+		return
+	}
+	return redis.ClearFollowingFeed(ctx, userID)
+}
+
+// Unfollow 取消关注，没有关注过时同样返回成功
+func Unfollow(ctx context.Context, userID, followeeID int64) (err error) {
+	ctx, span := trace.Start(ctx, "logic.Unfollow")
+	defer span.Finish(&err)
+	if err = mysql.Unfollow(ctx, userID, followeeID); err != nil {
+		return
+	}
+	return redis.ClearFollowingFeed(ctx, userID)
+}
+
+// GetUserProfile 查询用户的公开资料，viewerID为当前登录的用户
+func GetUserProfile(ctx context.Context, viewerID, userID int64) (profile *models.UserProfile, err error) {
+	ctx, span := trace.Start(ctx, "logic.GetUserProfile")
+	defer span.Finish(&err)
+	user, err := mysql.GetUserByID(ctx, userID)
+	if err != nil {
+		return
+	}
+	profile = &models.UserProfile{UserID: user.UserID, UserName: user.UserName}
+	if profile.FollowerCount, profile.FollowingCount, err = mysql.CountFollows(ctx, userID); err != nil {
+		return nil, err
+	}
+	if viewerID != userID {
+		if profile.Followed, err = mysql.IsFollowing(ctx, viewerID, userID); err != nil {
+			return nil, err
+		}
+	}
+	return profile, nil
+}
+
+// GetFollowingFeed 按发帖时间倒序分页查询关注的人发布的帖子
+func GetFollowingFeed(ctx context.Context, userID, page, size int64) (data []*models.ApiPostDetail, err error) {
+	ctx, span := trace.Start(ctx, "logic.GetFollowingFeed")
+	defer span.Finish(&err)
+	authorIDs, err := mysql.GetFolloweeIDs(ctx, userID)
+	if err != nil || len(authorIDs) == 0 {
+		return
+	}
+	ids, err := redis.GetFollowingFeedIDs(ctx, userID, authorIDs, page, size)
+	if err != nil || len(ids) == 0 {
+		return
+	}
+	return getPostDetailsByIDs(ctx, ids)
+}
@@ -0,0 +1,93 @@
+package logic
+
+import (
+	"blue-bell_back/dao/mysql"
+	"blue-bell_back/dao/redis"
+	"blue-bell_back/logger"
+	"blue-bell_back/models"
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// checkTimeout 每个依赖检查的超时时间
+const checkTimeout = time.Second
+
+// shuttingDown 服务是否已进入关闭阶段
+var shuttingDown atomic.Bool
+
+// StartShutdown 标记服务进入关闭阶段，之后就绪检查返回失败，负载均衡不再转发新的请求
+// 同时关闭所有实时事件的连接，否则长连接会阻塞服务关闭
+func StartShutdown() {
+	shuttingDown.Store(true)
+	streams.closeAll()
+}
+
+// dependencies 就绪检查需要检查的依赖
+var dependencies = map[string]func(ctx context.Context) error{
+	"mysql": mysql.Ping,
+	"redis": redis.Ping,
+}
+
+// CheckReadiness 并发检查所有依赖，返回每个依赖的状态和耗时
+func CheckReadiness(ctx context.Context) *models.Readiness {
+	res := &models.Readiness{Status: models.StatusUp}
+	if shuttingDown.Load() {
+		res.Status = models.StatusDown
+		res.ShuttingDown = true
+		return res
+	}
+
+	res.Checks = make(map[string]*models.DependencyStatus, len(dependencies))
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for name, ping := range dependencies {
+		wg.Add(1)
+		go func(name string, ping func(ctx context.Context) error) {
+			defer wg.Done()
+			st := checkDependency(ctx, name, ping)
+			mu.Lock()
+			res.Checks[name] = st
+			if st.Status != models.StatusUp {
+				res.Status = models.StatusDown
+			}
+			mu.Unlock()
+		}(name, ping)
+	}
+	wg.Wait()
+	return res
+}
+
+// checkDependency 在超时时间内执行一次检查，失败原因只记录日志
+// Redis客户端不支持通过context取消命令，所以放在goroutine中执行并等待超时
+func checkDependency(ctx context.Context, name string, ping func(ctx context.Context) error) *models.DependencyStatus {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- ping(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	st := &models.DependencyStatus{
+		Status:    models.StatusUp,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		st.Status = models.StatusDown
+		logger.FromContext(ctx).Warn("readiness check failed", zap.String("dependency", name), zap.Error(err))
+	}
+	return st
+}
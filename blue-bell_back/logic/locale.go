@@ -0,0 +1,42 @@
+package logic
+
+import (
+	"blue-bell_back/dao/mysql"
+	"blue-bell_back/dao/redis"
+	"blue-bell_back/logger"
+	"blue-bell_back/pkg/trace"
+	"context"
+
+	"go.uber.org/zap"
+)
+
+// GetUserLocale 返回用户设置的界面语言，没有设置或查询失败时返回空字符串
+// 每个响应都会调用，优先读取Redis缓存
+func GetUserLocale(ctx context.Context, userID int64) string {
+	locale, found, err := redis.GetUserLocale(ctx, userID)
+	if err == nil && found {
+		return locale
+	}
+	if err != nil {
+		logger.FromContext(ctx).Warn("redis.GetUserLocale failed", zap.Error(err))
+	}
+	locale, err = mysql.GetUserLocale(ctx, userID)
+	if err != nil {
+		logger.FromContext(ctx).Warn("mysql.GetUserLocale failed", zap.Int64("user_id", userID), zap.Error(err))
+		return ""
+	}
+	if err := redis.SetUserLocale(ctx, userID, locale); err != nil {
+		logger.FromContext(ctx).Warn("redis.SetUserLocale failed", zap.Error(err))
+	}
+	return locale
+}
+
+// SetUserLocale 修改用户的界面语言，为空时使用浏览器的语言设置
+func SetUserLocale(ctx context.Context, userID int64, locale string) (err error) {
+	ctx, span := trace.Start(ctx, "logic.SetUserLocale")
+	defer span.Finish(&err)
+	if err = mysql.SetUserLocale(ctx, userID, locale); err != nil {
+		return
+	}
+	return redis.SetUserLocale(ctx, userID, locale)
+}
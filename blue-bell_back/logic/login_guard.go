@@ -0,0 +1,174 @@
+package logic
+
+import (
+	"blue-bell_back/dao/mysql"
+	"blue-bell_back/dao/redis"
+	"blue-bell_back/logger"
+	"blue-bell_back/models"
+	"blue-bell_back/settings"
+	"context"
+	"errors"
+	"net/netip"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// maxUserAgentLen 和login_history表中user_agent字段的长度一致
+const maxUserAgentLen = 512
+
+// ErrAccountLocked 连续登录失败次数过多，账户被临时锁定
+var ErrAccountLocked = errors.New("登录失败次数过多，账户已被临时锁定")
+
+// AccountLockedError 账户锁定错误，带有剩余的锁定时间
+type AccountLockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *AccountLockedError) Error() string {
+	return ErrAccountLocked.Error()
+}
+
+func (e *AccountLockedError) Is(target error) bool {
+	return target == ErrAccountLocked
+}
+
+// defaultLoginGuard 未配置login_guard时使用的默认值
+var defaultLoginGuard = settings.LoginGuardConfig{
+	MaxUserFailures: 5,
+	MaxIPFailures:   20,
+	Window:          900,
+	LockDuration:    900,
+	DelayStep:       500,
+	MaxDelay:        3000,
+}
+
+func getLoginGuardConfig() *settings.LoginGuardConfig {
+	if c := settings.Get(); c != nil && c.LoginGuardConfig != nil {
+		return c.LoginGuardConfig
+	}
+	return &defaultLoginGuard
+}
+
+// ipSubject 返回按IP计数和锁定时使用的标识
+// ip来自gin的ClientIP，只有请求来自trusted_proxies中的代理时才取X-Forwarded-For，客户端无法伪造
+// IPv6客户端通常能使用整个/64网段，按网段计数，防止通过更换地址绕过限制
+func ipSubject(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ip
+	}
+	addr = addr.Unmap()
+	if addr.Is4() {
+		return addr.String()
+	}
+	return netip.PrefixFrom(addr, 64).Masked().String()
+}
+
+// checkLoginLocked 检查用户名和IP是否处于锁定状态
+// Redis不可用时不阻止登录
+func checkLoginLocked(ctx context.Context, username, ip string) error {
+	var retry time.Duration
+	for subject, id := range map[string]string{
+		redis.LoginSubjectUser: username,
+		redis.LoginSubjectIP:   ipSubject(ip),
+	} {
+		ttl, err := redis.GetLoginLockTTL(ctx, subject, id)
+		if err != nil {
+			logger.FromContext(ctx).Warn("redis.GetLoginLockTTL failed", zap.Error(err))
+			continue
+		}
+		if ttl > retry {
+			retry = ttl
+		}
+	}
+	if retry > 0 {
+		return &AccountLockedError{RetryAfter: retry}
+	}
+	return nil
+}
+
+// onLoginFailed 记录登录失败，超过阈值时锁定，并按失败次数延迟响应
+// 返回值: 本次失败导致锁定时返回 AccountLockedError
+func onLoginFailed(ctx context.Context, username, ip string) error {
+	conf := getLoginGuardConfig()
+	window := time.Duration(conf.Window) * time.Second
+	lock := time.Duration(conf.LockDuration) * time.Second
+	lg := logger.FromContext(ctx)
+	ip = ipSubject(ip)
+
+	userFails, err := redis.IncrLoginFailure(ctx, redis.LoginSubjectUser, username, window)
+	if err != nil {
+		lg.Warn("redis.IncrLoginFailure failed", zap.Error(err))
+	}
+	ipFails, err := redis.IncrLoginFailure(ctx, redis.LoginSubjectIP, ip, window)
+	if err != nil {
+		lg.Warn("redis.IncrLoginFailure failed", zap.Error(err))
+	}
+
+	var locked bool
+	if conf.MaxUserFailures > 0 && userFails >= int64(conf.MaxUserFailures) {
+		locked = true
+		if err := redis.LockLogin(ctx, redis.LoginSubjectUser, username, lock); err != nil {
+			lg.Warn("redis.LockLogin failed", zap.Error(err))
+		}
+		_ = redis.ResetLoginFailure(ctx, redis.LoginSubjectUser, username)
+		lg.Warn("account locked for too many login failures", zap.String("username", username))
+	}
+	if conf.MaxIPFailures > 0 && ipFails >= int64(conf.MaxIPFailures) {
+		locked = true
+		if err := redis.LockLogin(ctx, redis.LoginSubjectIP, ip, lock); err != nil {
+			lg.Warn("redis.LockLogin failed", zap.Error(err))
+		}
+		_ = redis.ResetLoginFailure(ctx, redis.LoginSubjectIP, ip)
+		lg.Warn("ip locked for too many login failures", zap.String("ip", ip))
+	}
+	if locked {
+		return &AccountLockedError{RetryAfter: lock}
+	}
+
+	// 失败次数越多，响应越慢
+	delay := time.Duration(userFails*int64(conf.DelayStep)) * time.Millisecond
+	if max := time.Duration(conf.MaxDelay) * time.Millisecond; delay > max {
+		delay = max
+	}
+	if delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+		}
+	}
+	return nil
+}
+
+// onLoginSucceeded 登录成功后清除用户名的失败次数
+func onLoginSucceeded(ctx context.Context, username string) {
+	if err := redis.ResetLoginFailure(ctx, redis.LoginSubjectUser, username); err != nil {
+		logger.FromContext(ctx).Warn("redis.ResetLoginFailure failed", zap.Error(err))
+	}
+}
+
+// recordLoginHistory 保存登录记录，失败不影响登录结果
+func recordLoginHistory(ctx context.Context, userID int64, meta *models.LoginMeta, success bool) {
+	if userID == 0 {
+		return
+	}
+	ua := meta.UserAgent
+	if len(ua) > maxUserAgentLen {
+		ua = ua[:maxUserAgentLen]
+	}
+	h := &models.LoginHistory{
+		UserID:    userID,
+		IP:        meta.IP,
+		UserAgent: ua,
+		Success:   success,
+	}
+	if err := mysql.InsertLoginHistory(ctx, h); err != nil {
+		logger.FromContext(ctx).Error("mysql.InsertLoginHistory failed", zap.Error(err))
+	}
+}
+
+// GetLoginHistory 查询当前用户的登录记录
+func GetLoginHistory(ctx context.Context, userID, page, size int64) ([]*models.LoginHistory, error) {
+	return mysql.GetLoginHistory(ctx, userID, page, size)
+}
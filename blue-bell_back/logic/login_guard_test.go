@@ -0,0 +1,159 @@
+package logic
+
+import (
+	"blue-bell_back/dao/redis"
+	"blue-bell_back/settings"
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+var mr *miniredis.Miniredis
+
+func TestMain(m *testing.M) {
+	var err error
+	mr, err = miniredis.Run()
+	if err != nil {
+		panic(err)
+	}
+	port, _ := strconv.Atoi(mr.Port())
+	if err = redis.Init(&settings.RedisConfig{Host: mr.Host(), Port: port}); err != nil {
+		panic(err)
+	}
+	code := m.Run()
+	mr.Close()
+	os.Exit(code)
+}
+
+// setLoginGuard 测试期间使用指定的阈值，不延迟响应
+func setLoginGuard(t *testing.T, maxUser, maxIP int) {
+	t.Helper()
+	old := defaultLoginGuard
+	defaultLoginGuard = settings.LoginGuardConfig{
+		MaxUserFailures: maxUser,
+		MaxIPFailures:   maxIP,
+		Window:          900,
+		LockDuration:    600,
+	}
+	mr.FlushAll()
+	t.Cleanup(func() { defaultLoginGuard = old })
+}
+
+func TestLoginGuardUserThreshold(t *testing.T) {
+	setLoginGuard(t, 3, 0)
+	ctx := context.Background()
+
+	for i := 1; i < 3; i++ {
+		if err := onLoginFailed(ctx, "alice", "10.0.0.1"); err != nil {
+			t.Fatalf("failure %d: got %v, want nil", i, err)
+		}
+		if err := checkLoginLocked(ctx, "alice", "10.0.0.1"); err != nil {
+			t.Fatalf("locked after %d failures", i)
+		}
+	}
+
+	err := onLoginFailed(ctx, "alice", "10.0.0.1")
+	var lockErr *AccountLockedError
+	if !errors.As(err, &lockErr) || lockErr.RetryAfter != 600*time.Second {
+		t.Fatalf("third failure: got %v, want lock for 600s", err)
+	}
+	err = checkLoginLocked(ctx, "alice", "10.0.0.2")
+	if !errors.Is(err, ErrAccountLocked) {
+		t.Fatalf("locked user from another ip: got %v, want ErrAccountLocked", err)
+	}
+	if err := checkLoginLocked(ctx, "bob", "10.0.0.1"); err != nil {
+		t.Fatalf("other user locked by user threshold: %v", err)
+	}
+
+	mr.FastForward(600 * time.Second)
+	if err := checkLoginLocked(ctx, "alice", "10.0.0.1"); err != nil {
+		t.Fatalf("still locked after lock duration: %v", err)
+	}
+}
+
+func TestLoginGuardSuccessResetsUserFailures(t *testing.T) {
+	setLoginGuard(t, 3, 0)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		_ = onLoginFailed(ctx, "alice", "10.0.0.1")
+	}
+	onLoginSucceeded(ctx, "alice")
+	for i := 0; i < 2; i++ {
+		if err := onLoginFailed(ctx, "alice", "10.0.0.1"); err != nil {
+			t.Fatalf("failure %d after success: got %v, want nil", i+1, err)
+		}
+	}
+}
+
+func TestLoginGuardIPThreshold(t *testing.T) {
+	setLoginGuard(t, 0, 3)
+	ctx := context.Background()
+
+	// 每次使用不同的用户名，只有IP的计数会达到阈值
+	for i := 1; i < 3; i++ {
+		if err := onLoginFailed(ctx, "user"+strconv.Itoa(i), "10.0.0.1"); err != nil {
+			t.Fatalf("failure %d: got %v, want nil", i, err)
+		}
+	}
+	if err := onLoginFailed(ctx, "user3", "10.0.0.1"); !errors.Is(err, ErrAccountLocked) {
+		t.Fatalf("third failure: got %v, want ErrAccountLocked", err)
+	}
+	if err := checkLoginLocked(ctx, "someone", "10.0.0.1"); !errors.Is(err, ErrAccountLocked) {
+		t.Fatalf("locked ip: got %v, want ErrAccountLocked", err)
+	}
+	if err := checkLoginLocked(ctx, "someone", "10.0.0.2"); err != nil {
+		t.Fatalf("other ip locked: %v", err)
+	}
+}
+
+func TestLoginGuardIPv6Prefix(t *testing.T) {
+	setLoginGuard(t, 0, 2)
+	ctx := context.Background()
+
+	// 同一个/64网段的地址共用计数
+	_ = onLoginFailed(ctx, "a", "2001:db8::1")
+	if err := onLoginFailed(ctx, "b", "2001:db8::2"); !errors.Is(err, ErrAccountLocked) {
+		t.Fatalf("second failure in same /64: got %v, want ErrAccountLocked", err)
+	}
+	if err := checkLoginLocked(ctx, "c", "2001:db8::ffff"); !errors.Is(err, ErrAccountLocked) {
+		t.Fatalf("address in locked /64: got %v, want ErrAccountLocked", err)
+	}
+	if err := checkLoginLocked(ctx, "c", "2001:db8:0:1::1"); err != nil {
+		t.Fatalf("address in other /64 locked: %v", err)
+	}
+}
+
+func TestLoginGuardDisabled(t *testing.T) {
+	setLoginGuard(t, 0, 0)
+	ctx := context.Background()
+
+	for i := 0; i < 10; i++ {
+		if err := onLoginFailed(ctx, "alice", "10.0.0.1"); err != nil {
+			t.Fatalf("failure %d with guard disabled: %v", i+1, err)
+		}
+	}
+}
+
+func TestIPSubject(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want string
+	}{
+		{"10.0.0.1", "10.0.0.1"},
+		{"::ffff:10.0.0.1", "10.0.0.1"},
+		{"2001:db8::1", "2001:db8::/64"},
+		{"2001:db8:0:0:ffff:ffff:ffff:ffff", "2001:db8::/64"},
+		{"not-an-ip", "not-an-ip"},
+	}
+	for _, tt := range tests {
+		if got := ipSubject(tt.ip); got != tt.want {
+			t.Errorf("ipSubject(%q) = %q, want %q", tt.ip, got, tt.want)
+		}
+	}
+}
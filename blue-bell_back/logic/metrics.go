@@ -0,0 +1,43 @@
+package logic
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// 业务相关的监控指标
+
+var (
+	// postCreatedTotal 创建成功的帖子数量
+	postCreatedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "bluebell_posts_created_total",
+		Help: "Total number of posts created.",
+	})
+	// voteTotal 投票成功的次数，direction为投票方向（1赞成 0取消 -1反对）
+	voteTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "bluebell_votes_total",
+		Help: "Total number of votes cast.",
+	}, []string{"direction"})
+	// streamDroppedTotal 客户端处理不过来被丢弃的实时事件数量
+	streamDroppedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "bluebell_stream_dropped_events_total",
+		Help: "Total number of real-time events dropped because the client buffer was full.",
+	})
+	// webhookDeliveriesTotal 事件推送的请求次数，result为结果（success成功 retry等待重试 failed重试次数用完）
+	webhookDeliveriesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "bluebell_webhook_deliveries_total",
+		Help: "Total number of webhook delivery attempts.",
+	}, []string{"result"})
+	// outboxFailuresTotal 发件箱消息同步到Redis失败的次数，type为消息类型
+	outboxFailuresTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "bluebell_outbox_failures_total",
+		Help: "Total number of failed attempts to apply outbox messages.",
+	}, []string{"type"})
+)
+
+func init() {
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "bluebell_stream_clients",
+		Help: "Number of clients connected to the event stream on this instance.",
+	}, func() float64 { return float64(streams.count()) })
+}
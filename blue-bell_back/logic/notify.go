@@ -0,0 +1,118 @@
+package logic
+
+import (
+	"blue-bell_back/dao/mysql"
+	"blue-bell_back/dao/redis"
+	"blue-bell_back/logger"
+	"blue-bell_back/models"
+	"blue-bell_back/pkg/trace"
+	"context"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// 站内通知
+// 业务操作成功后调用notify，通知在后台写入，失败只记录日志，不影响业务
+// 同一个帖子的同类未读通知聚合为一条，如"12人赞了你的帖子"，同一个用户只计入一次
+
+// notifyTimeout 后台写入一条通知的超时时间
+const notifyTimeout = 5 * time.Second
+
+// notify 在后台给userID发送一条通知，自己触发的和用户关闭的类型不发送
+func notify(ctx context.Context, userID int64, typ string, postID, actorID int64) {
+	if userID == actorID {
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		ctx, cancel := context.WithTimeout(ctx, notifyTimeout)
+		defer cancel()
+		if err := sendNotification(ctx, userID, typ, postID, actorID); err != nil {
+			logger.FromContext(ctx).Error("send notification failed",
+				zap.Int64("user_id", userID),
+				zap.String("type", typ),
+				zap.Int64("post_id", postID),
+				zap.Error(err))
+		}
+	}()
+}
+
+func sendNotification(ctx context.Context, userID int64, typ string, postID, actorID int64) (err error) {
+	ctx, span := trace.Start(ctx, "logic.sendNotification")
+	defer span.Finish(&err)
+	prefs, err := GetNotifyPreferences(ctx, userID)
+	if err != nil || !prefs[typ] {
+		return
+	}
+	added, err := redis.AddNotifyActor(ctx, userID, typ, postID, actorID)
+	if err != nil || !added {
+		return
+	}
+	return mysql.UpsertNotification(ctx, userID, typ, postID, actorID)
+}
+
+// notifyPostVoted 帖子被赞时通知作者，反对票和取消投票不通知
+func notifyPostVoted(ctx context.Context, post *models.CommunityPost, userID string, direction int8) {
+	if direction != 1 {
+		return
+	}
+	actorID, err := strconv.ParseInt(userID, 10, 64)
+	if err != nil {
+		return
+	}
+	notify(ctx, post.AuthorID, models.NotifyTypeVote, post.ID, actorID)
+}
+
+// GetNotifications 分页查询用户的通知
+func GetNotifications(ctx context.Context, userID, page, size int64) ([]*models.Notification, error) {
+	return mysql.GetNotifications(ctx, userID, page, size)
+}
+
+// CountUnreadNotifications 查询用户的未读通知数量
+func CountUnreadNotifications(ctx context.Context, userID int64) (int64, error) {
+	return mysql.CountUnreadNotifications(ctx, userID)
+}
+
+// MarkNotificationsRead 把通知标记为已读，ids为空时标记全部，返回标记的数量
+func MarkNotificationsRead(ctx context.Context, userID int64, ids []int64) (affected int64, err error) {
+	ctx, span := trace.Start(ctx, "logic.MarkNotificationsRead")
+	defer span.Finish(&err)
+	unread, err := mysql.GetUnreadNotifications(ctx, userID, ids)
+	if err != nil || len(unread) == 0 {
+		return 0, err
+	}
+	if affected, err = mysql.MarkNotificationsRead(ctx, userID, ids); err != nil {
+		return
+	}
+	// 清除失败时之前的用户不会再次计入新的通知，不影响标记结果
+	if err := redis.ClearNotifyActors(ctx, userID, unread); err != nil {
+		logger.FromContext(ctx).Warn("redis.ClearNotifyActors failed", zap.Int64("user_id", userID), zap.Error(err))
+	}
+	return affected, nil
+}
+
+// GetNotifyPreferences 返回用户所有通知类型的设置，没有修改过的类型默认开启
+func GetNotifyPreferences(ctx context.Context, userID int64) (models.NotifyPreferences, error) {
+	saved, err := mysql.GetNotifyPreferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	prefs := make(models.NotifyPreferences, len(models.NotifyTypes))
+	for _, typ := range models.NotifyTypes {
+		enabled, ok := saved[typ]
+		prefs[typ] = !ok || enabled
+	}
+	return prefs, nil
+}
+
+// SetNotifyPreferences 修改用户的通知设置，返回修改后的全部设置
+func SetNotifyPreferences(ctx context.Context, userID int64, prefs models.NotifyPreferences) (models.NotifyPreferences, error) {
+	if len(prefs) > 0 {
+		if err := mysql.SetNotifyPreferences(ctx, userID, prefs); err != nil {
+			return nil, err
+		}
+	}
+	return GetNotifyPreferences(ctx, userID)
+}
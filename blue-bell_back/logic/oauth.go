@@ -0,0 +1,297 @@
+package logic
+
+import (
+	"blue-bell_back/dao/mysql"
+	"blue-bell_back/dao/redis"
+	"blue-bell_back/logger"
+	"blue-bell_back/models"
+	"blue-bell_back/pkg/errs"
+	"blue-bell_back/pkg/jwt"
+	"blue-bell_back/pkg/oauth"
+	"blue-bell_back/pkg/snowflake"
+	"blue-bell_back/pkg/trace"
+	"blue-bell_back/settings"
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"regexp"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// 第三方登录
+
+// oauthStateTTL 从跳转到提供方到回调允许的最长时间
+const oauthStateTTL = 10 * time.Minute
+
+var (
+	// ErrOAuthProviderNotFound 没有配置对应的第三方登录提供方
+	ErrOAuthProviderNotFound = errs.NotFound("不支持的第三方登录方式")
+	// ErrInvalidOAuthState state无效、已过期或和提供方不匹配
+	ErrInvalidOAuthState = errs.Expired("登录请求已失效，请重新登录")
+	// ErrOAuthIdentityMismatch 再次验证身份时使用的第三方账户没有绑定到当前用户
+	ErrOAuthIdentityMismatch = errs.Forbidden("第三方账户未绑定到当前用户")
+)
+
+// cachedProvider 缓存根据配置创建的提供方，保留OIDC发现文档和公钥的缓存
+// 配置热加载后配置项的指针会变化，此时重新创建
+type cachedProvider struct {
+	conf     *settings.OAuthProvider
+	provider oauth.Provider
+}
+
+var (
+	providerMu sync.Mutex
+	providers  = make(map[string]*cachedProvider)
+)
+
+// getOAuthProvider 返回指定名称的提供方
+func getOAuthProvider(name string) (oauth.Provider, error) {
+	var conf *settings.OAuthProvider
+	if oc := settings.Get().OAuthConfig; oc != nil {
+		conf = oc.Providers[name]
+	}
+	if conf == nil {
+		return nil, ErrOAuthProviderNotFound
+	}
+
+	providerMu.Lock()
+	defer providerMu.Unlock()
+	if cp, ok := providers[name]; ok && cp.conf == conf {
+		return cp.provider, nil
+	}
+	p, err := oauth.New(&oauth.Config{
+		Type:         conf.Type,
+		ClientID:     conf.ClientID,
+		ClientSecret: conf.ClientSecret,
+		RedirectURL:  conf.RedirectURL,
+		Scopes:       append([]string(nil), conf.Scopes...),
+		Issuer:       conf.Issuer,
+	})
+	if err != nil {
+		return nil, err
+	}
+	providers[name] = &cachedProvider{conf: conf, provider: p}
+	return p, nil
+}
+
+// OAuthAuthorizeURL 生成跳转到提供方的授权地址
+// userID不为0时表示为已登录用户绑定第三方账户
+func OAuthAuthorizeURL(ctx context.Context, name string, userID int64) (authURL string, err error) {
+	ctx, span := trace.Start(ctx, "logic.OAuthAuthorizeURL", trace.String("oauth.provider", name))
+	defer span.Finish(&err)
+	return authorizeURL(ctx, name, userID, false)
+}
+
+// OAuthReauthURL 生成为当前用户再次验证身份的授权地址，要求用户在提供方重新登录
+func OAuthReauthURL(ctx context.Context, name string, userID int64) (authURL string, err error) {
+	ctx, span := trace.Start(ctx, "logic.OAuthReauthURL", trace.String("oauth.provider", name))
+	defer span.Finish(&err)
+	return authorizeURL(ctx, name, userID, true)
+}
+
+// authorizeURL 保存授权请求并返回提供方的授权地址
+func authorizeURL(ctx context.Context, name string, userID int64, reauth bool) (string, error) {
+	p, err := getOAuthProvider(name)
+	if err != nil {
+		return "", err
+	}
+	req, err := oauth.NewAuthRequest()
+	if err != nil {
+		return "", err
+	}
+	req.Reauth = reauth
+	if err = redis.SaveOAuthState(ctx, req.State, &redis.OAuthState{
+		Provider:     name,
+		CodeVerifier: req.CodeVerifier,
+		Nonce:        req.Nonce,
+		UserID:       userID,
+		Reauth:       reauth,
+	}, oauthStateTTL); err != nil {
+		return "", err
+	}
+	return p.AuthCodeURL(ctx, req)
+}
+
+// OAuthCallback 处理提供方的回调，返回登录的用户和令牌
+// 第三方账户已绑定时直接登录；未绑定时，如果两边的邮箱都已验证则绑定到同一邮箱的用户，否则创建新用户
+// 未验证邮箱的限制策略为block_login时，和密码登录一样拒绝邮箱未验证的账户
+// currentUserID 为当前登录的用户，绑定流程的state只能由发起绑定的用户使用，
+// 防止把别人诱导到自己的绑定链接上，从而把对方的第三方账户绑定到自己名下
+func OAuthCallback(ctx context.Context, name, state, code string, currentUserID int64, meta *models.LoginMeta) (user *models.User, err error) {
+	ctx, span := trace.Start(ctx, "logic.OAuthCallback", trace.String("oauth.provider", name))
+	defer span.Finish(&err)
+	s, err := redis.TakeOAuthState(ctx, state)
+	if err != nil {
+		if errors.Is(err, redis.ErrTokenInvalid) {
+			return nil, ErrInvalidOAuthState
+		}
+		return
+	}
+	if s.Provider != name || s.UserID != currentUserID || s.Reauth {
+		return nil, ErrInvalidOAuthState
+	}
+	p, err := getOAuthProvider(name)
+	if err != nil {
+		return
+	}
+	id, err := p.Exchange(ctx, code, &oauth.AuthRequest{State: state, CodeVerifier: s.CodeVerifier, Nonce: s.Nonce})
+	if err != nil {
+		return
+	}
+	identity := &models.UserIdentity{Provider: name, Subject: id.Subject}
+	if id.EmailVerified {
+		identity.Email = id.Email
+	}
+
+	var userID int64
+	if s.UserID != 0 {
+		// 绑定到当前登录的用户
+		identity.UserID = s.UserID
+		if err = mysql.InsertIdentity(ctx, identity); err != nil {
+			return
+		}
+		userID = s.UserID
+	} else {
+		userID, err = findOrCreateOAuthUser(ctx, identity, id)
+		if err != nil {
+			return
+		}
+	}
+
+	user, err = mysql.GetUserByID(ctx, userID)
+	if err != nil {
+		return
+	}
+	//和密码登录一样，按照策略禁止未验证邮箱的账户登录
+	if GetUnverifiedPolicy() == UnverifiedBlockLogin && !user.EmailVerified {
+		return nil, ErrEmailNotVerified
+	}
+	recordLoginHistory(ctx, user.UserID, meta, true)
+	user.Token, err = jwt.GenToken(user.UserID, user.UserName)
+	return
+}
+
+// OAuthReauth 处理再次验证身份的回调，第三方账户必须已绑定到当前用户
+// 验证通过后返回确认注销账户的一次性令牌，可以代替密码使用
+func OAuthReauth(ctx context.Context, name, state, code string, userID int64) (token string, err error) {
+	ctx, span := trace.Start(ctx, "logic.OAuthReauth", trace.String("oauth.provider", name))
+	defer span.Finish(&err)
+	s, err := redis.TakeOAuthState(ctx, state)
+	if err != nil {
+		if errors.Is(err, redis.ErrTokenInvalid) {
+			return "", ErrInvalidOAuthState
+		}
+		return
+	}
+	if s.Provider != name || s.UserID != userID || !s.Reauth {
+		return "", ErrInvalidOAuthState
+	}
+	p, err := getOAuthProvider(name)
+	if err != nil {
+		return
+	}
+	id, err := p.Exchange(ctx, code, &oauth.AuthRequest{State: state, CodeVerifier: s.CodeVerifier, Nonce: s.Nonce, Reauth: true})
+	if err != nil {
+		return
+	}
+	boundID, err := mysql.GetUserIDByIdentity(ctx, name, id.Subject)
+	if errors.Is(err, mysql.ErrorUserNotExist) || (err == nil && boundID != userID) {
+		return "", ErrOAuthIdentityMismatch
+	}
+	if err != nil {
+		return
+	}
+	return newAccountDeleteToken(ctx, userID)
+}
+
+// findOrCreateOAuthUser 查找第三方账户对应的用户，不存在时绑定或创建
+func findOrCreateOAuthUser(ctx context.Context, identity *models.UserIdentity, id *oauth.Identity) (userID int64, err error) {
+	userID, err = mysql.GetUserIDByIdentity(ctx, identity.Provider, identity.Subject)
+	if !errors.Is(err, mysql.ErrorUserNotExist) {
+		return
+	}
+
+	// 只有两边都验证过的邮箱才能证明是同一个人
+	if identity.Email != "" {
+		existing, err := mysql.GetUserByEmail(ctx, identity.Email)
+		if err != nil && !errors.Is(err, mysql.ErrorUserNotExist) {
+			return 0, err
+		}
+		if existing != nil && existing.EmailVerified {
+			identity.UserID = existing.UserID
+			if err := mysql.InsertIdentity(ctx, identity); err != nil {
+				return 0, err
+			}
+			return existing.UserID, nil
+		}
+	}
+
+	username, err := uniqueUsername(ctx, id.Username)
+	if err != nil {
+		return
+	}
+	user := &models.User{
+		UserID:   snowflake.GenID(),
+		UserName: username,
+	}
+	// 邮箱已被其他账户使用（未验证）时不保存，避免冲突
+	if identity.Email != "" {
+		if err := mysql.CheckEmailExist(ctx, identity.Email); err == nil {
+			user.Email = identity.Email
+			user.EmailVerified = true
+		}
+	}
+	err = mysql.InsertUserWithIdentity(ctx, user, identity)
+	if errors.Is(err, mysql.ErrorEmailExist) {
+		// 检查之后邮箱被其他账户注册，同样不保存邮箱
+		user.Email, user.EmailVerified = "", false
+		err = mysql.InsertUserWithIdentity(ctx, user, identity)
+	}
+	if err != nil {
+		if errors.Is(err, mysql.ErrorIdentityExist) {
+			// 同一个第三方账户并发登录，另一个请求已经创建了用户
+			return mysql.GetUserIDByIdentity(ctx, identity.Provider, identity.Subject)
+		}
+		return
+	}
+	logger.FromContext(ctx).Info("user created by oauth",
+		zap.String("provider", identity.Provider), zap.Int64("user_id", user.UserID))
+	return user.UserID, nil
+}
+
+// usernameInvalidChars 用户名中不允许的字符
+var usernameInvalidChars = regexp.MustCompile(`[^\p{L}\p{N}_.-]+`)
+
+// uniqueUsername 根据提供方建议的用户名生成一个未被使用的用户名
+func uniqueUsername(ctx context.Context, preferred string) (string, error) {
+	base := usernameInvalidChars.ReplaceAllString(preferred, "")
+	if r := []rune(base); len(r) > 32 {
+		base = string(r[:32])
+	}
+	if base == "" {
+		base = "user"
+	}
+	name := base
+	for i := 0; i < 5; i++ {
+		err := mysql.CheckUserExist(ctx, name)
+		if err == nil {
+			return name, nil
+		}
+		if !errors.Is(err, mysql.ErrorUserExist) {
+			return "", err
+		}
+		name = fmt.Sprintf("%s_%04d", base, rand.Intn(10000))
+	}
+	return fmt.Sprintf("%s_%d", base, snowflake.GenID()), nil
+}
+
+// GetUserIdentities 查询用户绑定的第三方账户
+func GetUserIdentities(ctx context.Context, userID int64) (list []*models.UserIdentity, err error) {
+	ctx, span := trace.Start(ctx, "logic.GetUserIdentities")
+	defer span.Finish(&err)
+	return mysql.GetIdentitiesByUser(ctx, userID)
+}
@@ -0,0 +1,153 @@
+package logic
+
+import (
+	"blue-bell_back/dao/mysql"
+	"blue-bell_back/dao/redis"
+	"blue-bell_back/logger"
+	"blue-bell_back/models"
+	"blue-bell_back/pkg/trace"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// 事务发件箱的同步
+// 帖子的创建和删除在MySQL事务中同时写入发件箱消息，提交后立即同步到Redis
+// 同步失败或实例在同步前退出时，由后台任务按退避间隔重试直到成功，所以每种消息的处理函数都必须可以重复执行
+// 以后新增的数据变更（如编辑帖子）只需要定义消息类型并在outboxHandlers中注册处理函数
+
+const (
+	outboxPollInterval = time.Second      // 没有到期消息时检查的间隔
+	outboxBatchSize    = 100              // 每次查询的消息数
+	outboxLease        = 30 * time.Second // 后台任务取出消息后，其他实例在租期内不会重复处理
+	outboxRetryBase    = time.Second      // 第一次重试的间隔，之后每次翻倍
+	outboxRetryMax     = 5 * time.Minute  // 重试间隔的上限
+	outboxMaxErrorLen  = 512              // 保存的失败原因的最大长度
+)
+
+// outboxHandlers 各类消息的处理函数
+var outboxHandlers = map[string]func(ctx context.Context, payload []byte) error{
+	models.OutboxPostCreated: syncPostCreated,
+	models.OutboxPostDeleted: syncPostDeleted,
+}
+
+// syncPostCreated 把帖子加入Redis中的排行、社区和标签
+// 重试的消息可能在帖子删除之后执行，此时删除消息已经把帖子从Redis移除，不能再加回去
+func syncPostCreated(ctx context.Context, payload []byte) error {
+	p := new(models.OutboxPost)
+	if err := json.Unmarshal(payload, p); err != nil {
+		return err
+	}
+	postID := strconv.FormatInt(p.PostID, 10)
+	exist, err := mysql.CheckPostExist(ctx, postID)
+	if err != nil || !exist {
+		return err
+	}
+	if err := redis.CreateCommunityPost(ctx, p.PostID, p.CommunityID, p.AuthorID, p.CreateTime, p.Tags); err != nil {
+		return err
+	}
+	// 检查之后、写入Redis之前帖子被删除时，删除消息可能已经执行完，再检查一次并撤销写入
+	if exist, err = mysql.CheckPostExist(ctx, postID); err != nil || exist {
+		return err
+	}
+	return removeOutboxPost(ctx, p)
+}
+
+func syncPostDeleted(ctx context.Context, payload []byte) error {
+	p := new(models.OutboxPost)
+	if err := json.Unmarshal(payload, p); err != nil {
+		return err
+	}
+	return removeOutboxPost(ctx, p)
+}
+
+func removeOutboxPost(ctx context.Context, p *models.OutboxPost) error {
+	return redis.RemovePosts(ctx, []*models.CommunityPost{{ID: p.PostID, CommunityID: p.CommunityID, AuthorID: p.AuthorID, Tags: p.Tags}})
+}
+
+// applyOutbox 在事务提交后立即同步消息，失败的消息留给后台任务重试，不影响业务
+// 事务已经提交，客户端断开连接时也继续同步
+func applyOutbox(ctx context.Context, msgs ...*models.OutboxMessage) {
+	ctx = context.WithoutCancel(ctx)
+	for _, m := range msgs {
+		if err := processOutbox(ctx, m); err != nil {
+			logger.FromContext(ctx).Warn("apply outbox message failed, will retry in background",
+				zap.Int64("outbox_id", m.ID),
+				zap.String("type", m.Type),
+				zap.Error(err))
+		}
+	}
+}
+
+// processOutbox 执行消息并删除，失败时记录原因并推迟到下次重试
+func processOutbox(ctx context.Context, m *models.OutboxMessage) (err error) {
+	ctx, span := trace.Start(ctx, "logic.processOutbox",
+		trace.Int64("outbox.id", m.ID), trace.String("outbox.type", m.Type))
+	defer span.Finish(&err)
+	if h, ok := outboxHandlers[m.Type]; ok {
+		err = h(ctx, []byte(m.Payload))
+	} else {
+		err = fmt.Errorf("unknown outbox message type %q", m.Type)
+	}
+	if err != nil {
+		outboxFailuresTotal.WithLabelValues(m.Type).Inc()
+		delay := outboxBackoff(m.Attempts + 1)
+		if rerr := mysql.RetryOutboxMessage(ctx, m.ID, delay, truncate(err.Error(), outboxMaxErrorLen)); rerr != nil {
+			logger.FromContext(ctx).Error("mysql.RetryOutboxMessage failed", zap.Int64("outbox_id", m.ID), zap.Error(rerr))
+		}
+		return err
+	}
+	return mysql.DeleteOutboxMessage(ctx, m.ID)
+}
+
+// StartOutboxRelay 在后台处理到期的发件箱消息，ctx取消后退出
+// 多个实例可以同时运行，每条消息取出后在租期内只会被一个实例处理
+func StartOutboxRelay(ctx context.Context) {
+	go func() {
+		for ctx.Err() == nil {
+			msgs, err := mysql.GetDueOutboxMessages(ctx, outboxBatchSize)
+			if err != nil && ctx.Err() == nil {
+				logger.FromContext(ctx).Error("mysql.GetDueOutboxMessages failed", zap.Error(err))
+			}
+			for _, m := range msgs {
+				relayOutbox(ctx, m)
+			}
+			// 取满一批时说明可能还有到期的消息，立即继续
+			if len(msgs) < outboxBatchSize {
+				sleepContext(ctx, outboxPollInterval)
+			}
+		}
+	}()
+}
+
+func relayOutbox(ctx context.Context, m *models.OutboxMessage) {
+	claimed, err := mysql.ClaimOutboxMessage(ctx, m.ID, outboxLease)
+	if err != nil {
+		logger.FromContext(ctx).Error("mysql.ClaimOutboxMessage failed", zap.Int64("outbox_id", m.ID), zap.Error(err))
+		return
+	}
+	if !claimed {
+		// 已经被其他实例取出
+		return
+	}
+	if err := processOutbox(ctx, m); err != nil {
+		logger.FromContext(ctx).Error("process outbox message failed",
+			zap.Int64("outbox_id", m.ID),
+			zap.String("type", m.Type),
+			zap.Int("attempts", m.Attempts+1),
+			zap.Error(err))
+	}
+}
+
+// outboxBackoff 第attempts次失败后等待的时间
+func outboxBackoff(attempts int) time.Duration {
+	d := outboxRetryBase
+	for i := 1; i < attempts && d < outboxRetryMax; i++ {
+		d *= 2
+	}
+	return min(d, outboxRetryMax)
+}
@@ -0,0 +1,48 @@
+package logic
+
+import (
+	"blue-bell_back/dao/redis"
+	"blue-bell_back/logger"
+	"blue-bell_back/models"
+	"blue-bell_back/pkg/markdown"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+
+	"go.uber.org/zap"
+)
+
+// 帖子内容的渲染
+// 内容按Markdown渲染为HTML，结果按帖子的版本缓存在Redis中；列表只返回纯文本摘要
+
+// excerptLen 列表中摘要的最大字符数
+const excerptLen = 140
+
+// postRevision 帖子内容的版本，由渲染规则的版本和内容的哈希组成，内容或规则变化时版本随之变化
+func postRevision(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return markdown.Version + "-" + hex.EncodeToString(sum[:8])
+}
+
+// renderPost 返回帖子内容渲染后的HTML，缓存读写失败时直接渲染，不影响查询
+func renderPost(ctx context.Context, post *models.CommunityPost) string {
+	rev := postRevision(post.Content)
+	html, ok, err := redis.GetPostHTML(ctx, post.ID, rev)
+	if err != nil {
+		logger.FromContext(ctx).Warn("redis.GetPostHTML failed", zap.Int64("post_id", post.ID), zap.Error(err))
+	}
+	if ok {
+		return html
+	}
+	html = markdown.Render(post.Content)
+	if err = redis.SetPostHTML(ctx, post.ID, rev, html); err != nil {
+		logger.FromContext(ctx).Warn("redis.SetPostHTML failed", zap.Int64("post_id", post.ID), zap.Error(err))
+	}
+	return html
+}
+
+// setExcerpt 生成列表中的摘要，去掉原文以减小响应
+func setExcerpt(detail *models.ApiPostDetail) {
+	detail.Excerpt = markdown.Excerpt(detail.Content, excerptLen)
+	detail.Content = ""
+}
@@ -0,0 +1,61 @@
+package logic
+
+import (
+	"blue-bell_back/dao/mysql"
+	"blue-bell_back/dao/redis"
+	"blue-bell_back/models"
+	"blue-bell_back/pkg/trace"
+	"context"
+	"time"
+)
+
+// 收藏帖子
+// MySQL中保存所有收藏，Redis中按收藏时间缓存每个用户的收藏列表用于分页
+
+// SavePost 收藏帖子，重复收藏时保持原来的收藏时间
+func SavePost(ctx context.Context, userID, postID int64) (err error) {
+	ctx, span := trace.Start(ctx, "logic.SavePost")
+	defer span.Finish(&err)
+	if _, err = mysql.GetPostDetailByID(ctx, uint64(postID)); err != nil {
+		return
+	}
+	now := time.Now()
+	if err = mysql.SavePost(ctx, userID, postID, now); err != nil {
+		return
+	}
+	return redis.AddSavedPost(ctx, userID, postID, now)
+}
+
+// UnsavePost 取消收藏，没有收藏过时同样返回成功
+func UnsavePost(ctx context.Context, userID, postID int64) (err error) {
+	ctx, span := trace.Start(ctx, "logic.UnsavePost")
+	defer span.Finish(&err)
+	if err = mysql.UnsavePost(ctx, userID, postID); err != nil {
+		return
+	}
+	return redis.RemoveSavedPost(ctx, userID, postID)
+}
+
+// GetSavedPosts 按收藏时间倒序分页查询收藏的帖子，已删除的帖子不返回
+func GetSavedPosts(ctx context.Context, userID, page, size int64) (data []*models.ApiPostDetail, err error) {
+	ctx, span := trace.Start(ctx, "logic.GetSavedPosts")
+	defer span.Finish(&err)
+	cached, err := redis.HasSavedPosts(ctx, userID)
+	if err != nil {
+		return
+	}
+	if !cached {
+		list, err := mysql.GetSavedPosts(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		if err = redis.CacheSavedPosts(ctx, userID, list); err != nil {
+			return nil, err
+		}
+	}
+	ids, err := redis.GetSavedPostIDs(ctx, userID, page, size)
+	if err != nil || len(ids) == 0 {
+		return
+	}
+	return getPostDetailsByIDs(ctx, ids)
+}
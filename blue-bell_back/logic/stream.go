@@ -0,0 +1,196 @@
+package logic
+
+import (
+	"blue-bell_back/dao/redis"
+	"blue-bell_back/logger"
+	"blue-bell_back/models"
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// 实时事件推送
+// 事件通过Redis发布订阅广播到所有实例，每个实例只订阅一次频道，再分发给本实例上连接的客户端
+
+const (
+	streamBufferSize     = 32               // 每个客户端缓冲的事件数，客户端处理不过来时丢弃新的事件
+	streamReceiveTimeout = time.Minute      // 超过该时间没有事件时检测Redis连接
+	streamRetryDelay     = time.Second      // 订阅断开后重新订阅的间隔
+	streamTicketTTL      = 30 * time.Second // 连接事件流的票据有效期
+)
+
+// ErrStreamClosed 服务正在关闭，不再接受新的订阅
+var ErrStreamClosed = errors.New("服务正在关闭")
+
+// EventStream 一个客户端的订阅
+type EventStream struct {
+	C           <-chan *models.Event // 推送给客户端的事件，服务关闭时被关闭
+	ch          chan *models.Event
+	communities map[int64]bool // 订阅的社区，为空时接收所有社区的事件
+}
+
+// wants 判断客户端是否订阅了事件所在的社区
+func (s *EventStream) wants(e *models.Event) bool {
+	return len(s.communities) == 0 || s.communities[e.CommunityID]
+}
+
+// Close 取消订阅
+func (s *EventStream) Close() {
+	streams.remove(s)
+}
+
+// eventHub 本实例上所有客户端的订阅
+type eventHub struct {
+	mu      sync.Mutex
+	streams map[*EventStream]struct{}
+	closed  bool
+}
+
+var streams = &eventHub{streams: make(map[*EventStream]struct{})}
+
+func (h *eventHub) add(s *EventStream) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return ErrStreamClosed
+	}
+	h.streams[s] = struct{}{}
+	return nil
+}
+
+func (h *eventHub) remove(s *EventStream) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.streams[s]; ok {
+		delete(h.streams, s)
+		close(s.ch)
+	}
+}
+
+func (h *eventHub) count() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.streams)
+}
+
+// dispatch 把事件分发给订阅了对应社区的客户端，不会阻塞
+func (h *eventHub) dispatch(e *models.Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for s := range h.streams {
+		if !s.wants(e) {
+			continue
+		}
+		select {
+		case s.ch <- e:
+		default:
+			streamDroppedTotal.Inc()
+		}
+	}
+}
+
+// closeAll 关闭所有订阅，之后不再接受新的订阅
+func (h *eventHub) closeAll() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for s := range h.streams {
+		delete(h.streams, s)
+		close(s.ch)
+	}
+}
+
+// SubscribeEventStream 订阅指定社区的实时事件，communityIDs为空时订阅所有社区
+func SubscribeEventStream(communityIDs []int64) (*EventStream, error) {
+	ch := make(chan *models.Event, streamBufferSize)
+	s := &EventStream{C: ch, ch: ch, communities: make(map[int64]bool, len(communityIDs))}
+	for _, id := range communityIDs {
+		s.communities[id] = true
+	}
+	if err := streams.add(s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// StartEventStream 在后台订阅Redis频道并分发事件，ctx取消后退出
+// Redis断开或热加载替换客户端后自动重新订阅
+func StartEventStream(ctx context.Context) {
+	go func() {
+		for ctx.Err() == nil {
+			sub, err := redis.SubscribeEvents()
+			if err != nil {
+				logger.FromContext(ctx).Error("redis.SubscribeEvents failed", zap.Error(err))
+				sleepContext(ctx, streamRetryDelay)
+				continue
+			}
+			receiveEvents(ctx, sub)
+			_ = sub.Close()
+		}
+	}()
+}
+
+// receiveEvents 接收并分发事件，直到订阅出错或ctx取消
+func receiveEvents(ctx context.Context, sub *redis.EventSubscription) {
+	stop := context.AfterFunc(ctx, func() { _ = sub.Close() })
+	defer stop()
+	for ctx.Err() == nil {
+		e, err := sub.Receive(streamReceiveTimeout)
+		if errors.Is(err, redis.ErrUnexpectedReply) {
+			logger.FromContext(ctx).Warn("invalid event message", zap.Error(err))
+			continue
+		}
+		if err != nil {
+			if ctx.Err() == nil {
+				logger.FromContext(ctx).Warn("event subscription broken, resubscribing", zap.Error(err))
+				sleepContext(ctx, streamRetryDelay)
+			}
+			return
+		}
+		if e != nil {
+			streams.dispatch(e)
+		}
+	}
+}
+
+func sleepContext(ctx context.Context, d time.Duration) {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+	case <-t.C:
+	}
+}
+
+// publishEvent 发布实时事件并推送给订阅了该事件的webhook，失败只记录日志，不影响业务
+func publishEvent(ctx context.Context, e *models.Event) {
+	e.Time = time.Now()
+	dispatchWebhooks(ctx, e)
+	if err := redis.PublishEvent(ctx, e); err != nil {
+		logger.FromContext(ctx).Warn("redis.PublishEvent failed",
+			zap.String("type", e.Type),
+			zap.Int64("post_id", e.PostID),
+			zap.Error(err))
+	}
+}
+
+// IssueStreamTicket 签发连接事件流使用的一次性票据
+// 浏览器的EventSource不能设置请求头，先携带JWT换取票据，再把票据放在查询参数中连接
+func IssueStreamTicket(ctx context.Context, userID int64) (ticket string, err error) {
+	ticket, err = newToken()
+	if err != nil {
+		return "", err
+	}
+	if err = redis.SaveToken(ctx, redis.TokenStreamTicket, ticket, userID, streamTicketTTL); err != nil {
+		return "", err
+	}
+	return ticket, nil
+}
+
+// TakeStreamTicket 校验票据并返回对应的用户ID，票据随即失效
+func TakeStreamTicket(ctx context.Context, ticket string) (int64, error) {
+	return redis.TakeToken(ctx, redis.TokenStreamTicket, ticket)
+}
@@ -0,0 +1,101 @@
+package logic
+
+import (
+	"blue-bell_back/dao/mysql"
+	"blue-bell_back/dao/redis"
+	"blue-bell_back/logger"
+	"blue-bell_back/models"
+	"blue-bell_back/pkg/trace"
+	"blue-bell_back/settings"
+	"context"
+	"errors"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+)
+
+// 帖子标签
+
+const (
+	maxTagLen       = 32 // 标签的最大长度（字符数）
+	defaultTagsSize = 20 // 热门标签默认返回的数量
+)
+
+// ErrInvalidTag 标签包含不允许的字符或不在允许使用的标签中
+var ErrInvalidTag = errors.New("无效的标签")
+
+// normalizeTag 去掉首尾空格并转为小写，只允许字母、数字、-和_
+// 配置了允许使用的标签时，不在列表中的标签也视为无效
+func normalizeTag(tag string) (string, error) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if tag == "" || utf8.RuneCountInString(tag) > maxTagLen {
+		return "", ErrInvalidTag
+	}
+	for _, r := range tag {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '_' {
+			return "", ErrInvalidTag
+		}
+	}
+	if conf := settings.Get().TagConfig; conf != nil && len(conf.Allowed) > 0 {
+		for _, allowed := range conf.Allowed {
+			if strings.ToLower(allowed) == tag {
+				return tag, nil
+			}
+		}
+		return "", ErrInvalidTag
+	}
+	return tag, nil
+}
+
+// normalizeTags 规范化帖子的标签并去掉重复的标签
+func normalizeTags(tags []string) ([]string, error) {
+	if len(tags) == 0 {
+		return nil, nil
+	}
+	seen := make(map[string]bool, len(tags))
+	list := make([]string, 0, len(tags))
+	for _, t := range tags {
+		tag, err := normalizeTag(t)
+		if err != nil {
+			return nil, err
+		}
+		if seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		list = append(list, tag)
+	}
+	return list, nil
+}
+
+// GetTagPostList 根据标签返回帖子，支持按时间和分数排序
+func GetTagPostList(ctx context.Context, p *models.ParamTagPostList) (data []*models.ApiPostDetail, err error) {
+	ctx, span := trace.Start(ctx, "logic.GetTagPostList")
+	defer span.Finish(&err)
+	if p.Tag, err = normalizeTag(p.Tag); err != nil {
+		return nil, err
+	}
+	//1.去redis查询id列表
+	ids, err := redis.GetTagPostListByID(ctx, p)
+	if err != nil {
+		return
+	}
+	logger.FromContext(ctx).Debug("redis ids", zap.Any("ids", ids))
+	if len(ids) == 0 {
+		return
+	}
+	//2.根据列表查询帖子详情
+	return getPostDetailsByIDs(ctx, ids)
+}
+
+// GetTags 按使用次数倒序返回标签
+func GetTags(ctx context.Context, p *models.ParamTagList) (list []*models.Tag, err error) {
+	ctx, span := trace.Start(ctx, "logic.GetTags")
+	defer span.Finish(&err)
+	if p.Size <= 0 {
+		p.Size = defaultTagsSize
+	}
+	return mysql.GetPopularTags(ctx, p.Size)
+}
@@ -2,9 +2,13 @@ package logic
 
 import (
 	"blue-bell_back/dao/mysql"
+	"blue-bell_back/logger"
 	"blue-bell_back/models"
 	"blue-bell_back/pkg/jwt"
 	"blue-bell_back/pkg/snowflake"
+	"blue-bell_back/pkg/trace"
+	"context"
+	"errors"
 
 	"go.uber.org/zap"
 )
@@ -12,17 +16,22 @@ import (
 // SignUp 用户注册函数
 // 参数 p 包含用户输入的用户名和密码
 // 返回值 error 用于返回注册过程中可能发生的错误
-func SignUp(p *models.ParamSignUp) (err error) {
+func SignUp(ctx context.Context, p *models.ParamSignUp) (err error) {
+	ctx, span := trace.Start(ctx, "logic.SignUp")
+	defer span.Finish(&err)
 	//处理注册逻辑
 	//1.判断用户是否存在 数据库中检查
-	if err := mysql.CheckUserExist(p.UserName); err != nil {
+	if err := mysql.CheckUserExist(ctx, p.UserName); err != nil {
+		return err
+	}
+	if err := mysql.CheckEmailExist(ctx, p.Email); err != nil {
 		return err
 	}
 
 	//2.生成UID
 	userID := snowflake.GenID()
 	if err != nil {
-		zap.L().Error("user snowflake.GenId failed.", zap.Error(err))
+		logger.FromContext(ctx).Error("user snowflake.GenId failed.", zap.Error(err))
 		return
 	}
 	//构造一个User实例
@@ -30,16 +39,32 @@ func SignUp(p *models.ParamSignUp) (err error) {
 		UserID:   userID,
 		UserName: p.UserName,
 		Password: p.RePassword,
+		Email:    p.Email,
 	}
 	//3.密码加密并保存进数据库
-	return mysql.InsertUser(user)
+	if err = mysql.InsertUser(ctx, user); err != nil {
+		return
+	}
+	//4.发送验证邮件，失败时用户可以稍后重新发送
+	if err := sendVerifyEmail(ctx, user.UserID, user.UserName, user.Email); err != nil {
+		logger.FromContext(ctx).Error("sendVerifyEmail failed", zap.Int64("user_id", user.UserID), zap.Error(err))
+	}
+	return
 
 }
 
 // Login 用户登录函数
-// 参数 p 包含用户输入的用户名和密码
+// 参数 p 包含用户输入的用户名和密码，meta 为客户端的IP和User-Agent
 // 返回值 user 是登录成功的用户信息，包括用户ID、用户名和令牌(Token)
-func Login(p *models.ParamLogin) (user *models.User, err error) {
+// 连续失败次数过多时返回 AccountLockedError
+func Login(ctx context.Context, p *models.ParamLogin, meta *models.LoginMeta) (user *models.User, err error) {
+	ctx, span := trace.Start(ctx, "logic.Login")
+	defer span.Finish(&err)
+	//检查用户名或IP是否已被锁定
+	if err := checkLoginLocked(ctx, p.UserName, meta.IP); err != nil {
+		return nil, err
+	}
+
 	//初始化用户信息
 	user = &models.User{
 		UserName: p.UserName,
@@ -49,9 +74,24 @@ func Login(p *models.ParamLogin) (user *models.User, err error) {
 	//return mysql.Login(user)
 	// 调用mysql.Login函数执行登录操作，如果登录失败，返回错误信息
 
-	if err := mysql.Login(user); err != nil {
+	if err := mysql.Login(ctx, user); err != nil {
+		if errors.Is(err, mysql.ErrorUserNotExist) || errors.Is(err, mysql.ErrorInvalidPassword) {
+			// 密码错误时mysql.Login已经查出了用户ID
+			recordLoginHistory(ctx, user.UserID, meta, false)
+			if lockErr := onLoginFailed(ctx, p.UserName, meta.IP); lockErr != nil {
+				return nil, lockErr
+			}
+		}
 		return nil, err
 	}
+	//按照策略禁止未验证邮箱的账户登录
+	if GetUnverifiedPolicy() == UnverifiedBlockLogin {
+		if err := CheckEmailVerified(ctx, user.UserID); err != nil {
+			return nil, err
+		}
+	}
+	onLoginSucceeded(ctx, p.UserName)
+	recordLoginHistory(ctx, user.UserID, meta, true)
 
 	//生成用户登录令牌，生成失败，返回错误信息
 	user.Token, err = jwt.GenToken(user.UserID, user.UserName)
@@ -3,15 +3,18 @@ package logic
 import (
 	"blue-bell_back/dao/mysql"
 	"blue-bell_back/dao/redis"
+	"blue-bell_back/logger"
 	"blue-bell_back/models"
-	"errors"
+	"blue-bell_back/pkg/trace"
+	"context"
 	"strconv"
 
 	"go.uber.org/zap"
 )
 
 var (
-	ErrNotExist = errors.New("当前post id不存在")
+	// ErrNotExist 投票的帖子不存在
+	ErrNotExist = mysql.ErrorPostNotExist
 )
 
 /*
@@ -34,32 +37,27 @@ var (
 
 // CommunityVote 帖子投票和功能逻辑处理
 // 参数：p 包含帖子ID和投票方向的参数结构体
-func CommunityVote(userID string, p *models.ParamCommunityVote) (err error) {
+func CommunityVote(ctx context.Context, userID string, p *models.ParamCommunityVote) (err error) {
+	ctx, span := trace.Start(ctx, "logic.CommunityVote")
+	defer span.Finish(&err)
 	// 将帖子ID转换为字符串形式。
 	var postID = strconv.FormatUint(p.PostID, 10)
 
-	// 检查帖子是否存在。
-	exist, err := mysql.CheckPostExist(postID)
+	// 检查帖子是否存在，同时拿到帖子所在的社区用于推送事件。
+	post, err := mysql.GetPostDetailByID(ctx, p.PostID)
 	if err != nil {
-		// 如果检查帖子存在性时发生错误，记录日志并返回。
-		zap.L().Error("mysql.CheckPostExist(post.CommunityID) failed.",
+		// 帖子不存在时返回ErrNotExist错误。
+		logger.FromContext(ctx).Error("mysql.GetPostDetailByID(p.PostID) failed.",
 			zap.Uint64("post_id:", p.PostID),
 			zap.Error(err))
 		return
 	}
-	if !exist {
-		//如果帖子不存在，记录日志并返回ErrNotExist错误。
-		zap.L().Error("post id not exist.",
-			zap.Uint64("post_id:", p.PostID),
-			zap.Error(err))
-		return ErrNotExist
-	}
 	// 调用Redis投票功能。
-	err = redis.VoteForCommunity(userID, postID, float64(p.Direction))
+	err = redis.VoteForCommunity(ctx, userID, postID, float64(p.Direction))
 	if err != nil {
 		// 如果Redis投票失败，记录日志并返回错误。
-		zap.L().Error("redis.VoteForCommunity(userID, p.PostID, float64(p.Direction)).", zap.Error(err))
-		zap.L().Debug("CommunityVote",
+		logger.FromContext(ctx).Error("redis.VoteForCommunity(userID, p.PostID, float64(p.Direction)).", zap.Error(err))
+		logger.FromContext(ctx).Debug("CommunityVote",
 			zap.String("user_id:", userID),
 			zap.Uint64("post_id:", p.PostID),
 			zap.Int8("direction:", p.Direction),
@@ -67,11 +65,28 @@ func CommunityVote(userID string, p *models.ParamCommunityVote) (err error) {
 		return
 	}
 	// 如果执行到此处，表示投票成功，记录日志。
-	zap.L().Debug("CommunityVote",
+	logger.FromContext(ctx).Debug("CommunityVote",
 		zap.String("user_id:", userID),
 		zap.Uint64("post_id:", p.PostID),
 		zap.Int8("direction:", p.Direction))
 
-	// 再次调用Redis投票功能，这次不记录错误，直接返回结果。
-	return redis.VoteForCommunity(userID, postID, float64(p.Direction))
+	voteTotal.WithLabelValues(strconv.Itoa(int(p.Direction))).Inc()
+	publishVoteChanged(ctx, post, postID)
+	notifyPostVoted(ctx, post, userID, p.Direction)
+	return nil
+}
+
+// publishVoteChanged 推送帖子最新的赞成票数
+func publishVoteChanged(ctx context.Context, post *models.CommunityPost, postID string) {
+	votes, err := redis.GetPostVoteData(ctx, []string{postID})
+	if err != nil || len(votes) == 0 {
+		logger.FromContext(ctx).Warn("redis.GetPostVoteData failed", zap.String("post_id", postID), zap.Error(err))
+		return
+	}
+	publishEvent(ctx, &models.Event{
+		Type:        models.EventVoteChanged,
+		CommunityID: post.CommunityID,
+		PostID:      post.ID,
+		VoteNum:     votes[0],
+	})
 }
@@ -0,0 +1,385 @@
+package logic
+
+import (
+	"blue-bell_back/dao/mysql"
+	"blue-bell_back/dao/redis"
+	"blue-bell_back/logger"
+	"blue-bell_back/models"
+	"blue-bell_back/pkg/errs"
+	"blue-bell_back/pkg/snowflake"
+	"blue-bell_back/pkg/trace"
+	"blue-bell_back/pkg/webhook"
+	"blue-bell_back/settings"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// 社区事件推送
+// 事件发生时为订阅了该事件的每个webhook创建一条推送记录，并加入Redis中的重试队列
+// 后台任务从队列中取出到期的记录发送，失败后按指数退避重试，次数用完后标记为失败，订阅的创建者可以重放
+// 创建记录和加入队列不在同一个事务中，加入队列失败或实例在此之间退出时，由定期的检查把待发送但不在队列中的记录重新加入队列
+
+const (
+	webhookPollInterval       = time.Second      // 队列为空时检查的间隔
+	webhookBatchSize          = 16               // 每次取出并同时发送的记录数
+	webhookRetryBase          = 30 * time.Second // 第一次重试的间隔，之后每次翻倍
+	webhookRetryMax           = time.Hour        // 重试间隔的上限
+	webhookDispatchTimeout    = 5 * time.Second  // 后台创建推送记录的超时时间
+	webhookMaxErrorLen        = 512              // 保存的失败原因的最大长度
+	webhookSweepInterval      = time.Minute      // 检查遗漏的待发送记录的间隔
+	webhookSweepAge           = time.Minute      // 超过这个时间没有更新的待发送记录才检查，避免和刚创建的记录竞争
+	webhookSweepBatch         = 500              // 检查时每次查询的记录数
+	defaultWebhookMaxAttempts = 8
+	defaultWebhookTimeout     = 10 * time.Second
+)
+
+// ErrWebhookURLNotAllowed 推送地址不是http(s)或指向内网
+var ErrWebhookURLNotAllowed = errs.Forbidden("不允许推送到该地址")
+
+var webhookClient = webhook.NewClient(func() bool { return getWebhookConfig().AllowPrivateNetwork })
+
+// webhookConfig 补全默认值后的推送配置
+type webhookConfig struct {
+	MaxAttempts         int
+	Timeout             time.Duration
+	AllowPrivateNetwork bool
+}
+
+func getWebhookConfig() webhookConfig {
+	c := webhookConfig{MaxAttempts: defaultWebhookMaxAttempts, Timeout: defaultWebhookTimeout}
+	if conf := settings.Get().WebhookConfig; conf != nil {
+		if conf.MaxAttempts > 0 {
+			c.MaxAttempts = conf.MaxAttempts
+		}
+		if conf.Timeout > 0 {
+			c.Timeout = time.Duration(conf.Timeout) * time.Second
+		}
+		c.AllowPrivateNetwork = conf.AllowPrivateNetwork
+	}
+	return c
+}
+
+// checkWebhookURL 只允许http(s)地址，不允许内网时拒绝回环、内网IP和localhost
+// 域名解析到内网的情况在建立连接时检查
+func checkWebhookURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return ErrWebhookURLNotAllowed
+	}
+	if getWebhookConfig().AllowPrivateNetwork {
+		return nil
+	}
+	host := u.Hostname()
+	if strings.EqualFold(host, "localhost") || strings.HasSuffix(strings.ToLower(host), ".localhost") {
+		return ErrWebhookURLNotAllowed
+	}
+	if ip := net.ParseIP(host); ip != nil && (ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast()) {
+		return ErrWebhookURLNotAllowed
+	}
+	return nil
+}
+
+// CreateWebhook 创建社区事件的订阅，返回的订阅中包含签名密钥，之后不再返回
+func CreateWebhook(ctx context.Context, userID int64, p *models.ParamCreateWebhook) (w *models.Webhook, err error) {
+	ctx, span := trace.Start(ctx, "logic.CreateWebhook")
+	defer span.Finish(&err)
+	if err = checkWebhookURL(p.URL); err != nil {
+		return
+	}
+	if _, err = mysql.GetCommunityByID(ctx, p.CommunityID); err != nil {
+		return
+	}
+	secret, err := newToken()
+	if err != nil {
+		return
+	}
+	// 按models.Events的顺序去重
+	events := make(models.EventList, 0, len(p.Events))
+	for _, e := range models.Events {
+		if p.Events.Contains(e) {
+			events = append(events, e)
+		}
+	}
+	w = &models.Webhook{
+		ID:          snowflake.GenID(),
+		UserID:      userID,
+		CommunityID: p.CommunityID,
+		URL:         p.URL,
+		Events:      events,
+		Secret:      secret,
+		CreateTime:  time.Now(),
+	}
+	if err = mysql.InsertWebhook(ctx, w); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// GetWebhooks 查询用户创建的订阅
+func GetWebhooks(ctx context.Context, userID int64) ([]*models.Webhook, error) {
+	return mysql.GetWebhooksByUser(ctx, userID)
+}
+
+// DeleteWebhook 删除用户的订阅，队列中剩余的推送记录在取出时丢弃
+func DeleteWebhook(ctx context.Context, userID, webhookID int64) error {
+	return mysql.DeleteWebhook(ctx, userID, webhookID)
+}
+
+// getOwnWebhook 查询用户自己的订阅，其他用户的订阅同样返回不存在
+func getOwnWebhook(ctx context.Context, userID, webhookID int64) (*models.Webhook, error) {
+	w, err := mysql.GetWebhookByID(ctx, webhookID)
+	if err != nil {
+		return nil, err
+	}
+	if w.UserID != userID {
+		return nil, mysql.ErrorWebhookNotExist
+	}
+	return w, nil
+}
+
+// GetWebhookDeliveries 分页查询订阅的推送记录
+func GetWebhookDeliveries(ctx context.Context, userID, webhookID int64, p *models.ParamWebhookDeliveries) (list []*models.WebhookDelivery, err error) {
+	ctx, span := trace.Start(ctx, "logic.GetWebhookDeliveries")
+	defer span.Finish(&err)
+	if _, err = getOwnWebhook(ctx, userID, webhookID); err != nil {
+		return
+	}
+	page, size := p.Page, p.Size
+	if page <= 0 {
+		page = 1
+	}
+	if size <= 0 {
+		size = 10
+	}
+	return mysql.GetDeliveries(ctx, webhookID, p.Status, page, size)
+}
+
+// ReplayWebhookDelivery 重新发送一条推送记录，重试次数从头计算
+func ReplayWebhookDelivery(ctx context.Context, userID, webhookID, deliveryID int64) (err error) {
+	ctx, span := trace.Start(ctx, "logic.ReplayWebhookDelivery")
+	defer span.Finish(&err)
+	if _, err = getOwnWebhook(ctx, userID, webhookID); err != nil {
+		return
+	}
+	d, err := mysql.GetDeliveryByID(ctx, deliveryID)
+	if err != nil {
+		return
+	}
+	if d.WebhookID != webhookID {
+		return mysql.ErrorDeliveryNotExist
+	}
+	if err = mysql.ResetDelivery(ctx, deliveryID); err != nil {
+		return
+	}
+	return redis.EnqueueDelivery(ctx, deliveryID, time.Now())
+}
+
+// dispatchWebhooks 在后台为订阅了事件的webhook创建推送记录，失败只记录日志，不影响业务
+func dispatchWebhooks(ctx context.Context, e *models.Event) {
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		ctx, cancel := context.WithTimeout(ctx, webhookDispatchTimeout)
+		defer cancel()
+		if err := enqueueWebhooks(ctx, e); err != nil {
+			logger.FromContext(ctx).Error("enqueue webhooks failed",
+				zap.String("type", e.Type),
+				zap.Int64("community_id", e.CommunityID),
+				zap.Error(err))
+		}
+	}()
+}
+
+func enqueueWebhooks(ctx context.Context, e *models.Event) (err error) {
+	ctx, span := trace.Start(ctx, "logic.enqueueWebhooks")
+	defer span.Finish(&err)
+	hooks, err := mysql.GetWebhooksByCommunity(ctx, e.CommunityID)
+	if err != nil || len(hooks) == 0 {
+		return
+	}
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	for _, w := range hooks {
+		if !w.Events.Contains(e.Type) {
+			continue
+		}
+		d := &models.WebhookDelivery{
+			ID:        snowflake.GenID(),
+			WebhookID: w.ID,
+			Event:     e.Type,
+			Payload:   string(payload),
+			Status:    models.DeliveryStatusPending,
+		}
+		if err = mysql.InsertDelivery(ctx, d); err != nil {
+			return
+		}
+		if err = redis.EnqueueDelivery(ctx, d.ID, time.Now()); err != nil {
+			return
+		}
+	}
+	return nil
+}
+
+// StartWebhookWorker 在后台发送队列中到期的推送记录，并定期把遗漏的待发送记录重新加入队列，ctx取消后退出
+// 多个实例可以同时运行，每条记录取出后在租期内只会被一个实例发送
+func StartWebhookWorker(ctx context.Context) {
+	go func() {
+		for ctx.Err() == nil {
+			n, err := sweepWebhookDeliveries(ctx)
+			if err != nil && ctx.Err() == nil {
+				logger.FromContext(ctx).Error("sweep webhook deliveries failed", zap.Error(err))
+			}
+			if n > 0 {
+				logger.FromContext(ctx).Warn("requeued webhook deliveries missing from the queue", zap.Int64("count", n))
+			}
+			sleepContext(ctx, webhookSweepInterval)
+		}
+	}()
+	go func() {
+		for ctx.Err() == nil {
+			lease := getWebhookConfig().Timeout + 30*time.Second
+			ids, err := redis.ClaimDeliveries(ctx, lease, webhookBatchSize)
+			if err != nil {
+				if ctx.Err() == nil {
+					logger.FromContext(ctx).Error("redis.ClaimDeliveries failed", zap.Error(err))
+				}
+				sleepContext(ctx, webhookPollInterval)
+				continue
+			}
+			var wg sync.WaitGroup
+			for _, id := range ids {
+				wg.Add(1)
+				go func(id int64) {
+					defer wg.Done()
+					if err := deliverWebhook(ctx, id); err != nil {
+						logger.FromContext(ctx).Error("deliver webhook failed", zap.Int64("delivery_id", id), zap.Error(err))
+					}
+				}(id)
+			}
+			wg.Wait()
+			// 取满一批时说明可能还有到期的记录，立即继续
+			if len(ids) < webhookBatchSize {
+				sleepContext(ctx, webhookPollInterval)
+			}
+		}
+	}()
+}
+
+// sweepWebhookDeliveries 把MySQL中待发送但不在队列中的记录重新加入队列，返回加入的数量
+// 等待重试的记录已在队列中，保持原来的发送时间；已经发送完的记录取出时会被丢弃，所以和发送并发执行没有影响
+func sweepWebhookDeliveries(ctx context.Context) (n int64, err error) {
+	ctx, span := trace.Start(ctx, "logic.sweepWebhookDeliveries")
+	defer span.Finish(&err)
+	var afterID int64
+	for {
+		ids, err := mysql.GetStalePendingDeliveryIDs(ctx, webhookSweepAge, afterID, webhookSweepBatch)
+		if err != nil {
+			return n, err
+		}
+		added, err := redis.RequeueDeliveries(ctx, ids)
+		n += added
+		if err != nil {
+			return n, err
+		}
+		if len(ids) < webhookSweepBatch {
+			return n, nil
+		}
+		afterID = ids[len(ids)-1]
+	}
+}
+
+// deliverWebhook 发送一条推送记录并保存结果，失败且还有重试次数时重新加入队列
+// 记录或订阅已被删除、记录已经不是待发送状态时直接移出队列
+func deliverWebhook(ctx context.Context, deliveryID int64) (err error) {
+	ctx, span := trace.Start(ctx, "logic.deliverWebhook", trace.Int64("webhook.delivery_id", deliveryID))
+	defer span.Finish(&err)
+	d, err := mysql.GetDeliveryByID(ctx, deliveryID)
+	if errors.Is(err, mysql.ErrorDeliveryNotExist) {
+		return redis.RemoveDelivery(ctx, deliveryID)
+	}
+	if err != nil {
+		return
+	}
+	if d.Status != models.DeliveryStatusPending {
+		return redis.RemoveDelivery(ctx, deliveryID)
+	}
+	w, err := mysql.GetWebhookByID(ctx, d.WebhookID)
+	if errors.Is(err, mysql.ErrorWebhookNotExist) {
+		return redis.RemoveDelivery(ctx, deliveryID)
+	}
+	if err != nil {
+		return
+	}
+
+	conf := getWebhookConfig()
+	sendCtx, cancel := context.WithTimeout(ctx, conf.Timeout)
+	status, sendErr := webhookClient.Send(sendCtx, &webhook.Request{
+		URL:        w.URL,
+		Secret:     w.Secret,
+		Event:      d.Event,
+		DeliveryID: strconv.FormatInt(d.ID, 10),
+		Body:       []byte(d.Payload),
+	})
+	cancel()
+
+	d.Attempts++
+	d.ResponseCode = status
+	d.LastError = ""
+	result := "success"
+	switch {
+	case sendErr == nil:
+		d.Status = models.DeliveryStatusSuccess
+	case d.Attempts >= conf.MaxAttempts:
+		d.Status = models.DeliveryStatusFailed
+		result = "failed"
+	default:
+		result = "retry"
+	}
+	if sendErr != nil {
+		d.LastError = truncate(sendErr.Error(), webhookMaxErrorLen)
+	}
+	webhookDeliveriesTotal.WithLabelValues(result).Inc()
+	logger.FromContext(ctx).Info("webhook delivered",
+		zap.Int64("delivery_id", d.ID),
+		zap.Int64("webhook_id", w.ID),
+		zap.String("event", d.Event),
+		zap.String("result", result),
+		zap.Int("attempts", d.Attempts),
+		zap.Int("response_code", status),
+		zap.String("error", d.LastError))
+
+	if err = mysql.UpdateDeliveryResult(ctx, d); err != nil {
+		return
+	}
+	if d.Status == models.DeliveryStatusPending {
+		return redis.EnqueueDelivery(ctx, d.ID, time.Now().Add(webhookBackoff(d.Attempts)))
+	}
+	return redis.RemoveDelivery(ctx, d.ID)
+}
+
+// webhookBackoff 第attempts次失败后等待的时间
+func webhookBackoff(attempts int) time.Duration {
+	d := webhookRetryBase
+	for i := 1; i < attempts && d < webhookRetryMax; i++ {
+		d *= 2
+	}
+	return min(d, webhookRetryMax)
+}
+
+// truncate 把字符串截断到最多n个字符
+func truncate(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n])
+	}
+	return s
+}
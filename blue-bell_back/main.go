@@ -5,7 +5,10 @@ import (
 	"blue-bell_back/dao/mysql"
 	"blue-bell_back/dao/redis"
 	"blue-bell_back/logger"
+	"blue-bell_back/logic"
+	"blue-bell_back/pkg/mailer"
 	"blue-bell_back/pkg/snowflake"
+	"blue-bell_back/pkg/trace"
 	"blue-bell_back/router"
 	"blue-bell_back/settings"
 	"context"
@@ -17,7 +20,6 @@ import (
 	"syscall"
 	"time"
 
-	"github.com/spf13/viper"
 	"go.uber.org/zap"
 )
 
@@ -29,49 +31,98 @@ func main() {
 		fmt.Printf("init settings failed,err:%v\n", err)
 		return
 	}
+	conf := settings.Get()
 	// 2.初始化日志
 	//if err := logger.Init(); err != nil {
-	if err := logger.Init(settings.Conf.LogConfig); err != nil {
+	if err := logger.Init(conf.LogConfig); err != nil {
 		fmt.Printf("init logger failed,err:%v\n", err)
 		return
 	}
 	//将缓冲区的日志写入磁盘（防止程序结束前日志丢失）。
 	//必须调用 .Sync()，否则最后几条日志可能不会落盘。
 	defer zap.L().Sync()
+	// 初始化链路追踪
+	if tc := conf.TraceConfig; tc != nil && tc.Enable {
+		shutdown, err := trace.Init(trace.Config{
+			ServiceName: conf.Name,
+			Exporter:    tc.Exporter,
+			Endpoint:    tc.Endpoint,
+			Headers:     tc.Headers,
+			SampleRatio: tc.SampleRatio,
+		})
+		if err != nil {
+			fmt.Printf("init trace failed,err:%v\n", err)
+			return
+		}
+		// 退出前导出剩余的链路数据
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_ = shutdown(ctx)
+		}()
+	}
 	// 3.初始化MySQL连接
-	if err := mysql.Init(settings.Conf.MySQLConfig); err != nil {
+	if err := mysql.Init(conf.MySQLConfig); err != nil {
 		fmt.Printf("init mysql failed,err:%v\n", err)
 		return
 	}
 	defer mysql.Close()
 	// 4.初始化Redis连接
-	if err := redis.Init(settings.Conf.RedisConfig); err != nil {
+	if err := redis.Init(conf.RedisConfig); err != nil {
 		fmt.Printf("init redis failed,err:%v\n", err)
 		return
 	}
 	defer redis.Close()
+	// 订阅Redis中的实时事件，分发给本实例上的客户端
+	streamCtx, stopStream := context.WithCancel(context.Background())
+	defer stopStream()
+	logic.StartEventStream(streamCtx)
+	// 发送Redis队列中的事件推送
+	logic.StartWebhookWorker(streamCtx)
+	// 把发件箱中未同步的帖子变更同步到Redis
+	logic.StartOutboxRelay(streamCtx)
+	// 初始化邮件发送
+	if err := mailer.Init(conf.MailConfig); err != nil {
+		fmt.Printf("init mailer failed,err:%v\n", err)
+		return
+	}
 
 	//5.初始化snowflake
-	if err := snowflake.Init(settings.Conf.StartTime, settings.Conf.MachineID); err != nil {
+	if err := snowflake.Init(conf.StartTime, conf.MachineID); err != nil {
 		fmt.Printf("init snowflake failed, err:%v\n", err)
 	}
 
 	// 6.初始化gin框架内置的校验器使用的翻译器
 	if err := controller.InitTrans("zh"); err != nil {
-		fmt.Println("init validator trans err:%v", err)
+		fmt.Printf("init validator trans err:%v\n", err)
 		return
 	}
 
 	//7.注册路由
-	r := router.Setup(settings.Conf.Mode)
+	r, err := router.Setup(conf.Mode, conf.TrustedProxies)
+	if err != nil {
+		fmt.Printf("init router failed,err:%v\n", err)
+		return
+	}
 
 	// 8.启动服务(优雅关机)
 	srv := &http.Server{
 		//Addr:    fmt.Sprintf(":#{settings.Conf.Port}"),  // 错误 TODO:
-		Addr:    fmt.Sprintf(":%d", viper.GetInt("port")), //Go 中要用 fmt.Sprintf 或直接拼接
+		Addr:    fmt.Sprintf(":%d", conf.Port), //Go 中要用 fmt.Sprintf 或直接拼接
 		Handler: r,
 	}
 
+	// 监控指标使用单独的地址，不对外暴露
+	var metricsSrv *http.Server
+	if mc := conf.MetricsConfig; mc != nil && mc.Addr != "" {
+		metricsSrv = &http.Server{Addr: mc.Addr, Handler: router.SetupMetrics()}
+		go func() {
+			if err := metricsSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				zap.L().Error("metrics listen failed", zap.Error(err))
+			}
+		}()
+	}
+
 	go func() {
 		// 开启一个goroutine启动服务
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed { //是主动调用 Shutdown() 导致的关闭，属于正常行为，不打印错误。
@@ -88,10 +139,18 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM) // 此处不会阻塞
 	<-quit                                               // 阻塞在此，当接收到上述两种信号时才会往下执行
 	zap.L().Info("Shutdown Server ...")
+	// 先让就绪检查返回503，等待负载均衡摘除流量后再关闭服务
+	logic.StartShutdown()
+	if wait := settings.Get().ShutdownWait; wait > 0 {
+		time.Sleep(time.Duration(wait) * time.Second)
+	}
 	// 创建一个5秒超时的context
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	// 5秒内优雅关闭服务（将未处理完的请求处理完再关闭服务），超过5秒就超时退出
+	if metricsSrv != nil {
+		_ = metricsSrv.Shutdown(ctx)
+	}
 	if err := srv.Shutdown(ctx); err != nil {
 		zap.L().Fatal("Server Shutdown:", zap.Error(err))
 	}
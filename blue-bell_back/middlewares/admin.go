@@ -0,0 +1,37 @@
+package middlewares
+
+import (
+	"blue-bell_back/controller"
+	"blue-bell_back/settings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminAuthMiddleware 管理员认证中间件
+// 需要放在JWTAuthMiddleware之后使用，只允许配置文件中admin.user_ids列出的用户访问
+func AdminAuthMiddleware() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		uid, ok := c.Get(controller.CtxUserIDKey)
+		userID, _ := uid.(int64)
+		if !ok || !isAdmin(userID) {
+			controller.ResponseError(c, controller.CodeNoPermission)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// isAdmin 判断用户是否是管理员
+func isAdmin(userID int64) bool {
+	conf := settings.Get().AdminConfig
+	if conf == nil {
+		return false
+	}
+	for _, id := range conf.UserIDs {
+		if id == userID {
+			return true
+		}
+	}
+	return false
+}
@@ -2,10 +2,13 @@ package middlewares
 
 import (
 	"blue-bell_back/controller"
+	"blue-bell_back/dao/redis"
+	"blue-bell_back/logger"
 	"blue-bell_back/pkg/jwt"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 )
 
 const (
@@ -29,8 +32,8 @@ func JWTAuthMiddleware() func(c *gin.Context) {
 		}
 		//按空格分割
 		parts := strings.SplitN(authHeader, SpaceKey, 2)
-		if !(len(parts) == 2) && parts[0] == BearerKey {
-			controller.ResponseError(c, controller.CodeInvalidParam)
+		if !(len(parts) == 2 && parts[0] == BearerKey) {
+			controller.ResponseError(c, controller.CodeInvalidToken)
 			c.Abort()
 			return
 		}
@@ -38,7 +41,18 @@ func JWTAuthMiddleware() func(c *gin.Context) {
 		//parts[1]获取到的是token string，使用之前定义好的解析函数
 		mc, err := jwt.ParseToken(parts[1])
 		if err != nil {
-			controller.ResponseError(c, controller.CodeInvalidParam)
+			controller.ResponseError(c, controller.CodeInvalidToken)
+			c.Abort()
+			return
+		}
+
+		//修改密码或注销账户后，签发时间不晚于吊销时间的令牌失效
+		//Redis不可用时放行，避免影响正常业务
+		validAfter, err := redis.GetTokenValidAfter(c.Request.Context(), mc.UserID)
+		if err != nil {
+			logger.FromContext(c.Request.Context()).Warn("redis.GetTokenValidAfter failed", zap.Error(err))
+		} else if mc.IssuedAtMillis() <= validAfter {
+			controller.ResponseError(c, controller.CodeInvalidToken)
 			c.Abort()
 			return
 		}
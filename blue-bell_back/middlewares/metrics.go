@@ -0,0 +1,48 @@
+package middlewares
+
+import (
+	"blue-bell_back/controller"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// httpRequestsTotal 按路由、HTTP状态码和业务码统计请求数量
+	httpRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "bluebell_http_requests_total",
+		Help: "Total number of HTTP requests.",
+	}, []string{"method", "route", "status", "code"})
+	// httpRequestDuration 按路由统计请求耗时
+	httpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "bluebell_http_request_duration_seconds",
+		Help:    "HTTP request latency in seconds.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "code"})
+)
+
+// MetricsMiddleware 记录请求数量和耗时的中间件
+// 路由使用注册时的路径模板（如 /api/v1/community/:id），避免标签数量无限增长
+func MetricsMiddleware() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		code := ""
+		if v, ok := c.Get(controller.CtxResCodeKey); ok {
+			if rc, ok := v.(controller.ResCode); ok {
+				code = strconv.FormatInt(int64(rc), 10)
+			}
+		}
+		status := strconv.Itoa(c.Writer.Status())
+		httpRequestsTotal.WithLabelValues(c.Request.Method, route, status, code).Inc()
+		httpRequestDuration.WithLabelValues(c.Request.Method, route, code).Observe(time.Since(start).Seconds())
+	}
+}
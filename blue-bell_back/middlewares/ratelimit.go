@@ -0,0 +1,73 @@
+package middlewares
+
+import (
+	"blue-bell_back/controller"
+	"blue-bell_back/dao/redis"
+	"blue-bell_back/logger"
+	"blue-bell_back/settings"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// 限流策略名称，对应配置文件中 rate_limit.policies 下的键
+const (
+	RateLimitLogin      = "login"
+	RateLimitSignUp     = "signup"
+	RateLimitCreatePost = "create_post"
+	RateLimitVote       = "vote"
+	RateLimitMail       = "mail"
+)
+
+// RateLimitMiddleware 基于Redis滑动窗口的限流中间件
+// 已登录的请求按用户ID限流，匿名请求按客户端IP限流，需要按用户限流时放在JWTAuthMiddleware之后
+// 策略在每次请求时从配置中读取，修改配置文件后立即生效
+func RateLimitMiddleware(name string) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		policy := getRateLimitPolicy(name)
+		if policy == nil {
+			c.Next()
+			return
+		}
+
+		key := name + ":ip:" + c.ClientIP()
+		if uid, ok := c.Get(controller.CtxUserIDKey); ok {
+			if userID, ok := uid.(int64); ok {
+				key = name + ":user:" + strconv.FormatInt(userID, 10)
+			}
+		}
+
+		allowed, retryAfter, err := redis.AllowRequest(c.Request.Context(), key,
+			policy.Limit, time.Duration(policy.Window)*time.Second)
+		if err != nil {
+			// Redis不可用时放行，避免限流影响正常业务
+			logger.FromContext(c.Request.Context()).Warn("redis.AllowRequest failed",
+				zap.String("key", key), zap.Error(err))
+			c.Next()
+			return
+		}
+		if !allowed {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			controller.ResponseError(c, controller.CodeTooManyRequests)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// getRateLimitPolicy 返回指定名称的限流策略，未开启限流或未配置时返回nil
+func getRateLimitPolicy(name string) *settings.RateLimitPolicy {
+	conf := settings.Get().RateLimitConfig
+	if conf == nil || !conf.Enable {
+		return nil
+	}
+	policy, ok := conf.Policies[name]
+	if !ok || policy == nil || policy.Limit <= 0 || policy.Window <= 0 {
+		return nil
+	}
+	return policy
+}
@@ -0,0 +1,35 @@
+package middlewares
+
+import (
+	"blue-bell_back/controller"
+	"blue-bell_back/logger"
+	"blue-bell_back/logic"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// StreamTicketKey 查询参数中事件流票据的名称
+const StreamTicketKey = "ticket"
+
+// StreamAuthMiddleware 事件流的认证中间件
+// 浏览器的EventSource不能设置请求头，没有Authorization时使用查询参数中的一次性票据认证
+func StreamAuthMiddleware() func(c *gin.Context) {
+	jwtAuth := JWTAuthMiddleware()
+	return func(c *gin.Context) {
+		ticket := c.Query(StreamTicketKey)
+		if c.GetHeader(AuthorizationKey) != NullKey || ticket == NullKey {
+			jwtAuth(c)
+			return
+		}
+		userID, err := logic.TakeStreamTicket(c.Request.Context(), ticket)
+		if err != nil {
+			logger.FromContext(c.Request.Context()).Warn("logic.TakeStreamTicket failed", zap.Error(err))
+			controller.ResponseError(c, controller.CodeInvalidToken)
+			c.Abort()
+			return
+		}
+		c.Set(controller.CtxUserIDKey, userID)
+		c.Next()
+	}
+}
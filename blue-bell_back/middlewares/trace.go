@@ -0,0 +1,43 @@
+package middlewares
+
+import (
+	"blue-bell_back/controller"
+	"blue-bell_back/pkg/trace"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// TraceMiddleware 链路追踪中间件
+// 为每个请求创建根节点，上游通过traceparent头传递链路信息时作为其子节点
+// 节点放在请求的context中，controller把c.Request.Context()传给logic和dao层
+func TraceMiddleware() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		ctx, span := trace.StartWithKind(ctx, c.Request.Method+" "+route, trace.KindServer,
+			trace.String("http.method", c.Request.Method),
+			trace.String("http.route", route),
+			trace.String("http.target", c.Request.URL.Path),
+			trace.String("http.client_ip", c.ClientIP()),
+		)
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(trace.Int("http.status_code", status))
+		if v, ok := c.Get(controller.CtxResCodeKey); ok {
+			if code, ok := v.(controller.ResCode); ok {
+				span.SetAttributes(trace.Int64("app.res_code", int64(code)))
+			}
+		}
+		if status >= 500 {
+			span.SetStatus(trace.StatusError, c.Errors.String())
+		}
+		span.End()
+	}
+}
@@ -0,0 +1,41 @@
+package middlewares
+
+import (
+	"blue-bell_back/controller"
+	"blue-bell_back/logger"
+	"blue-bell_back/logic"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// EmailVerifiedMiddleware 未验证邮箱的账户只读
+// 只在 account.unverified_policy 为 read_only 时生效，需要放在JWTAuthMiddleware之后
+func EmailVerifiedMiddleware() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		if logic.GetUnverifiedPolicy() != logic.UnverifiedReadOnly {
+			c.Next()
+			return
+		}
+		uid, ok := c.Get(controller.CtxUserIDKey)
+		userID, _ := uid.(int64)
+		if !ok {
+			controller.ResponseError(c, controller.CodeNeedLogin)
+			c.Abort()
+			return
+		}
+		if err := logic.CheckEmailVerified(c.Request.Context(), userID); err != nil {
+			if errors.Is(err, logic.ErrEmailNotVerified) {
+				controller.ResponseError(c, controller.CodeEmailNotVerified)
+				c.Abort()
+				return
+			}
+			logger.FromContext(c.Request.Context()).Error("logic.CheckEmailVerified failed", zap.Error(err))
+			controller.ResponseError(c, controller.CodeServerBusy)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
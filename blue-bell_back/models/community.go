@@ -17,6 +17,12 @@ type CommunityDetail struct {
 	CreateTime   time.Time `json:"create_time" db:"create_time"`
 }
 
+// 帖子状态
+const (
+	PostStatusDeleted int32 = 0 // 已删除
+	PostStatusNormal  int32 = 1 // 正常
+)
+
 // 帖子
 type CommunityPost struct {
 	ID          int64     `json:"id" db:"post_id"`
@@ -24,7 +30,8 @@ type CommunityPost struct {
 	CommunityID int64     `json:"community_id" db:"community_id" binding:"required"`
 	Status      int32     `json:"status" db:"status"`
 	Title       string    `json:"title" db:"title" binding:"required"`
-	Content     string    `json:"content" db:"content" binding:"required"`
+	Content     string    `json:"content,omitempty" db:"content" binding:"required,max=8192"` // Markdown原文，列表中不返回
+	Tags        []string  `json:"tags,omitempty" db:"-" binding:"omitempty,max=5,dive,min=1,max=32"`
 	CreateTime  time.Time `json:"create_time" db:"create_time"`
 }
 
@@ -32,6 +39,8 @@ type CommunityPost struct {
 type ApiPostDetail struct {
 	AuthorName       string `json:"author_name"`
 	VoteNum          int64  `json:"vote_num"`
+	ContentHTML      string `json:"content_html,omitempty"` // 渲染后的内容，只在帖子详情中返回
+	Excerpt          string `json:"excerpt,omitempty"`      // 纯文本摘要，只在帖子列表中返回
 	*CommunityDetail `json:"community_detail"`
 	*CommunityPost   `json:"community_post"`
 }
@@ -18,10 +18,14 @@ CREATE TABLE `user`
     `username` VARCHAR(64)  NOT NULL  DEFAULT   '',
     `password`  VARCHAR(64) not NULL DEFAULT    '',
     `email` VARCHAR(64) DEFAULT NULL,
+    `email_verified` TINYINT(1) NOT NULL DEFAULT '0',
     `gender`   TINYINT(3)   DEFAULT '0',
+    `locale`   VARCHAR(8)   NOT NULL DEFAULT '',
     `create_time`   DATETIME    DEFAULT NULL,
     `update_time`   DATETIME    DEFAULT NULL,
-    PRIMARY KEY (`id`)
+    `delete_time`   DATETIME    DEFAULT NULL,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `idx_email` (`email`)
 
 )ENGINE=INNODB AUTO_INCREMENT=2 DEFAULT CHARSET=utf8
 
@@ -64,4 +68,142 @@ CREATE TABLE `post`
     UNIQUE KEY `idx_post_id` (`post_id`),
     KEY            `idx_author_id` (`author_id`),
     KEY            `idx_community_id` (`community_id`)
-) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
\ No newline at end of file
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+DROP TABLE IF EXISTS `login_history`;
+CREATE TABLE `login_history`
+(
+    `id`          bigint(20) NOT NULL AUTO_INCREMENT,
+    `user_id`     bigint(20) NOT NULL COMMENT '用户id',
+    `ip`          varchar(64) COLLATE utf8mb4_general_ci NOT NULL DEFAULT '' COMMENT '登录IP',
+    `user_agent`  varchar(512) COLLATE utf8mb4_general_ci NOT NULL DEFAULT '' COMMENT '客户端信息',
+    `success`     tinyint(1) NOT NULL DEFAULT '0' COMMENT '是否登录成功',
+    `create_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP COMMENT '登录时间',
+    PRIMARY KEY (`id`),
+    KEY `idx_user_id_create_time` (`user_id`, `create_time`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+DROP TABLE IF EXISTS `user_identity`;
+CREATE TABLE `user_identity`
+(
+    `id`          bigint(20) NOT NULL AUTO_INCREMENT,
+    `user_id`     bigint(20) NOT NULL COMMENT '用户id',
+    `provider`    varchar(32) COLLATE utf8mb4_general_ci NOT NULL COMMENT '提供方名称',
+    `subject`     varchar(255) COLLATE utf8mb4_general_ci NOT NULL COMMENT '用户在提供方的唯一标识',
+    `email`       varchar(64) COLLATE utf8mb4_general_ci NOT NULL DEFAULT '' COMMENT '提供方返回的邮箱',
+    `create_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP COMMENT '绑定时间',
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `idx_provider_subject` (`provider`, `subject`),
+    KEY `idx_user_id` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+DROP TABLE IF EXISTS `notification`;
+CREATE TABLE `notification`
+(
+    `id`            bigint(20) NOT NULL AUTO_INCREMENT,
+    `user_id`       bigint(20) NOT NULL COMMENT '接收通知的用户id',
+    `type`          varchar(32) COLLATE utf8mb4_general_ci NOT NULL COMMENT '通知类型',
+    `post_id`       bigint(20) NOT NULL COMMENT '相关的帖子id',
+    `actor_count`   int(11) NOT NULL DEFAULT '1' COMMENT '聚合的人数',
+    `last_actor_id` bigint(20) NOT NULL COMMENT '最后一个触发通知的用户id',
+    `is_read`       tinyint(1) NOT NULL DEFAULT '0' COMMENT '是否已读',
+    `agg_key`       varchar(64) COLLATE utf8mb4_general_ci DEFAULT NULL COMMENT '未读通知的聚合键，已读后置为NULL',
+    `create_time`   timestamp NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    `update_time`   timestamp NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `idx_user_id_agg_key` (`user_id`, `agg_key`),
+    KEY `idx_user_id_update_time` (`user_id`, `update_time`),
+    KEY `idx_user_id_is_read` (`user_id`, `is_read`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+DROP TABLE IF EXISTS `notification_preference`;
+CREATE TABLE `notification_preference`
+(
+    `user_id`     bigint(20) NOT NULL COMMENT '用户id',
+    `type`        varchar(32) COLLATE utf8mb4_general_ci NOT NULL COMMENT '通知类型',
+    `enabled`     tinyint(1) NOT NULL COMMENT '是否接收',
+    `update_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+    PRIMARY KEY (`user_id`, `type`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+DROP TABLE IF EXISTS `webhook`;
+CREATE TABLE `webhook`
+(
+    `id`           bigint(20) NOT NULL AUTO_INCREMENT,
+    `webhook_id`   bigint(20) NOT NULL COMMENT '订阅id',
+    `user_id`      bigint(20) NOT NULL COMMENT '创建订阅的用户id',
+    `community_id` bigint(20) NOT NULL COMMENT '订阅的社区',
+    `url`          varchar(512) COLLATE utf8mb4_general_ci NOT NULL COMMENT '推送地址',
+    `events`       varchar(256) COLLATE utf8mb4_general_ci NOT NULL COMMENT '订阅的事件类型，逗号分隔',
+    `secret`       varchar(64) COLLATE utf8mb4_general_ci NOT NULL COMMENT '签名密钥',
+    `create_time`  timestamp NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `idx_webhook_id` (`webhook_id`),
+    KEY `idx_user_id` (`user_id`),
+    KEY `idx_community_id` (`community_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+DROP TABLE IF EXISTS `webhook_delivery`;
+CREATE TABLE `webhook_delivery`
+(
+    `id`            bigint(20) NOT NULL AUTO_INCREMENT,
+    `delivery_id`   bigint(20) NOT NULL COMMENT '推送记录id',
+    `webhook_id`    bigint(20) NOT NULL COMMENT '订阅id',
+    `event`         varchar(32) COLLATE utf8mb4_general_ci NOT NULL COMMENT '事件类型',
+    `payload`       text COLLATE utf8mb4_general_ci NOT NULL COMMENT '推送的请求体',
+    `status`        varchar(16) COLLATE utf8mb4_general_ci NOT NULL DEFAULT 'pending' COMMENT '状态 pending、success、failed',
+    `attempts`      int(11) NOT NULL DEFAULT '0' COMMENT '已经请求的次数',
+    `response_code` int(11) NOT NULL DEFAULT '0' COMMENT '最后一次请求的响应状态码',
+    `last_error`    varchar(512) COLLATE utf8mb4_general_ci NOT NULL DEFAULT '' COMMENT '最后一次失败的原因',
+    `create_time`   timestamp NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    `update_time`   timestamp NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `idx_delivery_id` (`delivery_id`),
+    KEY `idx_webhook_id_status` (`webhook_id`, `status`),
+    KEY `idx_status_delivery_id` (`status`, `delivery_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+DROP TABLE IF EXISTS `outbox`;
+CREATE TABLE `outbox`
+(
+    `id`              bigint(20) NOT NULL AUTO_INCREMENT,
+    `type`            varchar(32) COLLATE utf8mb4_general_ci NOT NULL COMMENT '消息类型',
+    `payload`         text COLLATE utf8mb4_general_ci NOT NULL COMMENT '消息内容（JSON）',
+    `attempts`        int(11) NOT NULL DEFAULT '0' COMMENT '已经失败的次数',
+    `last_error`      varchar(512) COLLATE utf8mb4_general_ci NOT NULL DEFAULT '' COMMENT '最后一次失败的原因',
+    `next_retry_time` datetime NOT NULL COMMENT '下次处理的时间，处理中时为租期的结束时间',
+    `create_time`     timestamp NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    PRIMARY KEY (`id`),
+    KEY `idx_next_retry_time` (`next_retry_time`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+DROP TABLE IF EXISTS `saved_post`;
+CREATE TABLE `saved_post`
+(
+    `user_id`     bigint(20) NOT NULL COMMENT '用户id',
+    `post_id`     bigint(20) NOT NULL COMMENT '收藏的帖子id',
+    `create_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP COMMENT '收藏时间',
+    PRIMARY KEY (`user_id`, `post_id`),
+    KEY `idx_user_id_create_time` (`user_id`, `create_time`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+DROP TABLE IF EXISTS `follow`;
+CREATE TABLE `follow`
+(
+    `follower_id` bigint(20) NOT NULL COMMENT '关注者的用户id',
+    `followee_id` bigint(20) NOT NULL COMMENT '被关注的用户id',
+    `create_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP COMMENT '关注时间',
+    PRIMARY KEY (`follower_id`, `followee_id`),
+    KEY `idx_followee_id` (`followee_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+DROP TABLE IF EXISTS `tag`;
+CREATE TABLE `tag`
+(
+    `id`          bigint(20) NOT NULL AUTO_INCREMENT,
+    `name`        varchar(32) COLLATE utf8mb4_general_ci NOT NULL COMMENT '标签名，小写',
+    `post_count`  int(11) NOT NULL DEFAULT '0' COMMENT '使用该标签的帖子数',
+    `create_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `idx_name` (`name`),
+    KEY `idx_post_count` (`post_count`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+DROP TABLE IF EXISTS `post_tag`;
+CREATE TABLE `post_tag`
+(
+    `post_id` bigint(20) NOT NULL COMMENT '帖子id',
+    `tag`     varchar(32) COLLATE utf8mb4_general_ci NOT NULL COMMENT '标签名',
+    PRIMARY KEY (`post_id`, `tag`),
+    KEY `idx_tag` (`tag`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// 实时事件类型
+const (
+	EventPostCreated = "post.created" // 社区中发布了新帖子
+	EventVoteChanged = "vote.changed" // 帖子的赞成票数发生变化
+	EventPostDeleted = "post.deleted" // 帖子被删除
+)
+
+// Event 推送给客户端的实时事件
+// ID使用字符串，避免雪花ID在JavaScript中丢失精度
+type Event struct {
+	Type        string    `json:"type"`
+	CommunityID int64     `json:"community_id"`
+	PostID      int64     `json:"post_id,string"`
+	AuthorID    int64     `json:"author_id,string,omitempty"` // post.created、post.deleted
+	Title       string    `json:"title,omitempty"`            // post.created
+	VoteNum     int64     `json:"vote_num"`                   // vote.changed 时为最新的赞成票数
+	Time        time.Time `json:"time"`
+}
+
+// Events 所有的事件类型
+var Events = []string{EventPostCreated, EventVoteChanged, EventPostDeleted}
+
+// ParamStream 订阅实时事件的参数
+type ParamStream struct {
+	CommunityIDs []int64 `form:"community_id" binding:"omitempty,max=50,dive,min=1"` // 订阅的社区，为空时订阅所有社区
+}
@@ -0,0 +1,10 @@
+package models
+
+// UserProfile 用户的公开资料
+type UserProfile struct {
+	UserID         int64  `json:"user_id,string" db:"user_id"`
+	UserName       string `json:"user_name" db:"username"`
+	FollowerCount  int64  `json:"follower_count"`  // 粉丝数
+	FollowingCount int64  `json:"following_count"` // 关注数
+	Followed       bool   `json:"followed"`        // 当前用户是否已关注
+}
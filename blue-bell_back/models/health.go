@@ -0,0 +1,19 @@
+package models
+
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// DependencyStatus 依赖服务的检查结果，接口是公开的，不返回失败原因，避免暴露内部地址
+type DependencyStatus struct {
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latency_ms"`
+}
+
+// Readiness 就绪检查的结果
+type Readiness struct {
+	Status       string                       `json:"status"`
+	ShuttingDown bool                         `json:"shutting_down,omitempty"`
+	Checks       map[string]*DependencyStatus `json:"checks,omitempty"`
+}
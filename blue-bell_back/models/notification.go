@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// 通知类型
+const (
+	NotifyTypeVote = "vote" // 帖子被赞
+)
+
+// NotifyTypes 所有的通知类型，用户可以按类型关闭通知
+var NotifyTypes = []string{NotifyTypeVote}
+
+// Notification 站内通知
+// 同一个帖子的同类未读通知会聚合为一条，actor_count为聚合的人数
+type Notification struct {
+	ID          int64     `json:"id" db:"id"`
+	UserID      int64     `json:"-" db:"user_id"`
+	Type        string    `json:"type" db:"type"`
+	PostID      int64     `json:"post_id,string" db:"post_id"`
+	PostTitle   string    `json:"post_title" db:"post_title"`
+	ActorCount  int64     `json:"actor_count" db:"actor_count"`
+	LastActorID int64     `json:"last_actor_id,string" db:"last_actor_id"`
+	IsRead      bool      `json:"is_read" db:"is_read"`
+	Message     string    `json:"message" db:"-"` // 按请求语言生成的提示信息
+	CreateTime  time.Time `json:"create_time" db:"create_time"`
+	UpdateTime  time.Time `json:"update_time" db:"update_time"`
+}
+
+// NotifyPreferences 用户的通知设置，键为通知类型
+type NotifyPreferences map[string]bool
+
+// ParamMarkRead 标记通知已读的参数
+type ParamMarkRead struct {
+	IDs []int64 `json:"ids" binding:"omitempty,max=100,dive,min=1"` // 为空时标记所有通知
+}
+
+// ParamNotifyPreferences 修改通知设置的参数，只修改传入的类型
+type ParamNotifyPreferences struct {
+	Vote *bool `json:"vote"`
+}
+
+// Preferences 转换为按类型索引的设置
+func (p *ParamNotifyPreferences) Preferences() NotifyPreferences {
+	prefs := make(NotifyPreferences)
+	if p.Vote != nil {
+		prefs[NotifyTypeVote] = *p.Vote
+	}
+	return prefs
+}
@@ -0,0 +1,24 @@
+package models
+
+// 发件箱消息类型，对应需要同步到Redis的数据变更
+const (
+	OutboxPostCreated = "post.created" // 帖子加入时间、分数排行和社区集合
+	OutboxPostDeleted = "post.deleted" // 帖子移出排行和社区集合，删除投票记录
+)
+
+// OutboxMessage 和业务数据在同一个事务中写入的消息，由后台任务同步到Redis
+type OutboxMessage struct {
+	ID       int64  `db:"id"`
+	Type     string `db:"type"`
+	Payload  string `db:"payload"` // JSON
+	Attempts int    `db:"attempts"`
+}
+
+// OutboxPost 帖子相关消息的内容
+type OutboxPost struct {
+	PostID      int64    `json:"post_id"`
+	CommunityID int64    `json:"community_id"`
+	AuthorID    int64    `json:"author_id"`
+	Tags        []string `json:"tags,omitempty"`
+	CreateTime  int64    `json:"create_time,omitempty"` // 发帖时间（Unix秒），重复执行时保持不变
+}
@@ -1,17 +1,23 @@
 package models
 
 const (
-	Page       = 10
-	Size       = 1
-	OrderTime  = "time"
-	OrderScore = "score"
+	DefaultPage = 1  // 默认页码
+	DefaultSize = 10 // 默认每页数量
+	OrderTime   = "time"
+	OrderScore  = "score"
+
+	OrderTopDay   = "top_day"  // 最近24小时得票最多
+	OrderTopWeek  = "top_week" // 最近7天得票最多
+	OrderTopAll   = "top_all"  // 得票最多
+	OrderTrending = "trending" // 最近几个小时得票最快，越近的投票权重越高
 )
 
 // 注册请求参数结构体
 type ParamSignUp struct {
 	UserName   string `json:"username" binding:"required"`
+	Email      string `json:"email" binding:"required,email,max=64"`
 	Password   string `json:"password" binding:"required"`
-	RePassword string `json:"re_password" binding:"required,eqfield=Password"`
+	RePassword string `json:"re_password" binding:"required"` // 和password是否一致由SignUpParamStructLevelValidation校验
 }
 
 // ParamLogin 登陆请求参数
@@ -22,9 +28,15 @@ type ParamLogin struct {
 
 // ParamOrderList 获取帖子列表
 type ParamOrderList struct {
-	Page  int64  `json:"page" form:"page"`
-	Size  int64  `json:"size" form:"size"`
-	Order string `json:"order" form:"order"`
+	Page  int64  `json:"page" form:"page" binding:"omitempty,min=1" default:"1"`
+	Size  int64  `json:"size" form:"size" binding:"omitempty,min=1,max=100" default:"10"`
+	Order string `json:"order" form:"order" binding:"omitempty,oneof=time score top_day top_week top_all trending" default:"time"`
+}
+
+// ParamPage 分页查询的参数，每页最多100条
+type ParamPage struct {
+	Page int64 `json:"page" form:"page" binding:"omitempty,min=1" default:"1"`
+	Size int64 `json:"size" form:"size" binding:"omitempty,min=1,max=100" default:"10"`
 }
 
 // ParamCommunityPostList 社区下帖子列表的接口
@@ -32,3 +44,50 @@ type ParamCommunityPostList struct {
 	*ParamOrderList
 	CommunityID int64 `json:"community_id" form:"community_id"`
 }
+
+// ParamTagPostList 标签下帖子列表的接口
+type ParamTagPostList struct {
+	*ParamOrderList
+	Tag string `json:"-" form:"-"`
+}
+
+// ParamLogLevel 修改日志级别的请求参数
+type ParamLogLevel struct {
+	Level string `json:"level" binding:"required,oneof=debug info warn error dpanic panic fatal"`
+}
+
+// ParamVerifyEmail 验证邮箱的请求参数
+type ParamVerifyEmail struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// ParamForgotPassword 忘记密码的请求参数
+type ParamForgotPassword struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ParamResetPassword 重置密码的请求参数
+type ParamResetPassword struct {
+	Token      string `json:"token" binding:"required"`
+	Password   string `json:"password" binding:"required"`
+	RePassword string `json:"re_password" binding:"required,eqfield=Password"`
+}
+
+// ParamChangePassword 修改密码的请求参数，未设置过密码的用户不需要旧密码
+type ParamChangePassword struct {
+	OldPassword string `json:"old_password"`
+	Password    string `json:"password" binding:"required"`
+	RePassword  string `json:"re_password" binding:"required,eqfield=Password"`
+}
+
+// ParamDeleteAccount 注销账户的请求参数，需要再次输入密码确认
+// 没有密码的用户使用第三方账户再次验证身份或邮件确认得到的令牌代替密码
+type ParamDeleteAccount struct {
+	Password string `json:"password" binding:"required_without=Token"`
+	Token    string `json:"token" binding:"required_without=Password"`
+}
+
+// ParamUserLocale 设置界面语言的请求参数，为空时使用浏览器的 Accept-Language
+type ParamUserLocale struct {
+	Locale string `json:"locale" binding:"omitempty,oneof=zh en"`
+}
@@ -0,0 +1,9 @@
+package models
+
+import "time"
+
+// SavedPost 用户收藏的帖子
+type SavedPost struct {
+	PostID     int64     `db:"post_id"`
+	CreateTime time.Time `db:"create_time"` // 收藏时间
+}
@@ -0,0 +1,18 @@
+package models
+
+// Tag 帖子的标签
+type Tag struct {
+	Name      string `json:"name" db:"name"`
+	PostCount int64  `json:"post_count" db:"post_count"` // 使用该标签的帖子数
+}
+
+// PostTag 帖子和标签的对应关系
+type PostTag struct {
+	PostID int64  `db:"post_id"`
+	Tag    string `db:"tag"`
+}
+
+// ParamTagList 查询热门标签的参数
+type ParamTagList struct {
+	Size int64 `form:"size" binding:"omitempty,min=1,max=100" default:"20"`
+}
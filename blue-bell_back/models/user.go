@@ -1,9 +1,39 @@
 package models
 
+import "time"
+
 // 存放数据相关的结构体
 type User struct {
-	UserID   int64  `db:"user_id"`
-	UserName string `db:"username"`
-	Password string `db:"password"`
-	Token    string `json:"token"`
+	UserID        int64  `db:"user_id"`
+	UserName      string `db:"username"`
+	Password      string `db:"password"`
+	Email         string `db:"email"`
+	EmailVerified bool   `db:"email_verified"`
+	Token         string `json:"token"`
+}
+
+// LoginHistory 登录记录
+type LoginHistory struct {
+	ID         int64     `json:"id" db:"id"`
+	UserID     int64     `json:"user_id" db:"user_id"`
+	IP         string    `json:"ip" db:"ip"`
+	UserAgent  string    `json:"user_agent" db:"user_agent"`
+	Success    bool      `json:"success" db:"success"`
+	CreateTime time.Time `json:"create_time" db:"create_time"`
+}
+
+// LoginMeta 登录请求的客户端信息
+type LoginMeta struct {
+	IP        string
+	UserAgent string
+}
+
+// UserIdentity 用户绑定的第三方账户
+type UserIdentity struct {
+	ID         int64     `json:"-" db:"id"`
+	UserID     int64     `json:"-" db:"user_id"`
+	Provider   string    `json:"provider" db:"provider"`
+	Subject    string    `json:"subject" db:"subject"`
+	Email      string    `json:"email" db:"email"`
+	CreateTime time.Time `json:"create_time" db:"create_time"`
 }
@@ -0,0 +1,91 @@
+package models
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// 推送记录的状态
+const (
+	DeliveryStatusPending = "pending" // 等待发送或等待重试
+	DeliveryStatusSuccess = "success" // 订阅方返回2xx
+	DeliveryStatusFailed  = "failed"  // 重试次数用完仍然失败
+)
+
+// EventList 订阅的事件类型，数据库中以逗号分隔保存
+type EventList []string
+
+// Value 实现 driver.Valuer
+func (l EventList) Value() (driver.Value, error) {
+	return strings.Join(l, ","), nil
+}
+
+// Scan 实现 sql.Scanner
+func (l *EventList) Scan(src interface{}) error {
+	var s string
+	switch v := src.(type) {
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	case nil:
+	default:
+		return fmt.Errorf("models: cannot scan %T into EventList", src)
+	}
+	*l = nil
+	if s != "" {
+		*l = strings.Split(s, ",")
+	}
+	return nil
+}
+
+// Contains 判断是否订阅了事件类型
+func (l EventList) Contains(typ string) bool {
+	for _, e := range l {
+		if e == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// Webhook 社区事件的订阅
+type Webhook struct {
+	ID          int64     `json:"id,string" db:"webhook_id"`
+	UserID      int64     `json:"-" db:"user_id"`
+	CommunityID int64     `json:"community_id" db:"community_id"`
+	URL         string    `json:"url" db:"url"`
+	Events      EventList `json:"events" db:"events"`
+	Secret      string    `json:"secret,omitempty" db:"secret"` // 只在创建时返回
+	CreateTime  time.Time `json:"create_time" db:"create_time"`
+}
+
+// WebhookDelivery 一次事件推送的记录，重试和重放都更新同一条记录
+type WebhookDelivery struct {
+	ID           int64     `json:"id,string" db:"delivery_id"`
+	WebhookID    int64     `json:"webhook_id,string" db:"webhook_id"`
+	Event        string    `json:"event" db:"event"`
+	Payload      string    `json:"payload" db:"payload"`
+	Status       string    `json:"status" db:"status"`
+	Attempts     int       `json:"attempts" db:"attempts"`
+	ResponseCode int       `json:"response_code" db:"response_code"` // 最后一次请求订阅方返回的状态码，连接失败时为0
+	LastError    string    `json:"last_error" db:"last_error"`       // 最后一次失败的原因
+	CreateTime   time.Time `json:"create_time" db:"create_time"`
+	UpdateTime   time.Time `json:"update_time" db:"update_time"`
+}
+
+// ParamCreateWebhook 创建订阅的参数
+type ParamCreateWebhook struct {
+	CommunityID int64     `json:"community_id" binding:"required,min=1"`
+	URL         string    `json:"url" binding:"required,url,max=512"`
+	Events      EventList `json:"events" binding:"required,min=1,dive,oneof=post.created vote.changed post.deleted"`
+}
+
+// ParamWebhookDeliveries 查询推送记录的参数
+type ParamWebhookDeliveries struct {
+	Page   int64  `form:"page" binding:"omitempty,min=1"`
+	Size   int64  `form:"size" binding:"omitempty,min=1,max=100"`
+	Status string `form:"status" binding:"omitempty,oneof=pending success failed"` // 为空时查询全部
+}
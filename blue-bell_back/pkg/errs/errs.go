@@ -0,0 +1,57 @@
+package errs
+
+import (
+	"errors"
+	"fmt"
+)
+
+// 领域错误
+// DAO和logic层的业务错误都归入以下几类，controller先匹配具体的错误，再按类别统一转换为ResCode
+//
+//	var ErrorUserExist = errs.Conflict("用户已存在")
+//
+//	errors.Is(err, mysql.ErrorUserExist) // 匹配具体的错误
+//	errors.Is(err, errs.ErrConflict)     // 匹配错误的类别
+
+// 错误类别
+var (
+	ErrNotFound  = errors.New("not found") // 数据不存在
+	ErrConflict  = errors.New("conflict")  // 和已有数据冲突，如重复注册、重复投票
+	ErrForbidden = errors.New("forbidden") // 没有权限或被策略禁止
+	ErrExpired   = errors.New("expired")   // 已过期或已失效，如投票期已过、令牌过期
+)
+
+// Error 带类别的错误
+type Error struct {
+	kind error
+	msg  string
+}
+
+func (e *Error) Error() string {
+	return e.msg
+}
+
+// Is 使 errors.Is(err, 类别) 成立
+func (e *Error) Is(target error) bool {
+	return target == e.kind
+}
+
+// NotFound 创建一个数据不存在类别的错误
+func NotFound(msg string) error { return &Error{kind: ErrNotFound, msg: msg} }
+
+// Conflict 创建一个数据冲突类别的错误
+func Conflict(msg string) error { return &Error{kind: ErrConflict, msg: msg} }
+
+// Forbidden 创建一个禁止访问类别的错误
+func Forbidden(msg string) error { return &Error{kind: ErrForbidden, msg: msg} }
+
+// Expired 创建一个已过期类别的错误
+func Expired(msg string) error { return &Error{kind: ErrExpired, msg: msg} }
+
+// Wrap 在错误前加上出错的操作，保留原错误的类别，err为nil时返回nil
+func Wrap(err error, op string) error {
+	if err == nil {
+		return nil
+	}
+	return fmt.Errorf("%s: %w", op, err)
+}
@@ -20,18 +20,34 @@ type MyClaims struct {
 	// 可根据需要自行添加字段
 	UserID             int64  `json:"user_id"`
 	Username           string `json:"username"`
+	IssuedAtMilli      int64  `json:"iat_ms"` // 毫秒精度的签发时间，用于判断是否已被吊销
 	jwt.StandardClaims        // 内嵌标准的声明
 }
 
+// IssuedAtMillis 返回毫秒精度的签发时间，之前签发的令牌没有iat_ms，按签发的那一秒开始计算
+func (mc *MyClaims) IssuedAtMillis() int64 {
+	if mc.IssuedAtMilli > 0 {
+		return mc.IssuedAtMilli
+	}
+	return mc.IssuedAt * 1000
+}
+
 // GenToken 生成JWT
 func GenToken(userID int64, username string) (string, error) {
+	return GenTokenAt(userID, username, time.Now())
+}
+
+// GenTokenAt 生成签发时间为issuedAt的JWT
+func GenTokenAt(userID int64, username string, issuedAt time.Time) (string, error) {
 	// 创建一个我们自己的声明数据
 	claims := MyClaims{
 		userID,
 		username, // 自定义字段
+		issuedAt.UnixMilli(),
 		jwt.StandardClaims{
-			ExpiresAt: time.Now().Add(TokenExpireDuration).Unix(), // 过期时间
-			Issuer:    "Forum",                                    // 签发人
+			ExpiresAt: issuedAt.Add(TokenExpireDuration).Unix(), // 过期时间
+			IssuedAt:  issuedAt.Unix(),                          // 签发时间
+			Issuer:    "Forum",                                  // 签发人
 		},
 	}
 	// 使用指定的签名方法创建签名对象
@@ -0,0 +1,38 @@
+package mailer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// LogMailer 本地开发使用，不真正发送邮件
+// dir不为空时把邮件保存为.eml文件，否则只写入日志
+type LogMailer struct {
+	dir string
+}
+
+// NewLogMailer 创建log驱动的邮件发送实例
+func NewLogMailer(dir string) *LogMailer {
+	return &LogMailer{dir: dir}
+}
+
+// Send 保存或记录邮件
+func (m *LogMailer) Send(ctx context.Context, msg *Message) error {
+	zap.L().Info("mail sent by log mailer",
+		zap.String("to", msg.To),
+		zap.String("subject", msg.Subject),
+		zap.String("body", msg.Body))
+	if m.dir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(m.dir, 0o755); err != nil {
+		return err
+	}
+	name := strconv.FormatInt(time.Now().UnixNano(), 10) + ".eml"
+	return os.WriteFile(filepath.Join(m.dir, name), buildMessage("bluebell@localhost", msg), 0o644)
+}
@@ -0,0 +1,55 @@
+package mailer
+
+import (
+	"blue-bell_back/settings"
+	"context"
+	"fmt"
+)
+
+// 邮件发送
+// 生产环境使用SMTP发送，本地开发使用log驱动把邮件写入文件或日志
+
+// Message 一封邮件
+type Message struct {
+	To      string
+	Subject string
+	Body    string // 纯文本正文
+}
+
+// Mailer 邮件发送接口
+type Mailer interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+const (
+	DriverSMTP = "smtp"
+	DriverLog  = "log"
+)
+
+// std 全局的邮件发送实例
+var std Mailer
+
+// Init 根据配置初始化邮件发送实例，未配置时使用log驱动
+func Init(cfg *settings.MailConfig) (err error) {
+	if cfg == nil {
+		std = NewLogMailer("")
+		return
+	}
+	switch cfg.Driver {
+	case DriverSMTP:
+		std = NewSMTPMailer(cfg.Host, cfg.Port, cfg.Username, cfg.Password, cfg.From)
+	case DriverLog, "":
+		std = NewLogMailer(cfg.Dir)
+	default:
+		err = fmt.Errorf("unsupported mail driver %q", cfg.Driver)
+	}
+	return
+}
+
+// Send 使用全局实例发送邮件
+func Send(ctx context.Context, msg *Message) error {
+	if std == nil {
+		return fmt.Errorf("mailer not initialized")
+	}
+	return std.Send(ctx, msg)
+}
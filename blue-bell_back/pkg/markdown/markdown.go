@@ -0,0 +1,635 @@
+package markdown
+
+import (
+	"html"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// 帖子内容的Markdown渲染
+// 支持标题、段落、引用、有序和无序列表、代码块、分割线，以及强调、删除线、行内代码、链接、图片和自动链接
+// 原始HTML一律转义，只输出渲染器自己生成的标签和属性：
+//
+//	p h1-h6 blockquote ul ol(start) li pre code(class) hr br strong em del a(href rel) img(src alt)
+//
+// 链接只允许http(s)、mailto和站内的相对地址，图片只允许http(s)，其他地址只输出文字，所以结果不需要再做过滤
+
+// Version 渲染规则的版本，修改渲染结果时需要增加，让按版本缓存的结果失效
+const Version = "1"
+
+// maxDepth 引用、列表和强调的最大嵌套层数，超过后按普通文字处理
+const maxDepth = 8
+
+// Render 把Markdown转换为安全的HTML
+func Render(src string) string {
+	r := new(renderer)
+	r.blocks(splitLines(src), 0)
+	return r.String()
+}
+
+// Excerpt 返回去掉Markdown格式的纯文本摘要，连续的空白合并为一个空格，超过n个字符时截断并加上省略号
+// 代码块不计入摘要
+func Excerpt(src string, n int) string {
+	r := &renderer{plain: true}
+	r.blocks(splitLines(src), 0)
+	text := strings.Join(strings.Fields(r.String()), " ")
+	if utf8.RuneCountInString(text) <= n {
+		return text
+	}
+	return strings.TrimSpace(string([]rune(text)[:n])) + "…"
+}
+
+// renderer plain为true时只输出文字，用于生成摘要
+type renderer struct {
+	strings.Builder
+	plain bool
+}
+
+// splitLines 统一换行符并把行首的制表符展开为4个空格
+func splitLines(src string) []string {
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	src = strings.ReplaceAll(src, "\r", "\n")
+	lines := strings.Split(src, "\n")
+	for i, line := range lines {
+		n := 0
+		for n < len(line) && (line[n] == ' ' || line[n] == '\t') {
+			n++
+		}
+		if strings.Contains(line[:n], "\t") {
+			lines[i] = strings.ReplaceAll(line[:n], "\t", "    ") + line[n:]
+		}
+	}
+	return lines
+}
+
+func indentOf(line string) int {
+	n := 0
+	for n < len(line) && line[n] == ' ' {
+		n++
+	}
+	return n
+}
+
+// text 输出普通文字
+func (r *renderer) text(s string) {
+	if r.plain {
+		r.WriteString(s)
+		return
+	}
+	r.WriteString(html.EscapeString(s))
+}
+
+// tag 输出标签，生成摘要时忽略
+func (r *renderer) tag(s string) {
+	if !r.plain {
+		r.WriteString(s)
+	}
+}
+
+// sep 生成摘要时在块之间加上换行，避免前后两个块的文字连在一起
+func (r *renderer) sep() {
+	if r.plain {
+		r.WriteByte('\n')
+	}
+}
+
+// ---------- 块级元素 ----------
+
+func (r *renderer) blocks(lines []string, depth int) {
+	for i := 0; i < len(lines); {
+		trimmed := strings.TrimSpace(lines[i])
+		switch {
+		case trimmed == "":
+			i++
+		case depth > maxDepth:
+			i = r.paragraph(lines, i)
+		case fenceOf(trimmed) != "":
+			i = r.codeBlock(lines, i)
+		case headingLevel(trimmed) > 0:
+			r.heading(trimmed)
+			i++
+		case isRule(trimmed):
+			r.tag("<hr>\n")
+			i++
+		case strings.HasPrefix(trimmed, ">"):
+			i = r.quote(lines, i, depth)
+		case isListItem(lines[i]):
+			i = r.list(lines, i, depth)
+		default:
+			i = r.paragraph(lines, i)
+		}
+	}
+}
+
+// startsBlock 判断一行是否开始新的块，用于结束段落
+func startsBlock(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return trimmed == "" || fenceOf(trimmed) != "" || headingLevel(trimmed) > 0 || isRule(trimmed) ||
+		strings.HasPrefix(trimmed, ">") || isListItem(line)
+}
+
+// paragraph 连续的文字行组成一个段落，行尾两个以上的空格表示换行
+func (r *renderer) paragraph(lines []string, i int) int {
+	start := i
+	for i++; i < len(lines) && !startsBlock(lines[i]); i++ {
+	}
+	r.tag("<p>")
+	r.inline(joinLines(lines[start:i]), 0, false)
+	r.tag("</p>\n")
+	r.sep()
+	return i
+}
+
+// joinLines 合并段落的行，需要换行的行尾改为反斜杠，由inline输出<br>
+func joinLines(lines []string) string {
+	var b strings.Builder
+	for i, line := range lines {
+		line = strings.TrimLeft(line, " ")
+		trimmed := strings.TrimRight(line, " ")
+		if i < len(lines)-1 && len(line)-len(trimmed) >= 2 && !strings.HasSuffix(trimmed, "\\") {
+			trimmed += "\\"
+		}
+		b.WriteString(trimmed)
+		if i < len(lines)-1 {
+			b.WriteByte('\n')
+		}
+	}
+	return b.String()
+}
+
+func headingLevel(trimmed string) int {
+	n := 0
+	for n < len(trimmed) && trimmed[n] == '#' {
+		n++
+	}
+	if n == 0 || n > 6 || (n < len(trimmed) && trimmed[n] != ' ') {
+		return 0
+	}
+	return n
+}
+
+func (r *renderer) heading(trimmed string) {
+	level := headingLevel(trimmed)
+	text := strings.TrimSpace(trimmed[level:])
+	// 去掉结尾可选的#
+	if t := strings.TrimRight(text, "#"); t == "" || strings.HasSuffix(t, " ") {
+		text = strings.TrimSpace(t)
+	}
+	h := strconv.Itoa(level)
+	r.tag("<h" + h + ">")
+	r.inline(text, 0, false)
+	r.tag("</h" + h + ">\n")
+	r.sep()
+}
+
+// isRule 判断是否为分割线：三个以上相同的-、*或_，中间可以有空格
+func isRule(trimmed string) bool {
+	if trimmed == "" || !strings.ContainsRune("-*_", rune(trimmed[0])) {
+		return false
+	}
+	n := 0
+	for i := 0; i < len(trimmed); i++ {
+		switch trimmed[i] {
+		case trimmed[0]:
+			n++
+		case ' ':
+		default:
+			return false
+		}
+	}
+	return n >= 3
+}
+
+// fenceOf 返回代码块的起始标记```或~~~，不是代码块时返回空字符串
+func fenceOf(trimmed string) string {
+	for _, f := range []string{"```", "~~~"} {
+		if strings.HasPrefix(trimmed, f) {
+			n := len(f)
+			for n < len(trimmed) && trimmed[n] == f[0] {
+				n++
+			}
+			return trimmed[:n]
+		}
+	}
+	return ""
+}
+
+// codeBlock 代码块的内容原样转义输出，语言名称只保留字母、数字和-_+
+func (r *renderer) codeBlock(lines []string, i int) int {
+	trimmed := strings.TrimSpace(lines[i])
+	fence := fenceOf(trimmed)
+	lang := ""
+	if fields := strings.Fields(trimmed[len(fence):]); len(fields) > 0 {
+		lang = strings.Map(func(c rune) rune {
+			if c < utf8.RuneSelf && (c == '-' || c == '_' || c == '+' || '0' <= c && c <= '9' ||
+				'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z') {
+				return c
+			}
+			return -1
+		}, fields[0])
+	}
+	var code []string
+	for i++; i < len(lines); i++ {
+		if t := strings.TrimSpace(lines[i]); strings.HasPrefix(t, fence) && strings.Trim(t, fence[:1]) == "" {
+			i++
+			break
+		}
+		code = append(code, lines[i])
+	}
+	if r.plain {
+		return i
+	}
+	if lang != "" {
+		r.WriteString(`<pre><code class="language-` + lang + `">`)
+	} else {
+		r.WriteString("<pre><code>")
+	}
+	for _, line := range code {
+		r.text(line)
+		r.WriteByte('\n')
+	}
+	r.WriteString("</code></pre>\n")
+	return i
+}
+
+// quote 连续的以>开头的行组成引用，去掉>之后按块级元素渲染
+func (r *renderer) quote(lines []string, i, depth int) int {
+	var inner []string
+	for ; i < len(lines); i++ {
+		t := strings.TrimLeft(lines[i], " ")
+		if !strings.HasPrefix(t, ">") {
+			break
+		}
+		t = t[1:]
+		if strings.HasPrefix(t, " ") {
+			t = t[1:]
+		}
+		inner = append(inner, t)
+	}
+	r.tag("<blockquote>\n")
+	r.blocks(inner, depth+1)
+	r.tag("</blockquote>\n")
+	return i
+}
+
+// listItem 解析列表项的标记，返回是否为有序列表、序号和内容开始的位置
+func listItem(line string) (ordered bool, num, offset int, ok bool) {
+	indent := indentOf(line)
+	if indent > 1 || indent >= len(line) {
+		return
+	}
+	rest := line[indent:]
+	if strings.ContainsRune("-*+", rune(rest[0])) {
+		if len(rest) > 1 && rest[1] == ' ' {
+			return false, 0, indent + 2, true
+		}
+		return
+	}
+	n := 0
+	for n < len(rest) && n < 9 && '0' <= rest[n] && rest[n] <= '9' {
+		n++
+	}
+	if n == 0 || n+1 >= len(rest) || (rest[n] != '.' && rest[n] != ')') || rest[n+1] != ' ' {
+		return
+	}
+	num, _ = strconv.Atoi(rest[:n])
+	return true, num, indent + n + 2, true
+}
+
+func isListItem(line string) bool {
+	_, _, _, ok := listItem(line)
+	return ok
+}
+
+// list 连续的同类列表项组成列表
+// 缩进两个以上空格的行属于上一个列表项，可以包含嵌套的列表、引用和代码块
+func (r *renderer) list(lines []string, i, depth int) int {
+	ordered, num, _, _ := listItem(lines[i])
+	if ordered {
+		if num != 1 {
+			r.tag(`<ol start="` + strconv.Itoa(num) + `">` + "\n")
+		} else {
+			r.tag("<ol>\n")
+		}
+	} else {
+		r.tag("<ul>\n")
+	}
+	for i < len(lines) {
+		o, _, offset, ok := listItem(lines[i])
+		if !ok || o != ordered || indentOf(lines[i]) >= 2 {
+			break
+		}
+		item := []string{lines[i][offset:]}
+		for i++; i < len(lines); i++ {
+			line := lines[i]
+			if strings.TrimSpace(line) == "" {
+				// 空行之后还有属于该列表项的行时保留空行
+				j := i
+				for j < len(lines) && strings.TrimSpace(lines[j]) == "" {
+					j++
+				}
+				if j < len(lines) && indentOf(lines[j]) >= 2 {
+					item = append(item, "")
+					continue
+				}
+				break
+			}
+			indent := indentOf(line)
+			if indent < 2 {
+				break
+			}
+			item = append(item, line[min(indent, offset):])
+		}
+		r.item(item, depth)
+		// 同一个列表中的项之间可以有空行
+		j := i
+		for j < len(lines) && strings.TrimSpace(lines[j]) == "" {
+			j++
+		}
+		if j < len(lines) && j > i {
+			if o, _, _, ok := listItem(lines[j]); ok && o == ordered && indentOf(lines[j]) < 2 {
+				i = j
+			}
+		}
+	}
+	if ordered {
+		r.tag("</ol>\n")
+	} else {
+		r.tag("</ul>\n")
+	}
+	return i
+}
+
+// item 列表项开头的文字直接输出，之后的内容按块级元素渲染
+func (r *renderer) item(lines []string, depth int) {
+	n := 1
+	for n < len(lines) && !startsBlock(lines[n]) {
+		n++
+	}
+	r.tag("<li>")
+	r.inline(joinLines(lines[:n]), 0, false)
+	if n < len(lines) {
+		r.tag("\n")
+		r.sep()
+		r.blocks(lines[n:], depth+1)
+	}
+	r.tag("</li>\n")
+	r.sep()
+}
+
+// ---------- 行内元素 ----------
+
+// inlineSpecial 行内可能开始格式的字符
+const inlineSpecial = "\\`*_~![<"
+
+// inline 渲染行内元素，inLink为true时不再解析链接
+func (r *renderer) inline(s string, depth int, inLink bool) {
+	for i := 0; i < len(s); {
+		switch c := s[i]; c {
+		case '\\':
+			if i+1 < len(s) && s[i+1] == '\n' {
+				r.tag("<br>")
+				r.WriteByte('\n')
+				i += 2
+				continue
+			}
+			if i+1 < len(s) && isPunct(s[i+1]) {
+				r.text(s[i+1 : i+2])
+				i += 2
+				continue
+			}
+		case '`':
+			n, ok := r.codeSpan(s[i:])
+			if !ok {
+				r.text(s[i : i+n])
+			}
+			i += n
+			continue
+		case '*', '_', '~':
+			n, ok := r.emphasis(s, i, depth, inLink)
+			if !ok {
+				r.text(s[i : i+n])
+			}
+			i += n
+			continue
+		case '!':
+			if !inLink && i+1 < len(s) && s[i+1] == '[' {
+				if n, ok := r.link(s[i+1:], true, depth); ok {
+					i += n + 1
+					continue
+				}
+			}
+		case '[':
+			if !inLink {
+				if n, ok := r.link(s[i:], false, depth); ok {
+					i += n
+					continue
+				}
+			}
+		case '<':
+			if !inLink {
+				if n, ok := r.autolink(s[i:]); ok {
+					i += n
+					continue
+				}
+			}
+		}
+		j := i + 1
+		for j < len(s) && !strings.ContainsRune(inlineSpecial, rune(s[j])) {
+			j++
+		}
+		r.text(s[i:j])
+		i = j
+	}
+}
+
+func isPunct(c byte) bool {
+	return c < utf8.RuneSelf && strings.ContainsRune("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", rune(c))
+}
+
+func isAlnum(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+// runLen 返回从i开始连续的c的个数
+func runLen(s string, i int, c byte) int {
+	n := 0
+	for i+n < len(s) && s[i+n] == c {
+		n++
+	}
+	return n
+}
+
+// codeSpan 解析行内代码，返回处理的长度；没有结束标记时ok为false，长度为起始的`的个数
+func (r *renderer) codeSpan(s string) (n int, ok bool) {
+	open := runLen(s, 0, '`')
+	for j := open; j < len(s); {
+		if s[j] != '`' {
+			j++
+			continue
+		}
+		k := runLen(s, j, '`')
+		if k == open {
+			code := s[open:j]
+			if len(code) > 2 && code[0] == ' ' && code[len(code)-1] == ' ' {
+				code = code[1 : len(code)-1]
+			}
+			r.tag("<code>")
+			r.text(strings.ReplaceAll(code, "\n", " "))
+			r.tag("</code>")
+			return j + k, true
+		}
+		j += k
+	}
+	return open, false
+}
+
+// emphasis 解析强调和删除线：*、_为<em>，**、__为<strong>，~~为<del>
+// 返回处理的长度；不能组成格式时ok为false，长度为起始标记的长度
+func (r *renderer) emphasis(s string, i, depth int, inLink bool) (n int, ok bool) {
+	c := s[i]
+	run := runLen(s, i, c)
+	if depth >= maxDepth || (c == '~' && run < 2) {
+		return run, false
+	}
+	k := min(run, 2)
+	start := i + k
+	// 起始标记后面不能是空白，_不能在单词中间
+	if start >= len(s) || isSpace(s[start]) || (c == '_' && i > 0 && isAlnum(s[i-1])) {
+		return run, false
+	}
+	for j := start + 1; j < len(s); {
+		if s[j] != c {
+			j++
+			continue
+		}
+		m := runLen(s, j, c)
+		end := j + m
+		if (m == k || m >= 3) && !isSpace(s[j-1]) && !(c == '_' && end < len(s) && isAlnum(s[end])) {
+			inner := s[start : end-k]
+			tag := "em"
+			if c == '~' {
+				tag = "del"
+			} else if k == 2 {
+				tag = "strong"
+			}
+			r.tag("<" + tag + ">")
+			r.inline(inner, depth+1, inLink)
+			r.tag("</" + tag + ">")
+			return end - i, true
+		}
+		j = end
+	}
+	return run, false
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\t'
+}
+
+// link 解析[文字](地址)和![描述](地址)，s以[开头
+// 地址后面可以有标题，标题不输出；地址不安全时只输出文字
+func (r *renderer) link(s string, image bool, depth int) (n int, ok bool) {
+	// 找到匹配的]
+	level := 0
+	closeAt := -1
+	for j := 0; j < len(s) && closeAt < 0; j++ {
+		switch s[j] {
+		case '\\':
+			j++
+		case '[':
+			level++
+		case ']':
+			level--
+			if level == 0 {
+				closeAt = j
+			}
+		}
+	}
+	if closeAt < 0 || closeAt+1 >= len(s) || s[closeAt+1] != '(' {
+		return 0, false
+	}
+	// 找到匹配的)，地址中可以有成对的括号
+	end := -1
+	level = 1
+	for j := closeAt + 2; j < len(s) && end < 0; j++ {
+		switch s[j] {
+		case '(':
+			level++
+		case ')':
+			level--
+			if level == 0 {
+				end = j
+			}
+		}
+	}
+	if end < 0 {
+		return 0, false
+	}
+	label := s[1:closeAt]
+	dest := ""
+	if fields := strings.Fields(s[closeAt+2 : end]); len(fields) > 0 {
+		dest = strings.Trim(fields[0], "<>")
+	}
+
+	if image {
+		alt := &renderer{plain: true}
+		alt.inline(label, depth+1, true)
+		if r.plain {
+			r.WriteString(alt.String())
+		} else if safeURL(dest, true) {
+			r.WriteString(`<img src="` + html.EscapeString(dest) + `" alt="` + html.EscapeString(alt.String()) + `">`)
+		} else {
+			r.text(alt.String())
+		}
+		return end + 1, true
+	}
+	if safeURL(dest, false) {
+		r.tag(`<a href="` + html.EscapeString(dest) + `" rel="nofollow noopener noreferrer">`)
+		r.inline(label, depth+1, true)
+		r.tag("</a>")
+	} else {
+		r.inline(label, depth+1, true)
+	}
+	return end + 1, true
+}
+
+// autolink 解析<http://...>形式的链接
+func (r *renderer) autolink(s string) (n int, ok bool) {
+	end := strings.IndexByte(s, '>')
+	if end < 0 {
+		return 0, false
+	}
+	dest := s[1:end]
+	if strings.ContainsAny(dest, " \n<") || !safeURL(dest, true) {
+		return 0, false
+	}
+	r.tag(`<a href="` + html.EscapeString(dest) + `" rel="nofollow noopener noreferrer">`)
+	r.text(dest)
+	r.tag("</a>")
+	return end + 1, true
+}
+
+// safeURL 只允许http(s)地址，非图片的链接还允许mailto和站内的相对地址
+func safeURL(raw string, onlyHTTP bool) bool {
+	// 浏览器会把\当作/，/\开头的地址同样指向其他网站
+	if raw == "" || strings.ContainsAny(raw, "\x00\n\r\t\\") {
+		return false
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https":
+		return u.Host != ""
+	case "mailto":
+		return !onlyHTTP
+	case "":
+		// 站内地址，//开头的是其他网站
+		return !onlyHTTP && (strings.HasPrefix(raw, "/") && !strings.HasPrefix(raw, "//") || strings.HasPrefix(raw, "#"))
+	}
+	return false
+}
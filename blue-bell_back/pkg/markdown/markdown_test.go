@@ -0,0 +1,168 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"paragraph", "hello\nworld", "<p>hello\nworld</p>\n"},
+		{"hard break", "a  \nb", "<p>a<br>\nb</p>\n"},
+		{"heading", "## Title ##", "<h2>Title</h2>\n"},
+		{"not heading", "#Title", "<p>#Title</p>\n"},
+		{"rule", "- - -", "<hr>\n"},
+		{"emphasis", "*a* **b** _c_ ~~d~~", "<p><em>a</em> <strong>b</strong> <em>c</em> <del>d</del></p>\n"},
+		{"underscore in word", "snake_case_name", "<p>snake_case_name</p>\n"},
+		{"code span", "`a < b`", "<p><code>a &lt; b</code></p>\n"},
+		{"escape", `\*not em\*`, "<p>*not em*</p>\n"},
+		{"code block", "```go\nx := \"<b>\"\n```", "<pre><code class=\"language-go\">x := &#34;&lt;b&gt;&#34;\n</code></pre>\n"},
+		{"code block lang filtered", "```\"><script>\nx\n```", "<pre><code class=\"language-script\">x\n</code></pre>\n"},
+		{"quote", "> a\n> b", "<blockquote>\n<p>a\nb</p>\n</blockquote>\n"},
+		{"unordered list", "- a\n- b", "<ul>\n<li>a</li>\n<li>b</li>\n</ul>\n"},
+		{"ordered list start", "3. a\n4. b", "<ol start=\"3\">\n<li>a</li>\n<li>b</li>\n</ol>\n"},
+		{"nested list", "- a\n  - b", "<ul>\n<li>a\n<ul>\n<li>b</li>\n</ul>\n</li>\n</ul>\n"},
+		{"link", "[x](https://example.com)", `<p><a href="https://example.com" rel="nofollow noopener noreferrer">x</a></p>` + "\n"},
+		{"link with parens", "[wiki](https://en.wikipedia.org/wiki/Go_(language))",
+			`<p><a href="https://en.wikipedia.org/wiki/Go_(language)" rel="nofollow noopener noreferrer">wiki</a></p>` + "\n"},
+		{"link title dropped", `[x](/post/1 "title")`, `<p><a href="/post/1" rel="nofollow noopener noreferrer">x</a></p>` + "\n"},
+		{"image", "![a *b*](https://example.com/a.png)", `<p><img src="https://example.com/a.png" alt="a b"></p>` + "\n"},
+		{"autolink", "<https://example.com/?a=1&b=2>",
+			`<p><a href="https://example.com/?a=1&amp;b=2" rel="nofollow noopener noreferrer">https://example.com/?a=1&amp;b=2</a></p>` + "\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Render(tt.src); got != tt.want {
+				t.Errorf("Render(%q)\n got %q\nwant %q", tt.src, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderEscapesHTML(t *testing.T) {
+	tests := []string{
+		"<script>alert(1)</script>",
+		"<img src=x onerror=alert(1)>",
+		"**<b>bold</b>**",
+		"> <iframe src=\"https://evil.example\">",
+		"- <svg onload=alert(1)>",
+		"[<script>](https://example.com)",
+		"![\"onerror=\"alert(1)](https://example.com/a.png)",
+		"[x](https://example.com/\"onmouseover=\"alert(1))",
+	}
+	for _, src := range tests {
+		got := Render(src)
+		for _, bad := range []string{"<script", "<img src=x", "<b>", "<iframe", "<svg", `" onerror`, `"onerror`, `"onmouseover`} {
+			if strings.Contains(got, bad) {
+				t.Errorf("Render(%q) = %q, contains %q", src, got, bad)
+			}
+		}
+	}
+}
+
+func TestRenderUnsafeURL(t *testing.T) {
+	tests := []string{
+		"[x](javascript:alert(1))",
+		"[x](JavaScript:alert(1))",
+		"[x](vbscript:msgbox(1))",
+		"[x](data:text/html;base64,PHNjcmlwdD4=)",
+		"[x](//evil.example)",
+		`[x](/\evil.example)`,
+		"[x](evil.example)",
+		"[x](https://)",
+		"<javascript:alert(1)>",
+		"<mailto:a@example.com>",
+		"![x](javascript:alert(1))",
+		"![x](data:image/png;base64,AAAA)",
+		"![x](/local.png)",
+	}
+	for _, src := range tests {
+		got := Render(src)
+		if strings.Contains(got, "<a ") || strings.Contains(got, "<img") {
+			t.Errorf("Render(%q) = %q, want no link or image", src, got)
+		}
+	}
+}
+
+func TestSafeURL(t *testing.T) {
+	tests := []struct {
+		raw      string
+		onlyHTTP bool
+		want     bool
+	}{
+		{"http://example.com", false, true},
+		{"HTTPS://example.com/a?b=c", true, true},
+		{"mailto:a@example.com", false, true},
+		{"mailto:a@example.com", true, false},
+		{"/post/1", false, true},
+		{"/post/1", true, false},
+		{"#comments", false, true},
+		{"//evil.example", false, false},
+		{`/\evil.example`, false, false},
+		{"javascript:alert(1)", false, false},
+		{"java\tscript:alert(1)", false, false},
+		{"data:text/html,x", false, false},
+		{"relative/path", false, false},
+		{"", false, false},
+	}
+	for _, tt := range tests {
+		if got := safeURL(tt.raw, tt.onlyHTTP); got != tt.want {
+			t.Errorf("safeURL(%q, %v) = %v, want %v", tt.raw, tt.onlyHTTP, got, tt.want)
+		}
+	}
+}
+
+func TestRenderTerminates(t *testing.T) {
+	// 缩进不足以属于列表项、也不能开始新列表的行不能让列表解析停止前进
+	tests := []string{
+		"- a\n -b",
+		"- a\n  \n -",
+		"1. a\n 1.",
+		" - a\n  - b\n - c",
+		strings.Repeat("- ", 1000) + "a",
+		strings.Repeat(">", 1000) + "a",
+		strings.Repeat("*", 1000) + "a",
+		strings.Repeat("[", 1000) + "a",
+		strings.Repeat("`", 1000),
+	}
+	for _, src := range tests {
+		done := make(chan struct{})
+		go func() {
+			Render(src)
+			Excerpt(src, 100)
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Render(%.40q) did not finish", src)
+		}
+	}
+}
+
+func TestExcerpt(t *testing.T) {
+	tests := []struct {
+		src  string
+		n    int
+		want string
+	}{
+		{"# Title\n\nSome **bold** and [link](https://example.com).", 100, "Title Some bold and link."},
+		{"intro\n\n```\ncode is skipped\n```\n\nend", 100, "intro end"},
+		{"- one\n- two\n  - three", 100, "one two three"},
+		{"> quoted <b>", 100, "quoted <b>"},
+		{"![alt text](https://example.com/a.png)", 100, "alt text"},
+		{"你好世界，欢迎", 4, "你好世界…"},
+		{"hello world", 6, "hello…"},
+		{"short", 5, "short"},
+	}
+	for _, tt := range tests {
+		if got := Excerpt(tt.src, tt.n); got != tt.want {
+			t.Errorf("Excerpt(%q, %d) = %q, want %q", tt.src, tt.n, got, tt.want)
+		}
+	}
+}
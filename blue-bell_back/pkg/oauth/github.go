@@ -0,0 +1,68 @@
+package oauth
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+)
+
+// GitHub的OAuth应用不支持OIDC，通过REST API获取用户信息
+const (
+	githubAuthURL   = "https://github.com/login/oauth/authorize"
+	githubTokenURL  = "https://github.com/login/oauth/access_token"
+	githubUserURL   = "https://api.github.com/user"
+	githubEmailsURL = "https://api.github.com/user/emails"
+)
+
+type githubProvider struct {
+	cfg *Config
+}
+
+func newGitHubProvider(cfg *Config) *githubProvider {
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"read:user", "user:email"}
+	}
+	return &githubProvider{cfg: cfg}
+}
+
+func (p *githubProvider) AuthCodeURL(ctx context.Context, req *AuthRequest) (string, error) {
+	return authCodeURL(githubAuthURL, p.cfg, req, nil)
+}
+
+func (p *githubProvider) Exchange(ctx context.Context, code string, req *AuthRequest) (*Identity, error) {
+	tr, err := exchangeCode(ctx, githubTokenURL, p.cfg, code, req)
+	if err != nil {
+		return nil, err
+	}
+	var user struct {
+		ID    int64  `json:"id"`
+		Login string `json:"login"`
+	}
+	if err := getJSON(ctx, githubUserURL, tr.AccessToken, &user); err != nil {
+		return nil, err
+	}
+	if user.ID == 0 {
+		return nil, fmt.Errorf("oauth: github user has no id")
+	}
+	id := &Identity{
+		Subject:  strconv.FormatInt(user.ID, 10),
+		Username: user.Login,
+	}
+
+	// 只使用主邮箱，并以GitHub的验证状态为准
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if err := getJSON(ctx, githubEmailsURL, tr.AccessToken, &emails); err == nil {
+		for _, e := range emails {
+			if e.Primary {
+				id.Email = e.Email
+				id.EmailVerified = e.Verified
+				break
+			}
+		}
+	}
+	return id, nil
+}
@@ -0,0 +1,32 @@
+package oauth
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// maxResponseSize 提供方响应的最大长度
+const maxResponseSize = 1 << 20
+
+// doJSON 发送请求并把JSON响应解析到v中
+// 令牌端点出错时也会返回JSON格式的error字段，所以4xx的响应同样尝试解析
+func doJSON(r *http.Request, v interface{}) error {
+	resp, err := httpClient.Do(r)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode/100 != 2 && resp.StatusCode != http.StatusBadRequest && resp.StatusCode != http.StatusUnauthorized {
+		return fmt.Errorf("oauth: %s %s: unexpected status %s", r.Method, r.URL.Redacted(), resp.Status)
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("oauth: %s %s: %w", r.Method, r.URL.Redacted(), err)
+	}
+	return nil
+}
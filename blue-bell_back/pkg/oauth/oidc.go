@@ -0,0 +1,289 @@
+package oauth
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+const (
+	// metadataTTL 发现文档和公钥的缓存时间
+	metadataTTL = time.Hour
+	// jwksRefetchInterval 遇到未知kid时重新获取JWKS的最小间隔，防止伪造kid的请求频繁访问提供方
+	jwksRefetchInterval = time.Minute
+)
+
+// oidcMetadata /.well-known/openid-configuration 中用到的字段
+type oidcMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// oidcProvider 标准OIDC提供方，通过ID Token获取用户信息
+type oidcProvider struct {
+	cfg *Config
+
+	mu            sync.Mutex
+	meta          *oidcMetadata
+	keys          map[string]*rsa.PublicKey
+	fetchedAt     time.Time
+	keysFetchedAt time.Time     // 上次获取JWKS的时间
+	keysFetching  chan struct{} // 正在获取JWKS时不为nil，获取完成后关闭
+}
+
+func newOIDCProvider(cfg *Config) *oidcProvider {
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "profile", "email"}
+	}
+	return &oidcProvider{cfg: cfg}
+}
+
+// metadata 返回发现文档，过期后重新获取
+func (p *oidcProvider) metadata(ctx context.Context) (*oidcMetadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.meta != nil && time.Since(p.fetchedAt) < metadataTTL {
+		return p.meta, nil
+	}
+	meta := new(oidcMetadata)
+	endpoint := strings.TrimRight(p.cfg.Issuer, "/") + "/.well-known/openid-configuration"
+	if err := getJSON(ctx, endpoint, "", meta); err != nil {
+		return nil, err
+	}
+	if meta.Issuer != p.cfg.Issuer {
+		return nil, fmt.Errorf("oauth: issuer mismatch, expected %q got %q", p.cfg.Issuer, meta.Issuer)
+	}
+	p.meta = meta
+	p.keys = nil
+	p.keysFetchedAt = time.Time{}
+	p.fetchedAt = time.Now()
+	return meta, nil
+}
+
+// publicKey 根据kid返回签名公钥，找不到时重新获取JWKS（提供方可能轮换了密钥）
+// 每jwksRefetchInterval最多获取一次，获取时不持有锁，同时到达的请求等待同一次获取的结果
+func (p *oidcProvider) publicKey(ctx context.Context, meta *oidcMetadata, kid string) (*rsa.PublicKey, error) {
+	p.mu.Lock()
+	if key, ok := p.keys[kid]; ok {
+		p.mu.Unlock()
+		return key, nil
+	}
+	wait := p.keysFetching
+	if wait == nil {
+		if !p.keysFetchedAt.IsZero() && time.Since(p.keysFetchedAt) < jwksRefetchInterval {
+			p.mu.Unlock()
+			return nil, fmt.Errorf("%w: unknown kid %q", ErrInvalidIDToken, kid)
+		}
+		wait = make(chan struct{})
+		p.keysFetching = wait
+		p.keysFetchedAt = time.Now()
+		p.mu.Unlock()
+
+		// 其他请求也在等待这次获取，不受当前请求取消的影响
+		keys, err := fetchJWKS(context.WithoutCancel(ctx), meta.JWKSURI)
+		p.mu.Lock()
+		if err == nil {
+			p.keys = keys
+		}
+		p.keysFetching = nil
+		close(wait)
+		p.mu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		p.mu.Unlock()
+		select {
+		case <-wait:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	p.mu.Lock()
+	key, ok := p.keys[kid]
+	p.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown kid %q", ErrInvalidIDToken, kid)
+	}
+	return key, nil
+}
+
+// fetchJWKS 获取提供方的签名公钥
+func fetchJWKS(ctx context.Context, jwksURI string) (map[string]*rsa.PublicKey, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := getJSON(ctx, jwksURI, "", &set); err != nil {
+		return nil, err
+	}
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		key, err := parseRSAKey(k)
+		if err != nil {
+			return nil, err
+		}
+		keys[k.Kid] = key
+	}
+	return keys, nil
+}
+
+func parseRSAKey(k jwk) (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, fmt.Errorf("oauth: invalid jwk %q: %w", k.Kid, err)
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, fmt.Errorf("oauth: invalid jwk %q: %w", k.Kid, err)
+	}
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(n),
+		E: int(new(big.Int).SetBytes(e).Int64()),
+	}, nil
+}
+
+func (p *oidcProvider) AuthCodeURL(ctx context.Context, req *AuthRequest) (string, error) {
+	meta, err := p.metadata(ctx)
+	if err != nil {
+		return "", err
+	}
+	extra := url.Values{"nonce": {req.Nonce}}
+	if req.Reauth {
+		extra.Set("prompt", "login")
+		extra.Set("max_age", strconv.Itoa(int(ReauthMaxAge.Seconds())))
+	}
+	return authCodeURL(meta.AuthorizationEndpoint, p.cfg, req, extra)
+}
+
+func (p *oidcProvider) Exchange(ctx context.Context, code string, req *AuthRequest) (*Identity, error) {
+	meta, err := p.metadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tr, err := exchangeCode(ctx, meta.TokenEndpoint, p.cfg, code, req)
+	if err != nil {
+		return nil, err
+	}
+	if tr.IDToken == "" {
+		return nil, fmt.Errorf("%w: missing id_token", ErrInvalidIDToken)
+	}
+	claims, err := p.verifyIDToken(ctx, meta, tr.IDToken, req.Nonce)
+	if err != nil {
+		return nil, err
+	}
+	if req.Reauth {
+		if err := checkAuthTime(claims, time.Now()); err != nil {
+			return nil, err
+		}
+	}
+
+	id := &Identity{Subject: claimString(claims, "sub")}
+	id.Username = claimString(claims, "preferred_username")
+	id.Email = claimString(claims, "email")
+	id.EmailVerified, _ = claims["email_verified"].(bool)
+	// 部分提供方的ID Token里没有邮箱，再从userinfo接口补充
+	if id.Email == "" && meta.UserinfoEndpoint != "" {
+		var info map[string]interface{}
+		if err := getJSON(ctx, meta.UserinfoEndpoint, tr.AccessToken, &info); err == nil &&
+			claimString(info, "sub") == id.Subject {
+			id.Email = claimString(info, "email")
+			id.EmailVerified, _ = info["email_verified"].(bool)
+			if id.Username == "" {
+				id.Username = claimString(info, "preferred_username")
+			}
+		}
+	}
+	if id.Username == "" {
+		id.Username = claimString(claims, "name")
+	}
+	return id, nil
+}
+
+// verifyIDToken 校验ID Token的签名、issuer、audience、有效期和nonce
+func (p *oidcProvider) verifyIDToken(ctx context.Context, meta *oidcMetadata, raw, nonce string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(raw, claims, func(t *jwt.Token) (interface{}, error) {
+		if t.Method.Alg() != jwt.SigningMethodRS256.Alg() {
+			return nil, fmt.Errorf("unexpected signing method %s", t.Method.Alg())
+		}
+		kid, _ := t.Header["kid"].(string)
+		return p.publicKey(ctx, meta, kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+	if claimString(claims, "iss") != meta.Issuer {
+		return nil, fmt.Errorf("%w: issuer mismatch", ErrInvalidIDToken)
+	}
+	if !audienceContains(claims["aud"], p.cfg.ClientID) {
+		return nil, fmt.Errorf("%w: audience mismatch", ErrInvalidIDToken)
+	}
+	if _, ok := claims["exp"]; !ok {
+		return nil, fmt.Errorf("%w: missing exp", ErrInvalidIDToken)
+	}
+	if claimString(claims, "nonce") != nonce {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+	}
+	if claimString(claims, "sub") == "" {
+		return nil, fmt.Errorf("%w: missing sub", ErrInvalidIDToken)
+	}
+	return claims, nil
+}
+
+// checkAuthTime 检查用户在提供方登录的时间，超过ReauthMaxAge说明没有重新登录
+func checkAuthTime(claims jwt.MapClaims, now time.Time) error {
+	authTime, ok := claims["auth_time"].(float64)
+	if !ok {
+		return fmt.Errorf("%w: missing auth_time", ErrInvalidIDToken)
+	}
+	// 允许一分钟的时钟偏差
+	if now.Sub(time.Unix(int64(authTime), 0)) > ReauthMaxAge+time.Minute {
+		return fmt.Errorf("%w: auth_time too old", ErrInvalidIDToken)
+	}
+	return nil
+}
+
+// audienceContains aud可以是字符串或字符串数组
+func audienceContains(aud interface{}, clientID string) bool {
+	switch v := aud.(type) {
+	case string:
+		return v == clientID
+	case []interface{}:
+		for _, a := range v {
+			if s, ok := a.(string); ok && s == clientID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func claimString(claims map[string]interface{}, key string) string {
+	s, _ := claims[key].(string)
+	return s
+}
@@ -0,0 +1,48 @@
+package openapi
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+)
+
+// Handler 返回以JSON格式输出文档的http.Handler，文档在创建时序列化一次
+func Handler(doc *Document) (http.Handler, error) {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		_, _ = w.Write(data)
+	}), nil
+}
+
+// uiTemplate Swagger UI页面，静态资源从CDN加载
+var uiTemplate = template.Must(template.New("swagger").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js" crossorigin></script>
+<script>
+window.onload = function () {
+	window.ui = SwaggerUIBundle({url: {{.SpecURL}}, dom_id: "#swagger-ui", persistAuthorization: true});
+};
+</script>
+</body>
+</html>
+`))
+
+// UIHandler 返回Swagger UI页面，specURL为文档的地址
+func UIHandler(title, specURL string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_ = uiTemplate.Execute(w, struct{ Title, SpecURL string }{title, specURL})
+	})
+}
@@ -0,0 +1,170 @@
+package openapi
+
+import (
+	"sort"
+	"strings"
+)
+
+// 生成OpenAPI 3.0文档
+// 请求参数和响应的结构由Go类型通过反射生成，json、form和binding标签分别对应字段名、查询参数名和校验规则
+// 参考 https://spec.openapis.org/oas/v3.0.3
+
+// Version 生成的文档使用的OpenAPI版本
+const Version = "3.0.3"
+
+// Document OpenAPI文档
+type Document struct {
+	OpenAPI    string                `json:"openapi"`
+	Info       Info                  `json:"info"`
+	Paths      map[string]PathItem   `json:"paths"`
+	Components Components            `json:"components"`
+	Tags       []*Tag                `json:"tags,omitempty"`
+	Security   []map[string][]string `json:"security,omitempty"`
+}
+
+// Info 文档的基本信息
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// Tag 接口分组
+type Tag struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem 一个路径下的各个接口，键为小写的HTTP方法
+type PathItem map[string]*Operation
+
+// Operation 一个接口
+type Operation struct {
+	Tags        []string              `json:"tags,omitempty"`
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	OperationID string                `json:"operationId,omitempty"`
+	Parameters  []*Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+// Parameter 路径、查询或请求头参数
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody 请求体
+type RequestBody struct {
+	Required bool                  `json:"required,omitempty"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+// Response 响应
+type Response struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+// MediaType 请求体或响应的内容
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Components 可复用的定义
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas,omitempty"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+// SecurityScheme 认证方式
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+// Builder 逐个添加接口生成文档
+type Builder struct {
+	doc   *Document
+	types map[typeKey]string // 已经生成过的具名类型对应的组件名
+}
+
+// NewBuilder 创建文档生成器
+func NewBuilder(info Info) *Builder {
+	return &Builder{
+		doc: &Document{
+			OpenAPI: Version,
+			Info:    info,
+			Paths:   make(map[string]PathItem),
+			Components: Components{
+				Schemas:         make(map[string]*Schema),
+				SecuritySchemes: make(map[string]*SecurityScheme),
+			},
+		},
+		types: make(map[typeKey]string),
+	}
+}
+
+// AddTag 添加接口分组的说明
+func (b *Builder) AddTag(name, description string) {
+	b.doc.Tags = append(b.doc.Tags, &Tag{Name: name, Description: description})
+}
+
+// AddSecurityScheme 添加认证方式
+func (b *Builder) AddSecurityScheme(name string, s *SecurityScheme) {
+	b.doc.Components.SecuritySchemes[name] = s
+}
+
+// AddSchema 以name注册一个组件，返回引用它的Schema
+func (b *Builder) AddSchema(name string, s *Schema) *Schema {
+	b.doc.Components.Schemas[name] = s
+	return Ref(name)
+}
+
+// AddOperation 添加一个接口
+// path 使用gin的路由格式，:name 形式的路径参数会转换为 {name} 并自动添加到参数列表
+func (b *Builder) AddOperation(method, path string, op *Operation) {
+	oasPath, names := convertPath(path)
+	params := make([]*Parameter, 0, len(names)+len(op.Parameters))
+	for _, name := range names {
+		params = append(params, &Parameter{Name: name, In: "path", Required: true, Schema: &Schema{Type: "string"}})
+	}
+	op.Parameters = append(params, op.Parameters...)
+	if op.Responses == nil {
+		op.Responses = make(map[string]*Response)
+	}
+
+	item, ok := b.doc.Paths[oasPath]
+	if !ok {
+		item = make(PathItem)
+		b.doc.Paths[oasPath] = item
+	}
+	item[strings.ToLower(method)] = op
+}
+
+// Document 返回生成的文档
+func (b *Builder) Document() *Document {
+	sort.Slice(b.doc.Tags, func(i, j int) bool { return b.doc.Tags[i].Name < b.doc.Tags[j].Name })
+	return b.doc
+}
+
+// convertPath 把 /community/:id 转换为 /community/{id}，同时返回路径参数名
+// *name 形式的通配参数同样处理
+func convertPath(path string) (string, []string) {
+	segments := strings.Split(path, "/")
+	var names []string
+	for i, seg := range segments {
+		if len(seg) > 1 && (seg[0] == ':' || seg[0] == '*') {
+			names = append(names, seg[1:])
+			segments[i] = "{" + seg[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/"), names
+}
@@ -0,0 +1,286 @@
+package openapi
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schema 数据结构的定义
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	AllOf                []*Schema          `json:"allOf,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Default              interface{}        `json:"default,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinLength            *int64             `json:"minLength,omitempty"`
+	MaxLength            *int64             `json:"maxLength,omitempty"`
+}
+
+// Ref 返回引用组件name的Schema
+func Ref(name string) *Schema {
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// typeKey 区分不同包中的同名类型
+type typeKey struct {
+	pkg  string
+	name string
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// Schema 根据v的类型生成Schema，v为nil时返回nil
+// 具名的结构体注册为组件并返回引用，组件名为类型名
+func (b *Builder) Schema(v interface{}) *Schema {
+	if v == nil {
+		return nil
+	}
+	return b.schemaOf(reflect.TypeOf(v))
+}
+
+func (b *Builder) schemaOf(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: b.schemaOf(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: b.schemaOf(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.structSchema(t)
+		}
+		return b.namedSchema(t)
+	}
+	// interface{} 等任意类型
+	return &Schema{}
+}
+
+// namedSchema 把具名结构体注册为组件
+func (b *Builder) namedSchema(t reflect.Type) *Schema {
+	key := typeKey{pkg: t.PkgPath(), name: t.Name()}
+	if name, ok := b.types[key]; ok {
+		return Ref(name)
+	}
+	name := t.Name()
+	if _, taken := b.doc.Components.Schemas[name]; taken {
+		name = pkgName(t.PkgPath()) + "." + name
+	}
+	b.types[key] = name
+	// 先占位，处理引用自身的结构体
+	b.doc.Components.Schemas[name] = &Schema{Type: "object"}
+	b.doc.Components.Schemas[name] = b.structSchema(t)
+	return Ref(name)
+}
+
+// structSchema 按照json标签生成结构体的Schema，匿名嵌入且没有json名称的结构体字段会被展开
+func (b *Builder) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	b.addFields(s, t)
+	return s
+}
+
+func (b *Builder) addFields(s *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, opts := parseTag(f.Tag.Get("json"))
+		if name == "-" {
+			continue
+		}
+		if f.Anonymous && name == "" {
+			if ft := indirect(f.Type); ft.Kind() == reflect.Struct {
+				b.addFields(s, ft)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		var fs *Schema
+		if opts.contains("string") {
+			// json:",string" 的数值以字符串形式传输
+			fs = &Schema{Type: "string"}
+		} else {
+			fs = b.schemaOf(f.Type)
+		}
+		if applyBinding(fs, f.Tag.Get("binding")) {
+			s.Required = append(s.Required, name)
+		}
+		s.Properties[name] = fs
+	}
+}
+
+// QueryParams 按照form标签生成查询参数，default标签作为参数的默认值
+func (b *Builder) QueryParams(v interface{}) []*Parameter {
+	if v == nil {
+		return nil
+	}
+	return b.queryParams(indirect(reflect.TypeOf(v)))
+}
+
+func (b *Builder) queryParams(t reflect.Type) []*Parameter {
+	var params []*Parameter
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _ := parseTag(f.Tag.Get("form"))
+		if name == "-" {
+			continue
+		}
+		if f.Anonymous && name == "" {
+			if ft := indirect(f.Type); ft.Kind() == reflect.Struct {
+				params = append(params, b.queryParams(ft)...)
+				continue
+			}
+		}
+		if !f.IsExported() || name == "" {
+			continue
+		}
+		p := &Parameter{Name: name, In: "query", Schema: b.schemaOf(f.Type)}
+		p.Required = applyBinding(p.Schema, f.Tag.Get("binding"))
+		// default标签为参数未传时handler使用的默认值，只用于文档
+		if d, ok := f.Tag.Lookup("default"); ok {
+			p.Schema.Default = enumValue(p.Schema.Type, d)
+		}
+		params = append(params, p)
+	}
+	return params
+}
+
+// applyBinding 把binding标签中的校验规则转换为Schema的约束，返回字段是否必填
+// 只处理 required、min、max、len、oneof、email、url 几种规则，其他规则忽略，dive之后的规则作用于数组元素
+// 引用组件的Schema不能添加约束
+func applyBinding(s *Schema, tag string) (required bool) {
+	rules := strings.Split(tag, ",")
+	for i, rule := range rules {
+		key, param, _ := strings.Cut(rule, "=")
+		if key == "required" {
+			required = true
+			continue
+		}
+		if key == "dive" {
+			if s.Items != nil {
+				applyBinding(s.Items, strings.Join(rules[i+1:], ","))
+			}
+			return
+		}
+		if s.Ref != "" {
+			continue
+		}
+		switch key {
+		case "min", "max", "len":
+			n, err := strconv.ParseFloat(param, 64)
+			if err != nil {
+				continue
+			}
+			setLimit(s, key, n)
+		case "oneof":
+			for _, v := range strings.Fields(param) {
+				s.Enum = append(s.Enum, enumValue(s.Type, v))
+			}
+		case "email":
+			s.Format = "email"
+		case "url":
+			s.Format = "uri"
+		}
+	}
+	return
+}
+
+// setLimit 数值限制取值范围，字符串限制长度，数组限制元素个数时不处理
+func setLimit(s *Schema, key string, n float64) {
+	switch s.Type {
+	case "integer", "number":
+		if key != "max" {
+			s.Minimum = &n
+		}
+		if key != "min" {
+			s.Maximum = &n
+		}
+	case "string":
+		l := int64(n)
+		if key != "max" {
+			s.MinLength = &l
+		}
+		if key != "min" {
+			s.MaxLength = &l
+		}
+	}
+}
+
+func enumValue(typ, v string) interface{} {
+	switch typ {
+	case "integer":
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return n
+		}
+	case "number":
+		if n, err := strconv.ParseFloat(v, 64); err == nil {
+			return n
+		}
+	}
+	return v
+}
+
+// tagOptions 标签中名称之后的选项
+type tagOptions string
+
+func (o tagOptions) contains(name string) bool {
+	for _, opt := range strings.Split(string(o), ",") {
+		if opt == name {
+			return true
+		}
+	}
+	return false
+}
+
+func parseTag(tag string) (string, tagOptions) {
+	name, opts, _ := strings.Cut(tag, ",")
+	return name, tagOptions(opts)
+}
+
+func indirect(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+func pkgName(path string) string {
+	if i := strings.LastIndex(path, "/"); i >= 0 {
+		return path[i+1:]
+	}
+	return path
+}
@@ -4,12 +4,20 @@ import (
 	"time"
 
 	sf "github.com/bwmarrin/snowflake"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 )
 
 //实现雪花算法
 
 var node *sf.Node
 
+// genTotal 记录生成的ID数量，通过rate()可以得到ID生成速率
+var genTotal = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "bluebell_snowflake_ids_generated_total",
+	Help: "Total number of snowflake IDs generated.",
+})
+
 func Init(startTime string, machineID int64) (err error) {
 	var st time.Time
 	st, err = time.Parse("2006-01-02", startTime)
@@ -23,5 +31,6 @@ func Init(startTime string, machineID int64) (err error) {
 }
 
 func GenID() int64 {
+	genTotal.Inc()
 	return node.Generate().Int64()
 }
@@ -0,0 +1,96 @@
+package trace
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+)
+
+// Config 链路追踪的配置
+type Config struct {
+	ServiceName string            // 服务名称，对应OTLP中的service.name
+	Exporter    string            // 导出方式 otlp、otlp_grpc、stdout
+	Endpoint    string            // OTLP接收地址，如 http://localhost:4318（HTTP）或 http://localhost:4317（gRPC），为空时使用OTEL_EXPORTER_OTLP_*环境变量
+	Headers     map[string]string // 调用OTLP接口时附加的请求头
+	SampleRatio float64           // 采样比例 0~1
+}
+
+const (
+	ExporterOTLP     = "otlp" // OTLP/HTTP
+	ExporterOTLPGRPC = "otlp_grpc"
+	ExporterStdout   = "stdout"
+)
+
+// Init 开启链路追踪，设置全局的TracerProvider和W3C Trace Context传播方式
+// 返回的shutdown函数会导出剩余的节点并关闭导出器，需要在程序退出前调用
+func Init(cfg Config) (shutdown func(context.Context) error, err error) {
+	exp, err := newExporter(context.Background(), cfg)
+	if err != nil {
+		return nil, err
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(cfg.ServiceName),
+	))
+	if err != nil {
+		return nil, err
+	}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(res),
+		// 上游已经决定是否采样时沿用上游的决定，否则按trace id比例采样
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	return tp.Shutdown, nil
+}
+
+func newExporter(ctx context.Context, cfg Config) (sdktrace.SpanExporter, error) {
+	switch cfg.Exporter {
+	case ExporterOTLP:
+		opts := []otlptracehttp.Option{otlptracehttp.WithHeaders(cfg.Headers)}
+		if cfg.Endpoint != "" {
+			endpoint, err := otlpHTTPEndpoint(cfg.Endpoint)
+			if err != nil {
+				return nil, err
+			}
+			opts = append(opts, otlptracehttp.WithEndpointURL(endpoint))
+		}
+		return otlptracehttp.New(ctx, opts...)
+	case ExporterOTLPGRPC:
+		opts := []otlptracegrpc.Option{otlptracegrpc.WithHeaders(cfg.Headers)}
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlptracegrpc.WithEndpointURL(cfg.Endpoint))
+		}
+		return otlptracegrpc.New(ctx, opts...)
+	case ExporterStdout:
+		return stdouttrace.New()
+	}
+	return nil, fmt.Errorf("unsupported trace exporter %q", cfg.Exporter)
+}
+
+// otlpHTTPEndpoint 只配置了地址时自动补全 /v1/traces 路径
+func otlpHTTPEndpoint(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid trace endpoint %q: %w", endpoint, err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return "", fmt.Errorf("invalid trace endpoint %q: scheme and host are required", endpoint)
+	}
+	if strings.TrimSuffix(u.Path, "/") == "" {
+		u.Path = "/v1/traces"
+	}
+	return u.String(), nil
+}
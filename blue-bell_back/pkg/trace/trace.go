@@ -0,0 +1,79 @@
+package trace
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// 链路追踪基于OpenTelemetry，这里只封装项目中常用的创建节点和记录错误的写法
+// 未开启链路追踪时使用OpenTelemetry默认的空实现，所有操作都不产生开销
+
+// instrumentationName 创建Tracer使用的名称
+const instrumentationName = "blue-bell_back"
+
+// tracer 通过全局的TracerProvider创建，Init之后自动使用新设置的TracerProvider
+var tracer = otel.Tracer(instrumentationName)
+
+// Attr 节点的属性
+type Attr = attribute.KeyValue
+
+var (
+	String  = attribute.String
+	Int     = attribute.Int
+	Int64   = attribute.Int64
+	Float64 = attribute.Float64
+	Bool    = attribute.Bool
+)
+
+// SpanKind 节点类型
+type SpanKind = oteltrace.SpanKind
+
+const (
+	KindInternal = oteltrace.SpanKindInternal
+	KindServer   = oteltrace.SpanKindServer
+	KindClient   = oteltrace.SpanKindClient
+)
+
+// StatusError 节点的错误状态
+const StatusError = codes.Error
+
+// Span 链路中的一个节点
+type Span struct {
+	oteltrace.Span
+}
+
+// Finish 记录*errp中的错误后结束节点，用于带命名返回值err的函数
+//
+//	ctx, span := trace.Start(ctx, "mysql.GetPostList")
+//	defer span.Finish(&err)
+func (s *Span) Finish(errp *error) {
+	if errp != nil && *errp != nil {
+		s.RecordError(*errp)
+		s.SetStatus(codes.Error, (*errp).Error())
+	}
+	s.End()
+}
+
+// Start 创建一个节点并放入返回的context中
+func Start(ctx context.Context, name string, attrs ...Attr) (context.Context, *Span) {
+	return StartWithKind(ctx, name, KindInternal, attrs...)
+}
+
+// StartWithKind 创建指定类型的节点
+func StartWithKind(ctx context.Context, name string, kind SpanKind, attrs ...Attr) (context.Context, *Span) {
+	ctx, span := tracer.Start(ctx, name, oteltrace.WithSpanKind(kind), oteltrace.WithAttributes(attrs...))
+	return ctx, &Span{Span: span}
+}
+
+// TraceID 返回context中的trace id，没有被采样的链路返回空字符串
+func TraceID(ctx context.Context) string {
+	sc := oteltrace.SpanContextFromContext(ctx)
+	if !sc.IsSampled() {
+		return ""
+	}
+	return sc.TraceID().String()
+}
@@ -0,0 +1,125 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// 向订阅方推送事件
+// 请求体使用HMAC-SHA256签名，订阅方用创建订阅时返回的密钥校验：
+//
+//	expected := "sha256=" + hex(HMAC-SHA256(secret, timestamp + "." + body))
+//	hmac.Equal(expected, X-Bluebell-Signature)
+//
+// 签名包含时间戳，订阅方可以拒绝时间过早的请求防止重放
+
+// 推送请求携带的请求头
+const (
+	HeaderEvent     = "X-Bluebell-Event"     // 事件类型
+	HeaderDelivery  = "X-Bluebell-Delivery"  // 推送记录ID，重试和重放时不变，订阅方可用于去重
+	HeaderTimestamp = "X-Bluebell-Timestamp" // 发送时的Unix时间戳（秒）
+	HeaderSignature = "X-Bluebell-Signature" // 签名
+)
+
+// maxResponseSize 读取订阅方响应的最大长度，响应体只用于复用连接
+const maxResponseSize = 64 << 10
+
+// ErrAddressNotAllowed 订阅地址解析到了内网、回环等地址
+var ErrAddressNotAllowed = errors.New("webhook: address not allowed")
+
+// Sign 计算请求体的签名
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Request 一次推送
+type Request struct {
+	URL        string
+	Secret     string
+	Event      string
+	DeliveryID string
+	Body       []byte
+}
+
+// Client 推送事件的HTTP客户端
+type Client struct {
+	hc *http.Client
+}
+
+// NewClient 创建推送客户端
+// allowPrivate 在每次建立连接时调用，返回false时拒绝连接内网、回环和链路本地地址，防止通过订阅地址访问内部服务
+func NewClient(allowPrivate func() bool) *Client {
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			if allowPrivate() {
+				return nil
+			}
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !isPublic(ip) {
+				return fmt.Errorf("%w: %s", ErrAddressNotAllowed, host)
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &Client{hc: &http.Client{
+		Transport: transport,
+		// 不跟随跳转，避免跳转到内网地址
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}}
+}
+
+// Send 发送一次推送，返回订阅方响应的状态码
+// 状态码不是2xx时返回错误，超时由ctx控制
+func (c *Client) Send(ctx context.Context, r *Request) (status int, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.URL, bytes.NewReader(r.Body))
+	if err != nil {
+		return 0, err
+	}
+	ts := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "bluebell-webhook/1.0")
+	req.Header.Set(HeaderEvent, r.Event)
+	req.Header.Set(HeaderDelivery, r.DeliveryID)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(ts, 10))
+	req.Header.Set(HeaderSignature, Sign(r.Secret, ts, r.Body))
+
+	resp, err := c.hc.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseSize))
+	if resp.StatusCode/100 != 2 {
+		return resp.StatusCode, fmt.Errorf("webhook: unexpected status %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// isPublic 判断是否为公网地址
+func isPublic(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast())
+}
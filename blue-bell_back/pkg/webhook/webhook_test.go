@@ -0,0 +1,138 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestIsPublic(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"8.8.8.8", true},
+		{"1.1.1.1", true},
+		{"2606:4700:4700::1111", true},
+		{"127.0.0.1", false},
+		{"127.1.2.3", false},
+		{"::1", false},
+		{"::ffff:127.0.0.1", false},
+		{"10.0.0.1", false},
+		{"172.16.0.1", false},
+		{"172.31.255.255", false},
+		{"192.168.1.1", false},
+		{"::ffff:192.168.1.1", false},
+		{"fc00::1", false},
+		{"fd12:3456::1", false},
+		{"169.254.169.254", false}, // 云服务器的元数据服务
+		{"fe80::1", false},
+		{"0.0.0.0", false},
+		{"::", false},
+		{"224.0.0.1", false},
+		{"ff02::1", false},
+	}
+	for _, tt := range tests {
+		ip := net.ParseIP(tt.ip)
+		if ip == nil {
+			t.Fatalf("invalid test ip %q", tt.ip)
+		}
+		if got := isPublic(ip); got != tt.want {
+			t.Errorf("isPublic(%s) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestSendRejectsPrivateAddress(t *testing.T) {
+	var called bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer srv.Close()
+
+	c := NewClient(func() bool { return false })
+	_, err := c.Send(context.Background(), &Request{URL: srv.URL, Secret: "s", Body: []byte("{}")})
+	if !errors.Is(err, ErrAddressNotAllowed) {
+		t.Fatalf("Send to %s: got %v, want ErrAddressNotAllowed", srv.URL, err)
+	}
+	if called {
+		t.Fatal("request reached the loopback server")
+	}
+
+	// 主机名同样在解析后按IP检查
+	_, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+	_, err = c.Send(context.Background(), &Request{URL: "http://localhost:" + port, Secret: "s", Body: []byte("{}")})
+	if !errors.Is(err, ErrAddressNotAllowed) {
+		t.Fatalf("Send to localhost: got %v, want ErrAddressNotAllowed", err)
+	}
+}
+
+func TestSendAllowPrivateIsCheckedPerDial(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Connection", "close")
+	}))
+	defer srv.Close()
+
+	allow := true
+	c := NewClient(func() bool { return allow })
+	if _, err := c.Send(context.Background(), &Request{URL: srv.URL, Secret: "s", Body: []byte("{}")}); err != nil {
+		t.Fatalf("Send with private addresses allowed: %v", err)
+	}
+	// 修改配置后新建立的连接立即使用新的设置
+	allow = false
+	if _, err := c.Send(context.Background(), &Request{URL: srv.URL, Secret: "s", Body: []byte("{}")}); !errors.Is(err, ErrAddressNotAllowed) {
+		t.Fatalf("Send after disallowing: got %v, want ErrAddressNotAllowed", err)
+	}
+}
+
+func TestSendDoesNotFollowRedirects(t *testing.T) {
+	var redirected bool
+	mux := http.NewServeMux()
+	mux.HandleFunc("/hook", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/internal", http.StatusFound)
+	})
+	mux.HandleFunc("/internal", func(w http.ResponseWriter, r *http.Request) {
+		redirected = true
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	c := NewClient(func() bool { return true })
+	status, err := c.Send(context.Background(), &Request{URL: srv.URL + "/hook", Secret: "s", Body: []byte("{}")})
+	if err == nil || status != http.StatusFound {
+		t.Fatalf("Send: got status %d err %v, want 302 and an error", status, err)
+	}
+	if redirected {
+		t.Fatal("redirect was followed")
+	}
+}
+
+func TestSendSignsRequest(t *testing.T) {
+	const secret = "secret"
+	body := []byte(`{"event":"post.created"}`)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ := io.ReadAll(r.Body)
+		ts, err := strconv.ParseInt(r.Header.Get(HeaderTimestamp), 10, 64)
+		if err != nil || r.Header.Get(HeaderSignature) != Sign(secret, ts, got) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Header.Get(HeaderEvent) != "post.created" || r.Header.Get(HeaderDelivery) != "d1" {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	c := NewClient(func() bool { return true })
+	status, err := c.Send(context.Background(), &Request{
+		URL: srv.URL, Secret: secret, Event: "post.created", DeliveryID: "d1", Body: body,
+	})
+	if err != nil || status != http.StatusOK {
+		t.Fatalf("Send: got status %d err %v, want 200", status, err)
+	}
+}
@@ -0,0 +1,253 @@
+package router
+
+import (
+	"blue-bell_back/controller"
+	"blue-bell_back/models"
+	"net/http"
+)
+
+// apiDocs /api/v1 下每个路由的接口文档，键为 controller.DocKey(方法, 路由)
+// 新增路由时需要同时在这里登记，否则 Setup 时panic
+
+// 没有对应模型的查询参数和响应数据
+type (
+	pageQuery struct {
+		Page int64 `form:"page" default:"1"`  // 页码
+		Size int64 `form:"size" default:"10"` // 每页数量
+	}
+	oauthCallbackQuery struct {
+		Code  string `form:"code" binding:"required"`  // 提供方返回的授权码
+		State string `form:"state" binding:"required"` // 授权地址中带的state
+		Error string `form:"error"`                    // 用户拒绝授权时提供方返回的错误
+	}
+	loginData struct {
+		UserID   int64  `json:"user_id"`
+		UserName string `json:"user_name"`
+		Token    string `json:"token"`
+	}
+	urlData struct {
+		URL string `json:"url"`
+	}
+	tokenData struct {
+		Token string `json:"token"`
+	}
+	localeData struct {
+		Locale string `json:"locale"`
+	}
+	ticketData struct {
+		Ticket string `json:"ticket"`
+	}
+	streamQuery struct {
+		models.ParamStream
+		Ticket string `form:"ticket"` // 没有Authorization请求头时使用 /stream/ticket 签发的票据
+	}
+	countData struct {
+		Count int64 `json:"count"`
+	}
+	logLevelData struct {
+		Level string `json:"level"`
+	}
+)
+
+const (
+	tagUser      = "user"
+	tagAccount   = "account"
+	tagOAuth     = "oauth"
+	tagCommunity = "community"
+	tagPost      = "post"
+	tagStream    = "stream"
+	tagNotify    = "notification"
+	tagWebhook   = "webhook"
+	tagAdmin     = "admin"
+)
+
+var apiDocs = map[string]controller.APIDoc{
+	controller.DocKey(http.MethodPost, "/api/v1/signup"): {
+		Summary: "注册", Tag: tagUser, Body: models.ParamSignUp{},
+		Description: "注册成功后向邮箱发送验证邮件",
+	},
+	controller.DocKey(http.MethodPost, "/api/v1/login"): {
+		Summary: "登录", Tag: tagUser, Body: models.ParamLogin{}, Data: loginData{},
+		Description: "连续失败多次后账户会被临时锁定，响应头Retry-After为剩余的秒数",
+	},
+	controller.DocKey(http.MethodPost, "/api/v1/email/verify"): {
+		Summary: "验证邮箱", Tag: tagAccount, Body: models.ParamVerifyEmail{},
+	},
+	controller.DocKey(http.MethodPost, "/api/v1/password/forgot"): {
+		Summary: "忘记密码", Tag: tagAccount, Body: models.ParamForgotPassword{},
+		Description: "无论邮箱是否注册都返回成功",
+	},
+	controller.DocKey(http.MethodPost, "/api/v1/password/reset"): {
+		Summary: "重置密码", Tag: tagAccount, Body: models.ParamResetPassword{},
+	},
+	controller.DocKey(http.MethodGet, "/api/v1/oauth/:provider/authorize"): {
+		Summary: "第三方登录的授权地址", Tag: tagOAuth, Data: urlData{},
+	},
+	controller.DocKey(http.MethodGet, "/api/v1/oauth/:provider/callback"): {
+		Summary: "第三方登录回调", Tag: tagOAuth, Query: oauthCallbackQuery{}, Data: loginData{},
+		Description: "使用提供方返回的code和state换取令牌",
+	},
+
+	controller.DocKey(http.MethodGet, "/api/v1/community"): {
+		Summary: "社区列表", Tag: tagCommunity, Auth: true, Data: []models.Community{},
+	},
+	controller.DocKey(http.MethodGet, "/api/v1/community/:id"): {
+		Summary: "社区详情", Tag: tagCommunity, Auth: true, Data: models.CommunityDetail{},
+	},
+	controller.DocKey(http.MethodGet, "/api/v1/community/post/list/:id"): {
+		Summary: "社区下的帖子", Tag: tagPost, Auth: true,
+		Query: models.ParamCommunityPostList{}, Data: []models.ApiPostDetail{},
+	},
+	controller.DocKey(http.MethodPost, "/api/v1/community/post"): {
+		Summary: "创建帖子", Tag: tagPost, Auth: true, Body: models.CommunityPost{},
+		Description: "content使用Markdown，不支持HTML",
+	},
+	controller.DocKey(http.MethodGet, "/api/v1/community/post/:id"): {
+		Summary: "帖子详情", Tag: tagPost, Auth: true, Data: models.ApiPostDetail{},
+		Description: "同时返回Markdown原文content和过滤后的HTML content_html，帖子列表只返回纯文本摘要excerpt",
+	},
+	controller.DocKey(http.MethodGet, "/api/v1/community/post/list"): {
+		Summary: "帖子列表", Tag: tagPost, Auth: true, Query: pageQuery{}, Data: []models.ApiPostDetail{},
+	},
+	controller.DocKey(http.MethodGet, "/api/v1/community/post/orderList"): {
+		Summary: "排序返回帖子列表", Tag: tagPost, Auth: true,
+		Query: models.ParamOrderList{}, Data: []models.ApiPostDetail{},
+		Description: "order: time发帖时间 score分数 top_day最近24小时得票 top_week最近7天得票 top_all总得票 trending最近6小时得票（越近权重越高），" +
+			"社区和标签下的帖子支持同样的排序，按时间段的排行缓存60秒",
+	},
+	controller.DocKey(http.MethodGet, "/api/v1/tags"): {
+		Summary: "热门标签", Tag: tagPost, Auth: true, Query: models.ParamTagList{}, Data: []models.Tag{},
+		Description: "按使用次数倒序，默认返回20个",
+	},
+	controller.DocKey(http.MethodGet, "/api/v1/tag/:name/posts"): {
+		Summary: "标签下的帖子", Tag: tagPost, Auth: true,
+		Query: models.ParamOrderList{}, Data: []models.ApiPostDetail{},
+	},
+	controller.DocKey(http.MethodPost, "/api/v1/community/vote"): {
+		Summary: "帖子投票", Tag: tagPost, Auth: true, Body: models.ParamCommunityVote{},
+		Description: "帖子发布7天后不能再投票",
+	},
+	controller.DocKey(http.MethodPost, "/api/v1/community/post/:id/save"): {
+		Summary: "收藏帖子", Tag: tagPost, Auth: true,
+		Description: "重复收藏时保持原来的收藏时间",
+	},
+	controller.DocKey(http.MethodDelete, "/api/v1/community/post/:id/save"): {
+		Summary: "取消收藏", Tag: tagPost, Auth: true,
+	},
+
+	controller.DocKey(http.MethodGet, "/api/v1/user/login_history"): {
+		Summary: "登录记录", Tag: tagUser, Auth: true, Query: models.ParamPage{}, Data: []models.LoginHistory{},
+	},
+	controller.DocKey(http.MethodPut, "/api/v1/user/locale"): {
+		Summary: "设置界面语言", Tag: tagUser, Auth: true, Body: models.ParamUserLocale{}, Data: localeData{},
+		Description: "locale为空时按请求头Accept-Language协商",
+	},
+	controller.DocKey(http.MethodPut, "/api/v1/user/password"): {
+		Summary: "修改密码", Tag: tagAccount, Auth: true, Body: models.ParamChangePassword{}, Data: tokenData{},
+		Description: "之前签发的令牌全部失效，响应中返回新的令牌；第三方登录创建的账户未设置过密码时old_password可以为空",
+	},
+	controller.DocKey(http.MethodGet, "/api/v1/user/me/saved"): {
+		Summary: "收藏的帖子", Tag: tagPost, Auth: true, Query: models.ParamPage{}, Data: []models.ApiPostDetail{},
+		Description: "按收藏时间倒序，已删除的帖子不返回",
+	},
+	controller.DocKey(http.MethodGet, "/api/v1/user/:id/profile"): {
+		Summary: "用户资料", Tag: tagUser, Auth: true, Data: models.UserProfile{},
+		Description: "followed表示当前用户是否已关注该用户",
+	},
+	controller.DocKey(http.MethodPost, "/api/v1/user/:id/follow"): {
+		Summary: "关注用户", Tag: tagUser, Auth: true,
+		Description: "重复关注时同样返回成功，不能关注自己",
+	},
+	controller.DocKey(http.MethodDelete, "/api/v1/user/:id/follow"): {
+		Summary: "取消关注", Tag: tagUser, Auth: true,
+	},
+	controller.DocKey(http.MethodGet, "/api/v1/feed/following"): {
+		Summary: "关注的人的帖子", Tag: tagPost, Auth: true, Query: models.ParamPage{}, Data: []models.ApiPostDetail{},
+		Description: "按发帖时间倒序，合并结果缓存60秒",
+	},
+	controller.DocKey(http.MethodDelete, "/api/v1/user/me"): {
+		Summary: "注销账户", Tag: tagAccount, Auth: true, Body: models.ParamDeleteAccount{},
+		Description: "password和token二选一；没有密码的用户通过 /oauth/{provider}/reauth 再次验证身份或 /user/delete/email 邮件确认得到token",
+	},
+	controller.DocKey(http.MethodPost, "/api/v1/user/delete/email"): {
+		Summary: "发送确认注销账户的邮件", Tag: tagAccount, Auth: true,
+		Description: "邮件中的令牌30分钟内有效，可以代替密码注销账户；邮箱未验证时无法使用",
+	},
+	controller.DocKey(http.MethodGet, "/api/v1/user/identities"): {
+		Summary: "已绑定的第三方账户", Tag: tagOAuth, Auth: true, Data: []models.UserIdentity{},
+	},
+	controller.DocKey(http.MethodGet, "/api/v1/oauth/:provider/link"): {
+		Summary: "绑定第三方账户的授权地址", Tag: tagOAuth, Auth: true, Data: urlData{},
+	},
+	controller.DocKey(http.MethodGet, "/api/v1/oauth/:provider/link/callback"): {
+		Summary: "绑定第三方账户回调", Tag: tagOAuth, Auth: true, Query: oauthCallbackQuery{}, Data: loginData{},
+	},
+	controller.DocKey(http.MethodGet, "/api/v1/oauth/:provider/reauth"): {
+		Summary: "再次验证身份的授权地址", Tag: tagOAuth, Auth: true, Data: urlData{},
+		Description: "要求用户在提供方重新登录，用于没有密码的用户注销账户",
+	},
+	controller.DocKey(http.MethodGet, "/api/v1/oauth/:provider/reauth/callback"): {
+		Summary: "再次验证身份回调", Tag: tagOAuth, Auth: true, Query: oauthCallbackQuery{}, Data: tokenData{},
+		Description: "第三方账户必须已绑定到当前用户，返回的token 30分钟内有效，可以代替密码注销账户",
+	},
+	controller.DocKey(http.MethodPost, "/api/v1/email/verify/resend"): {
+		Summary: "重新发送验证邮件", Tag: tagAccount, Auth: true,
+	},
+	controller.DocKey(http.MethodGet, "/api/v1/stream"): {
+		Summary: "实时事件推送", Tag: tagStream, Auth: true, Query: streamQuery{},
+		Description: "Server-Sent Events，响应类型为text/event-stream，不使用ResponseData包装。" +
+			"事件名为post.created、vote.changed或post.deleted，数据为Event的JSON，每30秒发送一次注释作为心跳",
+	},
+	controller.DocKey(http.MethodPost, "/api/v1/stream/ticket"): {
+		Summary: "连接事件流的票据", Tag: tagStream, Auth: true, Data: ticketData{},
+		Description: "浏览器的EventSource不能设置请求头，使用票据作为查询参数连接，票据30秒内有效且只能使用一次",
+	},
+	controller.DocKey(http.MethodGet, "/api/v1/notifications"): {
+		Summary: "通知列表", Tag: tagNotify, Auth: true, Query: models.ParamPage{}, Data: []models.Notification{},
+		Description: "按最近更新时间倒序，同一帖子未读的点赞通知合并为一条，actor_count为点赞人数",
+	},
+	controller.DocKey(http.MethodGet, "/api/v1/notifications/unread_count"): {
+		Summary: "未读通知数量", Tag: tagNotify, Auth: true, Data: countData{},
+	},
+	controller.DocKey(http.MethodPut, "/api/v1/notifications/read"): {
+		Summary: "标记通知已读", Tag: tagNotify, Auth: true, Body: models.ParamMarkRead{}, Data: countData{},
+		Description: "ids为空时标记全部通知，count为实际标记的数量",
+	},
+	controller.DocKey(http.MethodGet, "/api/v1/user/notification_preferences"): {
+		Summary: "通知设置", Tag: tagNotify, Auth: true, Data: models.NotifyPreferences{},
+		Description: "键为通知类型，未设置过的类型默认开启",
+	},
+	controller.DocKey(http.MethodPut, "/api/v1/user/notification_preferences"): {
+		Summary: "修改通知设置", Tag: tagNotify, Auth: true, Body: models.ParamNotifyPreferences{}, Data: models.NotifyPreferences{},
+		Description: "只修改传入的类型，返回修改后的全部设置",
+	},
+	controller.DocKey(http.MethodPost, "/api/v1/webhooks"): {
+		Summary: "创建事件订阅", Tag: tagWebhook, Auth: true, Body: models.ParamCreateWebhook{}, Data: models.Webhook{},
+		Description: "社区中发生订阅的事件时向url发送POST请求，请求体为Event的JSON。" +
+			"请求头X-Bluebell-Signature为 sha256=hex(HMAC-SHA256(secret, X-Bluebell-Timestamp + \".\" + 请求体))，" +
+			"X-Bluebell-Delivery为推送记录ID，重试时不变。secret只在创建时返回",
+	},
+	controller.DocKey(http.MethodGet, "/api/v1/webhooks"): {
+		Summary: "事件订阅列表", Tag: tagWebhook, Auth: true, Data: []models.Webhook{},
+	},
+	controller.DocKey(http.MethodDelete, "/api/v1/webhooks/:id"): {
+		Summary: "删除事件订阅", Tag: tagWebhook, Auth: true,
+		Description: "同时删除订阅的推送记录",
+	},
+	controller.DocKey(http.MethodGet, "/api/v1/webhooks/:id/deliveries"): {
+		Summary: "推送记录", Tag: tagWebhook, Auth: true,
+		Query: models.ParamWebhookDeliveries{}, Data: []models.WebhookDelivery{},
+		Description: "返回2xx以外的状态码或超时视为失败，按指数退避重试，次数用完后状态为failed",
+	},
+	controller.DocKey(http.MethodPost, "/api/v1/webhooks/:id/deliveries/:delivery_id/replay"): {
+		Summary: "重新推送", Tag: tagWebhook, Auth: true,
+		Description: "把推送记录恢复为pending并立即发送，重试次数从头计算",
+	},
+
+	controller.DocKey(http.MethodGet, "/api/v1/admin/log/level"): {
+		Summary: "查询日志级别", Tag: tagAdmin, Auth: true, Data: logLevelData{},
+	},
+	controller.DocKey(http.MethodPut, "/api/v1/admin/log/level"): {
+		Summary: "修改日志级别", Tag: tagAdmin, Auth: true, Body: models.ParamLogLevel{}, Data: logLevelData{},
+	},
+}
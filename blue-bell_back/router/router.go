@@ -5,26 +5,35 @@ import ( //倒入自定义的日志哭，用于记录API请求的日志和恢复
 	"blue-bell_back/controller"
 	"blue-bell_back/logger"
 	"blue-bell_back/middlewares"
-	"blue-bell_back/pkg/jwt"
 
 	"net/http"
-	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
 )
 
 // Setup函数用于初始化并配置gin框架，设置中间件和路由
+// trustedProxies为可信的反向代理，只有来自这些地址的请求才从X-Forwarded-For中取客户端IP
 // 返回值: *gin.Engine，初始化后的gin引擎实例
 
-func Setup(mode string) *gin.Engine {
+func Setup(mode string, trustedProxies []string) (*gin.Engine, error) {
 	if mode == gin.ReleaseMode {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
 	//创建一个新的gin引擎实例
 	r := gin.New()
+	// gin默认信任所有代理，任何人都可以通过X-Forwarded-For伪造客户端IP绕过按IP的限流和登录保护
+	// 没有配置代理时传nil，直接使用连接的对端地址
+	if len(trustedProxies) == 0 {
+		trustedProxies = nil
+	}
+	if err := r.SetTrustedProxies(trustedProxies); err != nil {
+		return nil, err
+	}
 	//使用自定义的日志记录🍺异常恢复中间件
-	r.Use(logger.GinLogger(), logger.GinRecovery(true))
+	r.Use(logger.GinLogger(), middlewares.TraceMiddleware(), middlewares.MetricsMiddleware(), logger.GinRecovery(true, controller.RecoveryHandler))
 
 	v1 := r.Group("/api/v1")
 
@@ -36,70 +45,99 @@ func Setup(mode string) *gin.Engine {
 	// 	//如果是登陆用户，判断请求头中是否有有效的JWT
 	// 	c.String(http.StatusOK, "pong")
 	// })
-	v1.POST("/signup", controller.SignUpHandler)
-	v1.POST("/login", controller.LoginHandler)
+	v1.POST("/signup", middlewares.RateLimitMiddleware(middlewares.RateLimitSignUp), controller.SignUpHandler)
+	v1.POST("/login", middlewares.RateLimitMiddleware(middlewares.RateLimitLogin), controller.LoginHandler)
+	v1.POST("/email/verify", controller.VerifyEmailHandler)                                                                           // 验证邮箱
+	v1.POST("/password/forgot", middlewares.RateLimitMiddleware(middlewares.RateLimitMail), controller.ForgotPasswordHandler)         // 忘记密码
+	v1.POST("/password/reset", controller.ResetPasswordHandler)                                                                       // 重置密码
+	v1.GET("/oauth/:provider/authorize", controller.OAuthAuthorizeHandler)                                                            // 第三方登录授权地址
+	v1.GET("/oauth/:provider/callback", middlewares.RateLimitMiddleware(middlewares.RateLimitLogin), controller.OAuthCallbackHandler) // 第三方登录回调
+
+	v1.GET("/stream", middlewares.StreamAuthMiddleware(), controller.StreamHandler) // 实时事件推送，支持使用票据认证
 
 	//JWT认证
 	v1.Use(middlewares.JWTAuthMiddleware())
 	{
-		v1.GET("/community", controller.CommunityHandler)                          // 社区列表
-		v1.GET("/community/:id", controller.CommunityDetailHandler)                // 社区详情
-		v1.GET("/community/post/list/:id", controller.GetCommunityPostListHandler) // 根据社区id返回对应社区下的帖子
-		v1.POST("/community/post", controller.CreatePostHandler)                   // 创建帖子
-		v1.GET("/community/post/:id", controller.PostDetailHandler)                // 帖子详情
-		v1.GET("/community/post/list", controller.GetPostListHandler)              // 帖子列表
-		v1.GET("/community/post/orderList", controller.GetPostOrderListHandler)    // 排序返回帖子列表
-		v1.POST("/community/vote", controller.CommunityVote)                       // 帖子投票
+		v1.GET("/community", controller.CommunityHandler)                                                                                                                 // 社区列表
+		v1.GET("/community/:id", controller.CommunityDetailHandler)                                                                                                       // 社区详情
+		v1.GET("/community/post/list/:id", controller.GetCommunityPostListHandler)                                                                                        // 根据社区id返回对应社区下的帖子
+		v1.POST("/community/post", middlewares.EmailVerifiedMiddleware(), middlewares.RateLimitMiddleware(middlewares.RateLimitCreatePost), controller.CreatePostHandler) // 创建帖子
+		v1.GET("/community/post/:id", controller.PostDetailHandler)                                                                                                       // 帖子详情
+		v1.GET("/community/post/list", controller.GetPostListHandler)                                                                                                     // 帖子列表
+		v1.GET("/community/post/orderList", controller.GetPostOrderListHandler)                                                                                           // 排序返回帖子列表
+		v1.POST("/community/vote", middlewares.EmailVerifiedMiddleware(), middlewares.RateLimitMiddleware(middlewares.RateLimitVote), controller.CommunityVote)           // 帖子投票
+		v1.POST("/community/post/:id/save", controller.SavePostHandler)                                                                                                   // 收藏帖子
+		v1.DELETE("/community/post/:id/save", controller.UnsavePostHandler)                                                                                               // 取消收藏
+		v1.GET("/tags", controller.TagListHandler)                                                                                                                        // 热门标签
+		v1.GET("/tag/:name/posts", controller.TagPostListHandler)                                                                                                         // 标签下的帖子
+
+		v1.GET("/user/login_history", controller.LoginHistoryHandler)                                                                    // 登录记录
+		v1.PUT("/user/locale", controller.SetUserLocaleHandler)                                                                          // 设置界面语言
+		v1.PUT("/user/password", controller.ChangePasswordHandler)                                                                       // 修改密码
+		v1.GET("/user/me/saved", controller.SavedPostListHandler)                                                                        // 收藏的帖子
+		v1.GET("/user/:id/profile", controller.UserProfileHandler)                                                                       // 用户资料
+		v1.POST("/user/:id/follow", controller.FollowHandler)                                                                            // 关注用户
+		v1.DELETE("/user/:id/follow", controller.UnfollowHandler)                                                                        // 取消关注
+		v1.GET("/feed/following", controller.FollowingFeedHandler)                                                                       // 关注的人的帖子
+		v1.DELETE("/user/me", controller.DeleteAccountHandler)                                                                           // 注销账户
+		v1.POST("/user/delete/email", middlewares.RateLimitMiddleware(middlewares.RateLimitMail), controller.AccountDeleteEmailHandler)  // 发送确认注销账户的邮件
+		v1.GET("/user/identities", controller.UserIdentitiesHandler)                                                                     // 已绑定的第三方账户
+		v1.GET("/oauth/:provider/link", controller.OAuthLinkHandler)                                                                     // 绑定第三方账户的授权地址
+		v1.GET("/oauth/:provider/link/callback", controller.OAuthLinkCallbackHandler)                                                    // 绑定第三方账户回调
+		v1.GET("/oauth/:provider/reauth", controller.OAuthReauthHandler)                                                                 // 再次验证身份的授权地址
+		v1.GET("/oauth/:provider/reauth/callback", controller.OAuthReauthCallbackHandler)                                                // 再次验证身份回调
+		v1.POST("/email/verify/resend", middlewares.RateLimitMiddleware(middlewares.RateLimitMail), controller.ResendVerifyEmailHandler) // 重新发送验证邮件
+		v1.POST("/stream/ticket", controller.StreamTicketHandler)                                                                        // 连接事件流的票据
+
+		v1.GET("/notifications", controller.NotificationListHandler)                     // 通知列表
+		v1.GET("/notifications/unread_count", controller.UnreadNotificationCountHandler) // 未读通知数量
+		v1.PUT("/notifications/read", controller.MarkNotificationsReadHandler)           // 标记已读
+		v1.GET("/user/notification_preferences", controller.GetNotifyPreferencesHandler) // 通知设置
+		v1.PUT("/user/notification_preferences", controller.SetNotifyPreferencesHandler) // 修改通知设置
+
+		v1.POST("/webhooks", middlewares.EmailVerifiedMiddleware(), controller.CreateWebhookHandler)     // 创建事件订阅
+		v1.GET("/webhooks", controller.WebhookListHandler)                                               // 事件订阅列表
+		v1.DELETE("/webhooks/:id", controller.DeleteWebhookHandler)                                      // 删除事件订阅
+		v1.GET("/webhooks/:id/deliveries", controller.WebhookDeliveriesHandler)                          // 推送记录
+		v1.POST("/webhooks/:id/deliveries/:delivery_id/replay", controller.ReplayWebhookDeliveryHandler) // 重新推送
+	}
+
+	//管理接口
+	admin := v1.Group("/admin", middlewares.AdminAuthMiddleware())
+	{
+		admin.GET("/log/level", controller.GetLogLevelHandler) // 查询日志级别
+		admin.PUT("/log/level", controller.SetLogLevelHandler) // 修改日志级别
 	}
 
+	//健康检查
+	r.GET("/healthz", controller.LivenessHandler) // 存活检查
+	r.GET("/readyz", controller.ReadinessHandler) // 就绪检查
+
 	//配置GET请求的路由，处理根路径的请求
 	r.GET("/", func(c *gin.Context) {
 		//响应客户端请求,返回HTTP状态码200和字符串"OK"
 		c.String(http.StatusOK, "ok")
 	})
 
+	//接口文档，/api/v1 下的路由缺少文档时记录错误并输出已有的部分，router_test.go 检查文档是否完整
+	doc, err := controller.NewOpenAPI(r.Routes(), apiDocs)
+	if err != nil {
+		zap.L().Error("api documentation incomplete", zap.Error(err))
+	}
+	specHandler, err := controller.OpenAPIHandler(doc)
+	if err != nil {
+		return nil, err
+	}
+	r.GET("/swagger", controller.SwaggerUIHandler("/swagger/openapi.json")) // Swagger UI
+	r.GET("/swagger/openapi.json", specHandler)                             // OpenAPI文档
+
 	//返回初始化后的gin引擎
-	return r
+	return r, nil
 }
 
-// JWTAuthMiddleware 基于JWT的认证中间件
-func JWTAuthMiddleware() func(c *gin.Context) {
-	return func(c *gin.Context) {
-		// 客户端携带Token有三种方式 1.放在请求头 2.放在请求体 3.放在URI
-		// 这里假设Token放在Header的Authorization中，并使用Bearer开头
-		// Authorization: Bearer xxxxxxx.xxx.xxxxxxx
-		// 这里的具体实现方式要依据实际业务情况决定
-		authHeader := c.Request.Header.Get("Authorization")
-		if authHeader == "" {
-			c.JSON(http.StatusOK, gin.H{
-				"code": 2003,
-				"msg":  "请求头中auth为空",
-			})
-			c.Abort()
-			return
-		}
-		// 按空格分割
-		parts := strings.SplitN(authHeader, " ", 2)
-		if !(len(parts) == 2 && parts[0] == "Bearer") {
-			c.JSON(http.StatusOK, gin.H{
-				"code": 2004,
-				"msg":  "请求头中auth格式有误",
-			})
-			c.Abort()
-			return
-		}
-		// parts[1]是获取到的tokenString，我们使用之前定义好的解析JWT的函数来解析它
-		mc, err := jwt.ParseToken(parts[1])
-		if err != nil {
-			c.JSON(http.StatusOK, gin.H{
-				"code": 2005,
-				"msg":  "无效的Token",
-			})
-			c.Abort()
-			return
-		}
-		// 将当前请求的userID信息保存到请求的上下文c上
-		c.Set("userID", mc.UserID)
-		c.Next() // 后续的处理函数可以用过c.Get("username")来获取当前请求的用户信息
-	}
+// SetupMetrics 返回只提供Prometheus监控指标的Handler，在单独的地址上监听
+func SetupMetrics() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	return mux
 }
@@ -0,0 +1,42 @@
+package router
+
+import (
+	"blue-bell_back/controller"
+	"blue-bell_back/models"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestAPIDocs /api/v1 下的每个路由都要在apiDocs中有文档，apiDocs中也不能有已删除的路由
+func TestAPIDocs(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r, err := Setup(gin.TestMode, nil)
+	if err != nil {
+		t.Fatalf("Setup: %v", err)
+	}
+	if _, err := controller.NewOpenAPI(r.Routes(), apiDocs); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestPageDefaults 文档中的分页默认值要和handler使用的一致
+func TestPageDefaults(t *testing.T) {
+	want := map[string]string{
+		"page":  strconv.Itoa(models.DefaultPage),
+		"size":  strconv.Itoa(models.DefaultSize),
+		"order": models.OrderTime,
+	}
+	for _, v := range []interface{}{models.ParamPage{}, models.ParamOrderList{}, pageQuery{}} {
+		typ := reflect.TypeOf(v)
+		for i := 0; i < typ.NumField(); i++ {
+			f := typ.Field(i)
+			name := f.Tag.Get("form")
+			if got := f.Tag.Get("default"); got != want[name] {
+				t.Errorf("%s.%s default = %q, want %q", typ.Name(), f.Name, got, want[name])
+			}
+		}
+	}
+}
@@ -2,58 +2,197 @@ package settings
 
 import (
 	"fmt"
+	"sync"
+	"sync/atomic"
 
 	"github.com/fsnotify/fsnotify"
 	"github.com/spf13/viper"
+	"go.uber.org/zap"
 )
 
-// Conf是一个全局变量，用于
-var Conf = new(AppConfig)
+// conf 保存当前生效的配置快照
+// 配置热加载时整体替换指针，读取方通过Get拿到的快照不会被并发修改
+var conf atomic.Pointer[AppConfig]
+
+// Get 返回当前生效的配置快照
+// 返回的结构体只读，不要修改其中的字段
+func Get() *AppConfig {
+	return conf.Load()
+}
 
 // 定义了应用程序配置的结构体，包含了应用程序的基本配置信息
+// 带有 reload:"static" 标签的字段只在启动时生效，运行期间修改会被拒绝
 type AppConfig struct {
 	// Name         string                 `mapstructure:"name"` //应用程序名称
 	// Mode         string                 `mapstructure:"mode"` //程序运行模式
 	// Port         int                    `mapstructure:"port"` //程序运行端口
 
-	Name         string                 `mapstructure:"name"`       // 应用程序名称
-	Mode         string                 `mapstructure:"mode"`       // 应用程序运行模式
-	Port         int                    `mapstructure:"port"`       // 应用程序运行端口
-	StartTime    string                 `mapstructure:"start_time"` // 程序开始时间
-	MachineID    int64                  `mapstructure:"machine_id"` //机器ID
-	*LogConfig   `mapstructure:"log"`   //日志配置信息
-	*MySQLConfig `mapstructure:"mysql"` //MySQL数据库配置信息
-	*RedisConfig `mapsturcture:"redis"` //redis数据库配置信息
+	Name              string                       `mapstructure:"name" reload:"static"`            // 应用程序名称
+	Mode              string                       `mapstructure:"mode" reload:"static"`            // 应用程序运行模式
+	Port              int                          `mapstructure:"port" reload:"static"`            // 应用程序运行端口
+	StartTime         string                       `mapstructure:"start_time" reload:"static"`      // 程序开始时间
+	MachineID         int64                        `mapstructure:"machine_id" reload:"static"`      //机器ID
+	ShutdownWait      int                          `mapstructure:"shutdown_wait"`                   //关机前等待负载均衡摘除流量的秒数
+	TrustedProxies    []string                     `mapstructure:"trusted_proxies" reload:"static"` //可信的反向代理地址或网段，只有来自这些地址的请求才使用X-Forwarded-For中的客户端IP，为空时直接使用连接的对端地址
+	*LogConfig        `mapstructure:"log"`         //日志配置信息
+	*MySQLConfig      `mapstructure:"mysql"`       //MySQL数据库配置信息
+	*RedisConfig      `mapstructure:"redis"`       //redis数据库配置信息
+	*AdminConfig      `mapstructure:"admin"`       //管理员配置信息
+	*TraceConfig      `mapstructure:"trace"`       //链路追踪配置信息
+	*RateLimitConfig  `mapstructure:"rate_limit"`  //限流配置信息
+	*LoginGuardConfig `mapstructure:"login_guard"` //登录保护配置信息
+	*MailConfig       `mapstructure:"mail"`        //邮件配置信息
+	*AccountConfig    `mapstructure:"account"`     //账户配置信息
+	*OAuthConfig      `mapstructure:"oauth"`       //第三方登录配置信息
+	*ResponseConfig   `mapstructure:"response"`    //响应格式配置信息
+	*WebhookConfig    `mapstructure:"webhook"`     //事件推送配置信息
+	*TagConfig        `mapstructure:"tag"`         //标签配置信息
+	*MetricsConfig    `mapstructure:"metrics"`     //监控指标配置信息
 }
 
 // LogConfig定义了日志配置的结构体，包含了日志的相关配置信息
 type LogConfig struct {
-	Level      string `mapstructure:"level"`      //日志级别
-	FileName   string `mapstructure:"filename"`   //日志文件名
-	MaxSize    int    `mapstructure:"max_size"`   //日志文件最大的尺寸（MB）
-	MaxAge     int    `mapstructure:"max_age"`    //日志文件最大保留的天数
-	MaxBackups int    `mapstructure:max_backups"` //最多保留的日志文件个数
+	Level      string `mapstructure:"level"`                       //日志级别
+	FileName   string `mapstructure:"filename" reload:"static"`    //日志文件名
+	MaxSize    int    `mapstructure:"max_size" reload:"static"`    //日志文件最大的尺寸（MB）
+	MaxAge     int    `mapstructure:"max_age" reload:"static"`     //日志文件最大保留的天数
+	MaxBackups int    `mapstructure:"max_backups" reload:"static"` //最多保留的日志文件个数
+	Console    bool   `mapstructure:"console" reload:"static"`     //是否同时输出到终端（开发环境使用）
 
 }
 
 //MySQLConfig定义了MySQL数据库配置的结构体，包含了连接MySQL数据库所需的信息
 
 type MySQLConfig struct {
-	Host         string `mapstructure:"host"`           //数据库主机地址
-	Port         int    `mapstructure:"port"`           //数据库端口
-	User         string `mapstructure:"user"`           //数据库用户名
-	Password     string `mapstructure:"password"`       //
-	DbName       string `mapstructure::"dbname"`        //数据库
-	MaxOpenConns int    `mapstructure:"max_open_conns"` //最大打开的连接数
-	MaxIdleConns int    `mapstructure:max_idle_conns"`  //最大空闲连接数
+	Host         string `mapstructure:"host" reload:"static"`     //数据库主机地址
+	Port         int    `mapstructure:"port" reload:"static"`     //数据库端口
+	User         string `mapstructure:"user" reload:"static"`     //数据库用户名
+	Password     string `mapstructure:"password" reload:"static"` //
+	DbName       string `mapstructure:"dbname" reload:"static"`   //数据库
+	MaxOpenConns int    `mapstructure:"max_open_conns"`           //最大打开的连接数
+	MaxIdleConns int    `mapstructure:"max_idle_conns"`           //最大空闲连接数
 }
 
 type RedisConfig struct {
-	Host     string `mapstructure:"host"`      //Redis主机地址
-	Port     int    `mapstructure:"port"`      //端口
-	Password string `mapstructure:"password"`  //
-	DB       int    `mapstructure:"db"`        //数据编号
-	PoolSize int    `mapstructure:"pool_size"` //连接池大小
+	Host     string `mapstructure:"host" reload:"static"`     //Redis主机地址
+	Port     int    `mapstructure:"port" reload:"static"`     //端口
+	Password string `mapstructure:"password" reload:"static"` //
+	DB       int    `mapstructure:"db" reload:"static"`       //数据编号
+	PoolSize int    `mapstructure:"pool_size"`                //连接池大小
+}
+
+// AdminConfig 管理员配置，只有列表中的用户可以访问管理接口
+type AdminConfig struct {
+	UserIDs []int64 `mapstructure:"user_ids"` //管理员用户ID列表
+}
+
+// TraceConfig 链路追踪配置
+type TraceConfig struct {
+	Enable      bool              `mapstructure:"enable" reload:"static"`       //是否开启链路追踪
+	Exporter    string            `mapstructure:"exporter" reload:"static"`     //导出方式 otlp（OTLP/HTTP）、otlp_grpc、stdout
+	Endpoint    string            `mapstructure:"endpoint" reload:"static"`     //OTLP接收地址
+	Headers     map[string]string `mapstructure:"headers" reload:"static"`      //调用OTLP接口时附加的请求头
+	SampleRatio float64           `mapstructure:"sample_ratio" reload:"static"` //采样比例 0~1
+}
+
+// RateLimitConfig 限流配置，修改后立即生效
+type RateLimitConfig struct {
+	Enable   bool                        `mapstructure:"enable"`   //是否开启限流
+	Policies map[string]*RateLimitPolicy `mapstructure:"policies"` //按路由名称配置的限流策略
+}
+
+// RateLimitPolicy 限流策略，window秒内最多允许limit次请求
+type RateLimitPolicy struct {
+	Limit  int `mapstructure:"limit"`  //窗口内允许的请求次数
+	Window int `mapstructure:"window"` //窗口大小（秒）
+}
+
+// LoginGuardConfig 登录保护配置，连续登录失败后延迟响应并临时锁定账户
+type LoginGuardConfig struct {
+	MaxUserFailures int `mapstructure:"max_user_failures"` //同一用户名窗口内允许的失败次数
+	MaxIPFailures   int `mapstructure:"max_ip_failures"`   //同一IP窗口内允许的失败次数
+	Window          int `mapstructure:"window"`            //失败次数统计窗口（秒）
+	LockDuration    int `mapstructure:"lock_duration"`     //锁定时长（秒）
+	DelayStep       int `mapstructure:"delay_step"`        //每次失败增加的响应延迟（毫秒）
+	MaxDelay        int `mapstructure:"max_delay"`         //最大响应延迟（毫秒）
+}
+
+// MailConfig 邮件配置
+type MailConfig struct {
+	Driver   string `mapstructure:"driver" reload:"static"`   //发送方式 smtp、log
+	Host     string `mapstructure:"host" reload:"static"`     //SMTP服务器地址
+	Port     int    `mapstructure:"port" reload:"static"`     //SMTP服务器端口
+	Username string `mapstructure:"username" reload:"static"` //SMTP用户名
+	Password string `mapstructure:"password" reload:"static"` //SMTP密码
+	From     string `mapstructure:"from" reload:"static"`     //发件人
+	Dir      string `mapstructure:"dir" reload:"static"`      //log驱动保存邮件的目录，为空时只写日志
+	BaseURL  string `mapstructure:"base_url"`                 //邮件中链接的前缀，如 https://forum.example.com
+}
+
+// AccountConfig 账户配置
+type AccountConfig struct {
+	// UnverifiedPolicy 邮箱未验证账户的限制
+	// allow: 不限制  read_only: 不能发帖和投票  block_login: 不能登录
+	UnverifiedPolicy string `mapstructure:"unverified_policy"`
+	// DeletePosts 注销账户时如何处理用户的帖子
+	// keep: 保留  soft_delete: 标记为已删除
+	DeletePosts string `mapstructure:"delete_posts"`
+}
+
+// OAuthConfig 第三方登录配置
+type OAuthConfig struct {
+	Providers map[string]*OAuthProvider `mapstructure:"providers"` //按名称配置的提供方，名称出现在登录地址中
+}
+
+// OAuthProvider 第三方登录提供方
+type OAuthProvider struct {
+	Type         string   `mapstructure:"type"`          //提供方类型 oidc、github
+	ClientID     string   `mapstructure:"client_id"`     //客户端ID
+	ClientSecret string   `mapstructure:"client_secret"` //客户端密钥
+	Issuer       string   `mapstructure:"issuer"`        //OIDC提供方的issuer
+	RedirectURL  string   `mapstructure:"redirect_url"`  //回调地址，需要和提供方中登记的一致
+	Scopes       []string `mapstructure:"scopes"`        //申请的权限，为空时使用默认值
+}
+
+// ResponseConfig 响应格式配置
+type ResponseConfig struct {
+	// LegacyStatus 为true时所有响应都返回HTTP 200，错误只通过响应体中的code区分，兼容旧客户端
+	LegacyStatus bool `mapstructure:"legacy_status"`
+}
+
+// WebhookConfig 事件推送配置
+type WebhookConfig struct {
+	MaxAttempts         int  `mapstructure:"max_attempts"`          //每条记录最多发送的次数，之后标记为失败
+	Timeout             int  `mapstructure:"timeout"`               //每次请求的超时时间（秒）
+	AllowPrivateNetwork bool `mapstructure:"allow_private_network"` //是否允许推送到内网地址（开发环境使用）
+}
+
+// TagConfig 标签配置，修改后立即生效
+type TagConfig struct {
+	Allowed []string `mapstructure:"allowed"` //允许使用的标签，为空时可以使用任意标签
+}
+
+// MetricsConfig 监控指标配置
+// 指标包含路由和流量等内部信息，使用单独的地址监听，不通过对外的API端口提供
+type MetricsConfig struct {
+	Addr string `mapstructure:"addr" reload:"static"` //监听地址，如127.0.0.1:9091，为空时不提供指标
+}
+
+// ChangeFunc 配置变更的回调函数
+// oldConf是变更前的快照，newConf是变更后的快照
+type ChangeFunc func(oldConf, newConf *AppConfig)
+
+var (
+	subMu       sync.Mutex
+	subscribers []ChangeFunc
+)
+
+// Subscribe 订阅配置变更
+// 配置文件修改并重新加载成功后，按注册顺序依次调用回调函数
+func Subscribe(fn ChangeFunc) {
+	subMu.Lock()
+	defer subMu.Unlock()
+	subscribers = append(subscribers, fn)
 }
 
 func Init() (err error) {
@@ -79,19 +218,44 @@ func Init() (err error) {
 		return
 	}
 
-	//把读取到的配置信息反序列到Conf全局变量中
-	if err := viper.Unmarshal(Conf); err != nil {
+	//把读取到的配置信息反序列到新的配置快照中
+	c := new(AppConfig)
+	if err = viper.Unmarshal(c); err != nil {
 		fmt.Printf("viper.Unmarshall failed, err:%v\n", err)
+		return
 	}
+	conf.Store(c)
 
 	//监控配置文件的变化
 	viper.WatchConfig()
 	//当配置文件发生变化 回调函数启动
 	viper.OnConfigChange(func(in fsnotify.Event) {
-		fmt.Println("配置文件修改了")
-		if err := viper.Unmarshal(Conf); err != nil {
-			fmt.Printf("viper.Unmarshall failed, err:%v\n", err)
-		}
+		zap.L().Info("config file changed", zap.String("file", in.Name))
+		reload()
 	})
 	return
 }
+
+// reload 重新加载配置并原子替换快照，然后通知订阅者
+func reload() {
+	newConf := new(AppConfig)
+	if err := viper.Unmarshal(newConf); err != nil {
+		zap.L().Error("viper.Unmarshal failed, keep current config", zap.Error(err))
+		return
+	}
+	oldConf := conf.Load()
+	// 不支持运行时修改的字段保持原值
+	for _, name := range keepStatic(oldConf, newConf) {
+		zap.L().Warn("config field can not be changed at runtime, restart required",
+			zap.String("field", name))
+	}
+	conf.Store(newConf)
+
+	subMu.Lock()
+	fns := make([]ChangeFunc, len(subscribers))
+	copy(fns, subscribers)
+	subMu.Unlock()
+	for _, fn := range fns {
+		fn(oldConf, newConf)
+	}
+}
@@ -0,0 +1,63 @@
+package settings
+
+import (
+	"reflect"
+	"strings"
+)
+
+// staticTag 标记只能在启动时生效的配置字段
+const staticTag = "static"
+
+// keepStatic 把新配置中带有 reload:"static" 标签的字段恢复为旧值
+// 返回值: 被拒绝修改的字段名称（mapstructure路径，如 mysql.host）
+func keepStatic(oldConf, newConf *AppConfig) (rejected []string) {
+	if oldConf == nil || newConf == nil {
+		return
+	}
+	walkStatic(reflect.ValueOf(oldConf).Elem(), reflect.ValueOf(newConf).Elem(), "", &rejected)
+	return
+}
+
+func walkStatic(oldV, newV reflect.Value, prefix string, rejected *[]string) {
+	t := newV.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.SplitN(field.Tag.Get("mapstructure"), ",", 2)[0]
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		if prefix != "" {
+			name = prefix + "." + name
+		}
+		oldF, newF := oldV.Field(i), newV.Field(i)
+
+		// 嵌套的配置段
+		if field.Type.Kind() == reflect.Ptr && field.Type.Elem().Kind() == reflect.Struct {
+			if newF.IsNil() && oldF.IsNil() {
+				continue
+			}
+			if oldF.IsNil() {
+				// 新增的配置段，启动时没有这个配置段，static字段按零值比较
+				oldF = reflect.New(field.Type.Elem())
+			} else if newF.IsNil() {
+				// 整个配置段被删除，沿用旧的配置段
+				newF.Set(oldF)
+				continue
+			}
+			walkStatic(oldF.Elem(), newF.Elem(), name, rejected)
+			continue
+		}
+		if field.Type.Kind() == reflect.Struct {
+			walkStatic(oldF, newF, name, rejected)
+			continue
+		}
+
+		if field.Tag.Get("reload") != staticTag {
+			continue
+		}
+		if !reflect.DeepEqual(oldF.Interface(), newF.Interface()) {
+			newF.Set(oldF)
+			*rejected = append(*rejected, name)
+		}
+	}
+}
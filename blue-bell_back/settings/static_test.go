@@ -0,0 +1,35 @@
+package settings
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestKeepStatic(t *testing.T) {
+	oldConf := &AppConfig{
+		Port:        8080,
+		RedisConfig: &RedisConfig{Host: "127.0.0.1", PoolSize: 10},
+	}
+	newConf := &AppConfig{
+		Port:          8081,
+		RedisConfig:   &RedisConfig{Host: "10.0.0.1", PoolSize: 20},
+		MetricsConfig: &MetricsConfig{Addr: "127.0.0.1:9091"},
+	}
+	rejected := keepStatic(oldConf, newConf)
+
+	want := []string{"port", "redis.host", "metrics.addr"}
+	if !reflect.DeepEqual(rejected, want) {
+		t.Errorf("rejected = %v, want %v", rejected, want)
+	}
+	if newConf.Port != 8080 || newConf.RedisConfig.Host != "127.0.0.1" {
+		t.Errorf("static fields changed: port %d, redis.host %q", newConf.Port, newConf.RedisConfig.Host)
+	}
+	// 非static字段正常生效
+	if newConf.RedisConfig.PoolSize != 20 {
+		t.Errorf("redis.pool_size = %d, want 20", newConf.RedisConfig.PoolSize)
+	}
+	// 新增的配置段中的static字段保持启动时的零值
+	if newConf.MetricsConfig == nil || newConf.MetricsConfig.Addr != "" {
+		t.Errorf("metrics.addr = %+v, want empty", newConf.MetricsConfig)
+	}
+}